@@ -0,0 +1,311 @@
+// Package broker provides a lightweight message broker which can be embedded in a process, for deployments where
+// running a separate broker such as Redis or an MQTT broker isn't possible. Clients connect over TCP or a Unix socket
+// and exchange frames carrying types.MessageEnvelope, see Frame. Topics use the MQTT style scheme with the "+" and "#"
+// wildcards.
+//
+// The "builtin" MessageClient implementation is the client of this broker.
+package broker
+
+import (
+	"errors"
+	"messaging/pkg/internal"
+	"net"
+	"sync"
+)
+
+// outboundBufferSize is the number of frames buffered for a client, a client falling further behind is disconnected
+// rather than holding up the publishers.
+const outboundBufferSize = 256
+
+// ErrBrokerClosed is returned by Serve and ListenAndServe once the broker has been closed.
+var ErrBrokerClosed = errors.New("broker closed")
+
+// Broker routes the messages published by its clients to the clients subscribed with a matching topic filter.
+type Broker struct {
+	listeners map[net.Listener]bool
+	clients   map[*client]bool
+	closed    bool
+	mutex     sync.RWMutex
+
+	// Tracks the go routines of the connected clients
+	clientsGroup sync.WaitGroup
+}
+
+// NewBroker creates a Broker which serves clients once Serve or ListenAndServe is called.
+func NewBroker() *Broker {
+	return &Broker{
+		listeners: make(map[net.Listener]bool),
+		clients:   make(map[*client]bool),
+	}
+}
+
+// ListenAndServe listens on the address of the network, "tcp" or "unix", and serves the clients until the broker is
+// closed.
+func (b *Broker) ListenAndServe(network string, address string) error {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	return b.Serve(listener)
+}
+
+// Serve accepts the clients connecting to the listener until the broker is closed, in which case ErrBrokerClosed is
+// returned. The listener is closed when Serve returns.
+func (b *Broker) Serve(listener net.Listener) error {
+	if !b.addListener(listener) {
+		_ = listener.Close()
+		return ErrBrokerClosed
+	}
+	defer b.removeListener(listener)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if b.isClosed() {
+				return ErrBrokerClosed
+			}
+			return err
+		}
+
+		c := newClient(b, conn)
+		if !b.addClient(c) {
+			_ = conn.Close()
+			return ErrBrokerClosed
+		}
+
+		c.start()
+	}
+}
+
+// Close stops accepting clients, disconnects the connected ones and waits for them to be cleaned up.
+func (b *Broker) Close() error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return nil
+	}
+	b.closed = true
+
+	var err error
+	for listener := range b.listeners {
+		if closeErr := listener.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+
+	clients := make([]*client, 0, len(b.clients))
+	for c := range b.clients {
+		clients = append(clients, c)
+	}
+	b.mutex.Unlock()
+
+	for _, c := range clients {
+		c.close()
+	}
+	b.clientsGroup.Wait()
+
+	return err
+}
+
+func (b *Broker) addListener(listener net.Listener) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return false
+	}
+
+	b.listeners[listener] = true
+	return true
+}
+
+func (b *Broker) removeListener(listener net.Listener) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.listeners[listener] {
+		_ = listener.Close()
+		delete(b.listeners, listener)
+	}
+}
+
+func (b *Broker) addClient(c *client) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return false
+	}
+
+	b.clients[c] = true
+	b.clientsGroup.Add(2)
+	return true
+}
+
+func (b *Broker) removeClient(c *client) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.clients, c)
+}
+
+func (b *Broker) isClosed() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.closed
+}
+
+// route sends the message frame to every client with a subscription matching the frame's topic.
+func (b *Broker) route(frame Frame) {
+	b.mutex.RLock()
+	var recipients []*client
+	for c := range b.clients {
+		if c.matches(frame.Topic) {
+			recipients = append(recipients, c)
+		}
+	}
+	b.mutex.RUnlock()
+
+	// Sending happens outside the lock since it disconnects the slow clients
+	for _, c := range recipients {
+		c.send(frame)
+	}
+}
+
+// client is the broker side of a client connection.
+type client struct {
+	broker *Broker
+	conn   net.Conn
+
+	filters      map[string]bool
+	filtersMutex sync.RWMutex
+
+	outbound  chan Frame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newClient(b *Broker, conn net.Conn) *client {
+	return &client{
+		broker:   b,
+		conn:     conn,
+		filters:  make(map[string]bool),
+		outbound: make(chan Frame, outboundBufferSize),
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *client) start() {
+	go c.read()
+	go c.write()
+}
+
+// read processes the frames received from the client, in order, until the connection is closed.
+func (c *client) read() {
+	defer c.broker.clientsGroup.Done()
+	defer c.close()
+
+	for {
+		frame, err := ReadFrame(c.conn)
+		if err != nil {
+			return
+		}
+
+		if err = c.handle(frame); err != nil {
+			c.send(Frame{Type: FrameError, ID: frame.ID, Error: err.Error()})
+			continue
+		}
+
+		c.send(Frame{Type: FrameAck, ID: frame.ID})
+	}
+}
+
+func (c *client) handle(frame Frame) error {
+	switch frame.Type {
+	case FramePublish:
+		if err := internal.ValidatePublishTopic(frame.Topic); err != nil {
+			return err
+		}
+		if frame.Envelope == nil && frame.Data == nil {
+			return errors.New("publish frame is missing the envelope")
+		}
+
+		c.broker.route(Frame{Type: FrameMessage, Topic: frame.Topic, Envelope: frame.Envelope, Data: frame.Data})
+
+	case FrameSubscribe:
+		for _, topic := range frame.Topics {
+			if err := internal.ValidateTopicFilter(topic); err != nil {
+				return err
+			}
+		}
+
+		c.filtersMutex.Lock()
+		for _, topic := range frame.Topics {
+			c.filters[topic] = true
+		}
+		c.filtersMutex.Unlock()
+
+	case FrameUnsubscribe:
+		c.filtersMutex.Lock()
+		for _, topic := range frame.Topics {
+			delete(c.filters, topic)
+		}
+		c.filtersMutex.Unlock()
+
+	default:
+		return errors.New("unsupported frame type '" + string(frame.Type) + "'")
+	}
+
+	return nil
+}
+
+// write sends the outbound frames to the client until the connection is closed.
+func (c *client) write() {
+	defer c.broker.clientsGroup.Done()
+
+	for {
+		select {
+		case frame := <-c.outbound:
+			if err := WriteFrame(c.conn, frame); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// send queues the frame for the client unless the connection is closed. A client too slow to keep up, i.e. whose
+// outbound buffer is full, is disconnected instead of waiting for it.
+func (c *client) send(frame Frame) {
+	select {
+	case c.outbound <- frame:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) matches(topic string) bool {
+	c.filtersMutex.RLock()
+	defer c.filtersMutex.RUnlock()
+
+	for filter := range c.filters {
+		if internal.TopicMatches(filter, topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+		c.broker.removeClient(c)
+	})
+}
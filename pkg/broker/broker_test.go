@@ -0,0 +1,238 @@
+package broker
+
+import (
+	"errors"
+	"io"
+	"messaging/pkg/types"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startBroker(t *testing.T, network string, address string) (*Broker, string) {
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+
+	b := NewBroker()
+	served := make(chan error, 1)
+	go func() { served <- b.Serve(listener) }()
+
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+		assert.Equal(t, ErrBrokerClosed, <-served)
+	})
+
+	return b, listener.Addr().String()
+}
+
+// testConn is a raw protocol connection to the broker.
+type testConn struct {
+	t      *testing.T
+	conn   net.Conn
+	nextID uint64
+}
+
+func dial(t *testing.T, network string, address string) *testConn {
+	conn, err := net.Dial(network, address)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testConn{t: t, conn: conn}
+}
+
+func (c *testConn) send(frame Frame) Frame {
+	c.nextID++
+	frame.ID = c.nextID
+	require.NoError(c.t, WriteFrame(c.conn, frame))
+
+	response := c.receive()
+	require.Equal(c.t, frame.ID, response.ID)
+
+	return response
+}
+
+func (c *testConn) receive() Frame {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	frame, err := ReadFrame(c.conn)
+	require.NoError(c.t, err)
+
+	return frame
+}
+
+func (c *testConn) expectNothing() {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	frame, err := ReadFrame(c.conn)
+	require.Error(c.t, err, "unexpected frame %v", frame)
+}
+
+func TestBrokerRouting(t *testing.T) {
+	_, address := startBroker(t, "tcp", "127.0.0.1:0")
+
+	publisher := dial(t, "tcp", address)
+	exact := dial(t, "tcp", address)
+	wildcard := dial(t, "tcp", address)
+
+	assert.Equal(t, FrameAck, exact.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/events/device1"}}).Type)
+	assert.Equal(t, FrameAck, wildcard.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/+/device1", "edgex/#"}}).Type)
+
+	envelope := types.MessageEnvelope{CorrelationID: "123"}
+	assert.Equal(t, FrameAck, publisher.send(Frame{Type: FramePublish, Topic: "edgex/events/device1", Envelope: &envelope}).Type)
+
+	for _, subscriber := range []*testConn{exact, wildcard} {
+		frame := subscriber.receive()
+		assert.Equal(t, FrameMessage, frame.Type)
+		assert.Equal(t, "edgex/events/device1", frame.Topic)
+		assert.Equal(t, "123", frame.Envelope.CorrelationID)
+	}
+
+	// Delivered once per client even with several matching subscriptions
+	wildcard.expectNothing()
+	publisher.expectNothing()
+
+	assert.Equal(t, FrameAck, wildcard.send(Frame{Type: FrameUnsubscribe, Topics: []string{"edgex/+/device1", "edgex/#"}}).Type)
+	assert.Equal(t, FrameAck, publisher.send(Frame{Type: FramePublish, Topic: "edgex/events/device1", Envelope: &envelope}).Type)
+	assert.Equal(t, FrameMessage, exact.receive().Type)
+	wildcard.expectNothing()
+}
+
+// pipeListener accepts the server ends of in-memory pipes, whose writes block until the other end reads, so that the
+// broker can't get ahead of a client which doesn't read.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// dial connects a new client through a pipe.
+func (l *pipeListener) dial(t *testing.T) *testConn {
+	server, conn := net.Pipe()
+	t.Cleanup(func() { _ = conn.Close() })
+	l.conns <- server
+
+	return &testConn{t: t, conn: conn}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+func TestBrokerDisconnectsSlowClient(t *testing.T) {
+	listener := newPipeListener()
+	b := NewBroker()
+	served := make(chan error, 1)
+	go func() { served <- b.Serve(listener) }()
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+		assert.Equal(t, ErrBrokerClosed, <-served)
+	})
+
+	publisher := listener.dial(t)
+	slow := listener.dial(t)
+	assert.Equal(t, FrameAck, slow.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/events"}}).Type)
+
+	// The slow client no longer reads, so the broker blocks writing it the first message and queues the next ones
+	// until its outbound buffer overflows. The messages are routed before their publish is acknowledged.
+	envelope := types.MessageEnvelope{CorrelationID: "123"}
+	for i := 0; i < outboundBufferSize+2; i++ {
+		require.Equal(t, FrameAck, publisher.send(Frame{Type: FramePublish, Topic: "edgex/events", Envelope: &envelope}).Type)
+	}
+
+	b.mutex.RLock()
+	clients := len(b.clients)
+	b.mutex.RUnlock()
+	require.Equal(t, 1, clients, "slow client not disconnected")
+
+	// Reading again finds the connection closed, possibly in the middle of the frame being written when it was closed
+	var err error
+	for err == nil {
+		_, err = ReadFrame(slow.conn)
+	}
+	assert.True(t, errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF), "unexpected error %v", err)
+
+	// The publisher is still served
+	assert.Equal(t, FrameAck, publisher.send(Frame{Type: FramePublish, Topic: "edgex/events", Envelope: &envelope}).Type)
+}
+
+func TestBrokerInvalidFrames(t *testing.T) {
+	_, address := startBroker(t, "tcp", "127.0.0.1:0")
+	conn := dial(t, "tcp", address)
+
+	tests := []struct {
+		name  string
+		frame Frame
+	}{
+		{"Publish to wildcard topic", Frame{Type: FramePublish, Topic: "edgex/#", Envelope: &types.MessageEnvelope{}}},
+		{"Publish without envelope", Frame{Type: FramePublish, Topic: "edgex/events"}},
+		{"Subscribe to invalid filter", Frame{Type: FrameSubscribe, Topics: []string{"edgex/#/events"}}},
+		{"Unsupported type", Frame{Type: FrameMessage}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := conn.send(tt.frame)
+			assert.Equal(t, FrameError, response.Type)
+			assert.NotEmpty(t, response.Error)
+		})
+	}
+}
+
+func TestBrokerUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "broker.sock")
+	startBroker(t, "unix", socket)
+
+	subscriber := dial(t, "unix", socket)
+	publisher := dial(t, "unix", socket)
+	assert.Equal(t, FrameAck, subscriber.send(Frame{Type: FrameSubscribe, Topics: []string{"#"}}).Type)
+	assert.Equal(t, FrameAck, publisher.send(Frame{Type: FramePublish, Topic: "test", Envelope: &types.MessageEnvelope{}}).Type)
+	assert.Equal(t, "test", subscriber.receive().Topic)
+}
+
+func TestBrokerClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	b := NewBroker()
+	served := make(chan error, 1)
+	go func() { served <- b.Serve(listener) }()
+
+	conn := dial(t, "tcp", listener.Addr().String())
+	conn.send(Frame{Type: FrameSubscribe, Topics: []string{"#"}})
+
+	require.NoError(t, b.Close())
+	assert.Equal(t, ErrBrokerClosed, <-served)
+
+	// Connected clients are disconnected
+	require.NoError(t, conn.conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = ReadFrame(conn.conn)
+	require.Error(t, err)
+
+	// A closed broker can't serve anymore
+	assert.Equal(t, ErrBrokerClosed, b.ListenAndServe("tcp", "127.0.0.1:0"))
+}
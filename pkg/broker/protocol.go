@@ -0,0 +1,88 @@
+package broker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"messaging/pkg/types"
+)
+
+// MaxFrameSize is the maximum size in bytes of an encoded frame.
+const MaxFrameSize = 16 * 1024 * 1024
+
+// FrameType identifies the purpose of a frame.
+type FrameType string
+
+const (
+	// FramePublish is sent by a client to publish the Envelope, or its encoding in Data, to the Topic.
+	FramePublish FrameType = "publish"
+	// FrameSubscribe is sent by a client to subscribe to the Topics, which are topic filters with MQTT wildcards.
+	FrameSubscribe FrameType = "subscribe"
+	// FrameUnsubscribe is sent by a client to unsubscribe from the Topics.
+	FrameUnsubscribe FrameType = "unsubscribe"
+	// FrameMessage is sent by the broker to deliver the Envelope, or its encoding in Data, as published to the Topic.
+	// It is sent once per client even if several of the client's subscriptions match the Topic.
+	FrameMessage FrameType = "message"
+	// FrameAck is sent by the broker once the client's frame with the same ID has been processed.
+	FrameAck FrameType = "ack"
+	// FrameError is sent by the broker when the client's frame with the same ID failed, the Error describes why.
+	FrameError FrameType = "error"
+)
+
+// Frame is the unit exchanged between the broker and its clients. On the wire each frame is a 4 bytes big endian
+// length followed by the JSON encoded frame. The clients using the JSON Format send the Envelope as part of the frame,
+// the others send its encoding with the codec of their Format as Data. The broker routes either as is.
+type Frame struct {
+	Type     FrameType              `json:"type"`
+	ID       uint64                 `json:"id,omitempty"`
+	Topic    string                 `json:"topic,omitempty"`
+	Topics   []string               `json:"topics,omitempty"`
+	Envelope *types.MessageEnvelope `json:"envelope,omitempty"`
+	Data     []byte                 `json:"data,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// WriteFrame encodes the frame to the writer.
+func WriteFrame(w io.Writer, frame Frame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("unable to encode frame: %w", err)
+	}
+
+	if len(data) > MaxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds the maximum of %d bytes", len(data), MaxFrameSize)
+	}
+
+	buffer := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buffer, uint32(len(data)))
+	copy(buffer[4:], data)
+
+	_, err = w.Write(buffer)
+	return err
+}
+
+// ReadFrame decodes the next frame from the reader.
+func ReadFrame(r io.Reader) (Frame, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Frame{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return Frame{}, fmt.Errorf("frame of %d bytes exceeds the maximum of %d bytes", size, MaxFrameSize)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Frame{}, err
+	}
+
+	var frame Frame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return Frame{}, fmt.Errorf("unable to decode frame: %w", err)
+	}
+
+	return frame, nil
+}
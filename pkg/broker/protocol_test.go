@@ -0,0 +1,55 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadFrame(t *testing.T) {
+	envelope := types.MessageEnvelope{CorrelationID: "123", Payload: []byte("payload"), ContentType: types.ContentTypeJSON}
+	frames := []Frame{
+		{Type: FramePublish, ID: 1, Topic: "edgex/events", Envelope: &envelope},
+		{Type: FrameSubscribe, ID: 2, Topics: []string{"edgex/#", "edgex/+/device"}},
+		{Type: FrameError, ID: 3, Error: "failed"},
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, frame := range frames {
+		require.NoError(t, WriteFrame(buffer, frame))
+	}
+
+	for _, expected := range frames {
+		actual, err := ReadFrame(buffer)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	tooLarge := make([]byte, 4)
+	binary.BigEndian.PutUint32(tooLarge, MaxFrameSize+1)
+
+	invalid := []byte{0, 0, 0, 3, '{', 'x', '}'}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Truncated header", []byte{0, 0}},
+		{"Truncated body", []byte{0, 0, 0, 10, '{'}},
+		{"Too large", tooLarge},
+		{"Invalid JSON", invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFrame(bytes.NewReader(tt.data))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package codec encodes and decodes the MessageEnvelope carried by the messaging clients. Each client encodes the
+// envelopes it publishes with the codec named by its Format optional property, JSON when not set, and decodes the
+// envelopes it receives with the codec detected from their encoding, falling back to its own codec. JSON, CBOR,
+// MessagePack and Protobuf are registered by default and Register adds other codecs.
+//
+// The "builtin" and "websocket" clients exchange JSON frames, see broker.Frame and websocket.Frame, which carry the
+// envelopes as is with the JSON Format and carry their encoding with the other formats.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+)
+
+// Names of the codecs registered by default.
+const (
+	NameJSON        = "json"
+	NameCBOR        = "cbor"
+	NameMessagePack = "msgpack"
+	NameProtobuf    = "protobuf"
+)
+
+// Codec encodes and decodes the envelopes in one encoding.
+type Codec interface {
+	// Name is the name the codec is registered and selected with, e.g. "json".
+	Name() string
+	// ContentType is the content type of the encoded envelopes, set on the messages by the transports supporting it.
+	ContentType() string
+	// Encode returns the encoding of the envelope.
+	Encode(envelope types.MessageEnvelope) ([]byte, error)
+	// Decode populates the envelope from its encoding.
+	Decode(data []byte, envelope *types.MessageEnvelope) error
+}
+
+// Detector is implemented by the codecs whose encoding is recognized from the data itself, so that the envelopes they
+// encode are decoded by any client. The data must not be recognized by the Detector of another codec.
+type Detector interface {
+	// Detect reports whether the data is an envelope encoded by the codec.
+	Detect(data []byte) bool
+}
+
+var (
+	codecs      = make(map[string]Codec)
+	codecsOrder []Codec
+	codecsMutex sync.RWMutex
+)
+
+func init() {
+	for _, c := range []Codec{JSON, CBOR, MessagePack, Protobuf} {
+		if err := Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds the codec to the registry, making it available to the clients whose Format is its name. The names
+// are case-insensitive and can't be registered twice.
+func Register(c Codec) error {
+	name := strings.ToLower(c.Name())
+	if name == "" {
+		return errors.New("codec name can't be empty")
+	}
+
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+
+	if _, exists := codecs[name]; exists {
+		return fmt.Errorf("codec '%s' already registered", name)
+	}
+
+	codecs[name] = c
+	codecsOrder = append(codecsOrder, c)
+
+	return nil
+}
+
+// Lookup returns the codec registered with the name, JSON when the name is empty.
+func Lookup(name string) (Codec, error) {
+	if name == "" {
+		return JSON, nil
+	}
+
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+
+	c, exists := codecs[strings.ToLower(name)]
+	if !exists {
+		return nil, fmt.Errorf("unknown codec '%s', must be one of %s", name, strings.Join(names(), ", "))
+	}
+
+	return c, nil
+}
+
+// Detect returns the registered codec recognizing the data, if any.
+func Detect(data []byte) (Codec, bool) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+
+	for _, c := range codecsOrder {
+		if detector, ok := c.(Detector); ok && detector.Detect(data) {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// Decode populates the envelope with the codec detected from the data, or with the fallback codec when none
+// recognizes it.
+func Decode(data []byte, envelope *types.MessageEnvelope, fallback Codec) error {
+	if c, detected := Detect(data); detected {
+		return c.Decode(data, envelope)
+	}
+
+	return fallback.Decode(data, envelope)
+}
+
+// names returns the names of the registered codecs, must be called with the lock held.
+func names() []string {
+	result := make([]string, 0, len(codecsOrder))
+	for _, c := range codecsOrder {
+		result = append(result, "'"+strings.ToLower(c.Name())+"'")
+	}
+
+	return result
+}
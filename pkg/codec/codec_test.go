@@ -0,0 +1,174 @@
+package codec
+
+import (
+	"messaging/pkg/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEnvelope() types.MessageEnvelope {
+	return types.MessageEnvelope{
+		ReceivedTopic: "test/topic",
+		CorrelationID: "fa1def22-96de-4d44-8811-00333438c8e3",
+		ApiVersion:    types.ApiVersion,
+		RequestID:     "3ab0e022-464b-4bfe-bf7f-b0154093ddad",
+		ErrorCode:     1,
+		Payload:       []byte{0x00, 0x01, 0xfe, 0xff},
+		ContentType:   types.ContentTypeJSON,
+		QueryParams:   map[string]string{"key": "value"},
+		Sequence:      3,
+		EndOfStream:   true,
+		Deadline:      1700000000000,
+		Headers:       map[string]string{types.HeaderTenantID: "tenant", "custom": "value"},
+	}
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	for _, c := range []Codec{JSON, CBOR, MessagePack, Protobuf} {
+		t.Run(c.Name(), func(t *testing.T) {
+			expected := testEnvelope()
+
+			data, err := c.Encode(expected)
+			require.NoError(t, err)
+
+			var actual types.MessageEnvelope
+			require.NoError(t, c.Decode(data, &actual))
+			assert.Equal(t, expected, actual)
+
+			empty, err := c.Encode(types.MessageEnvelope{QueryParams: map[string]string{}})
+			require.NoError(t, err)
+
+			actual = types.MessageEnvelope{}
+			require.NoError(t, c.Decode(empty, &actual))
+			assert.Nil(t, actual.Headers)
+		})
+	}
+}
+
+func TestCodecsContentType(t *testing.T) {
+	assert.Equal(t, types.ContentTypeJSON, JSON.ContentType())
+	assert.Equal(t, types.ContentTypeCBOR, CBOR.ContentType())
+	assert.Equal(t, types.ContentTypeMessagePack, MessagePack.ContentType())
+	assert.Equal(t, types.ContentTypeProtobuf, Protobuf.ContentType())
+}
+
+func TestDetect(t *testing.T) {
+	for _, c := range []Codec{JSON, CBOR, MessagePack} {
+		data, err := c.Encode(testEnvelope())
+		require.NoError(t, err)
+
+		detected, ok := Detect(data)
+		require.True(t, ok, c.Name())
+		assert.Equal(t, c.Name(), detected.Name())
+	}
+
+	data, err := Protobuf.Encode(testEnvelope())
+	require.NoError(t, err)
+	_, ok := Detect(data)
+	assert.False(t, ok)
+
+	_, ok = Detect(nil)
+	assert.False(t, ok)
+}
+
+func TestDecode(t *testing.T) {
+	expected := testEnvelope()
+
+	data, err := CBOR.Encode(expected)
+	require.NoError(t, err)
+
+	var actual types.MessageEnvelope
+	require.NoError(t, Decode(data, &actual, JSON))
+	assert.Equal(t, expected, actual)
+
+	data, err = Protobuf.Encode(expected)
+	require.NoError(t, err)
+
+	actual = types.MessageEnvelope{}
+	require.NoError(t, Decode(data, &actual, Protobuf))
+	assert.Equal(t, expected, actual)
+
+	require.Error(t, Decode([]byte("not an envelope"), &types.MessageEnvelope{}, JSON))
+}
+
+func TestCBORSmallerThanJSON(t *testing.T) {
+	envelope := testEnvelope()
+	envelope.Payload = make([]byte, 1024)
+
+	jsonData, err := JSON.Encode(envelope)
+	require.NoError(t, err)
+	cborData, err := CBOR.Encode(envelope)
+	require.NoError(t, err)
+
+	assert.Less(t, len(cborData), len(jsonData))
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    Codec
+		expectError bool
+	}{
+		{"empty", JSON, false},
+		{NameJSON, JSON, false},
+		{NameCBOR, CBOR, false},
+		{"MsgPack", MessagePack, false},
+		{NameProtobuf, Protobuf, false},
+		{"xml", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := test.name
+			if name == "empty" {
+				name = ""
+			}
+
+			actual, err := Lookup(name)
+			if test.expectError {
+				require.Error(t, err)
+				assert.True(t, strings.Contains(err.Error(), "'msgpack'"))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+type testCodec struct {
+	name string
+}
+
+func (c testCodec) Name() string {
+	return c.name
+}
+
+func (testCodec) ContentType() string {
+	return types.ContentTypeText
+}
+
+func (testCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	return envelope.Payload, nil
+}
+
+func (testCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	*envelope = types.MessageEnvelope{Payload: data}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	require.NoError(t, Register(testCodec{name: "Test-Register"}))
+
+	actual, err := Lookup("test-register")
+	require.NoError(t, err)
+	assert.Equal(t, "Test-Register", actual.Name())
+
+	require.Error(t, Register(testCodec{name: "TEST-REGISTER"}))
+	require.Error(t, Register(testCodec{name: NameJSON}))
+	require.Error(t, Register(testCodec{}))
+}
@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"messaging/pkg/types"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// JSON encodes the envelopes as JSON objects, the binary Payload being base64 encoded.
+	JSON Codec = jsonCodec{}
+	// CBOR encodes the envelopes as CBOR maps, which carry the binary Payload as is.
+	CBOR Codec = cborCodec{}
+	// MessagePack encodes the envelopes as MessagePack maps, which carry the binary Payload as is.
+	MessagePack Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return NameJSON
+}
+
+func (jsonCodec) ContentType() string {
+	return types.ContentTypeJSON
+}
+
+func (jsonCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (jsonCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	return json.Unmarshal(data, envelope)
+}
+
+// Detect recognizes the JSON objects. Those starting with white spaces are only decoded by the clients using JSON.
+func (jsonCodec) Detect(data []byte) bool {
+	return len(data) > 0 && data[0] == '{'
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string {
+	return NameCBOR
+}
+
+func (cborCodec) ContentType() string {
+	return types.ContentTypeCBOR
+}
+
+func (cborCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	return cbor.Marshal(envelope)
+}
+
+func (cborCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	return cbor.Unmarshal(data, envelope)
+}
+
+// Detect recognizes the CBOR maps, whose first byte is 0xa0 to 0xbf.
+func (cborCodec) Detect(data []byte) bool {
+	return len(data) > 0 && data[0] >= 0xa0 && data[0] <= 0xbf
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string {
+	return NameMessagePack
+}
+
+func (msgpackCodec) ContentType() string {
+	return types.ContentTypeMessagePack
+}
+
+// Encode uses the JSON field names and omits the same empty fields as JSON.
+func (msgpackCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+
+	if err := encoder.Encode(envelope); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (msgpackCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+
+	return decoder.Decode(envelope)
+}
+
+// Detect recognizes the MessagePack maps, whose first byte is 0x80 to 0x8f, 0xde or 0xdf.
+func (msgpackCodec) Detect(data []byte) bool {
+	return len(data) > 0 && ((data[0] >= 0x80 && data[0] <= 0x8f) || data[0] == 0xde || data[0] == 0xdf)
+}
@@ -0,0 +1,215 @@
+package codec
+
+import (
+	"fmt"
+	"messaging/pkg/types"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Protobuf encodes the envelopes as the MessageEnvelope message of envelope.proto. The encoding isn't recognized from
+// the data, so the envelopes are only decoded by the clients using Protobuf.
+var Protobuf Codec = protobufCodec{}
+
+// Field numbers of envelope.proto
+const (
+	protobufReceivedTopic protowire.Number = iota + 1
+	protobufCorrelationID
+	protobufApiVersion
+	protobufRequestID
+	protobufErrorCode
+	protobufPayload
+	protobufContentType
+	protobufQueryParams
+	protobufSequence
+	protobufEndOfStream
+	protobufDeadline
+	protobufHeaders
+)
+
+// Field numbers of the map entries
+const (
+	protobufMapKey   protowire.Number = 1
+	protobufMapValue protowire.Number = 2
+)
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string {
+	return NameProtobuf
+}
+
+func (protobufCodec) ContentType() string {
+	return types.ContentTypeProtobuf
+}
+
+func (protobufCodec) Encode(envelope types.MessageEnvelope) ([]byte, error) {
+	var data []byte
+	data = appendString(data, protobufReceivedTopic, envelope.ReceivedTopic)
+	data = appendString(data, protobufCorrelationID, envelope.CorrelationID)
+	data = appendString(data, protobufApiVersion, envelope.ApiVersion)
+	data = appendString(data, protobufRequestID, envelope.RequestID)
+	data = appendVarint(data, protobufErrorCode, uint64(envelope.ErrorCode))
+	if len(envelope.Payload) > 0 {
+		data = protowire.AppendTag(data, protobufPayload, protowire.BytesType)
+		data = protowire.AppendBytes(data, envelope.Payload)
+	}
+	data = appendString(data, protobufContentType, envelope.ContentType)
+	data = appendMap(data, protobufQueryParams, envelope.QueryParams)
+	data = appendVarint(data, protobufSequence, uint64(envelope.Sequence))
+	data = appendVarint(data, protobufEndOfStream, protowire.EncodeBool(envelope.EndOfStream))
+	data = appendVarint(data, protobufDeadline, uint64(envelope.Deadline))
+	data = appendMap(data, protobufHeaders, envelope.Headers)
+
+	return data, nil
+}
+
+// Decode populates the envelope, the QueryParams being empty rather than nil when there are none. The unknown fields
+// are skipped.
+func (protobufCodec) Decode(data []byte, envelope *types.MessageEnvelope) error {
+	*envelope = types.MessageEnvelope{QueryParams: make(map[string]string)}
+
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf field tag: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var err error
+		switch wireType {
+		case protowire.BytesType:
+			var value []byte
+			value, n = protowire.ConsumeBytes(data)
+			if n >= 0 {
+				err = decodeBytesField(envelope, number, value)
+			}
+		case protowire.VarintType:
+			var value uint64
+			value, n = protowire.ConsumeVarint(data)
+			if n >= 0 {
+				decodeVarintField(envelope, number, value)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+		}
+
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf field %d: %w", number, protowire.ParseError(n))
+		}
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+
+	return nil
+}
+
+func decodeBytesField(envelope *types.MessageEnvelope, number protowire.Number, value []byte) error {
+	switch number {
+	case protobufReceivedTopic:
+		envelope.ReceivedTopic = string(value)
+	case protobufCorrelationID:
+		envelope.CorrelationID = string(value)
+	case protobufApiVersion:
+		envelope.ApiVersion = string(value)
+	case protobufRequestID:
+		envelope.RequestID = string(value)
+	case protobufPayload:
+		envelope.Payload = append([]byte(nil), value...)
+	case protobufContentType:
+		envelope.ContentType = string(value)
+	case protobufQueryParams:
+		return decodeMapEntry(envelope.QueryParams, value)
+	case protobufHeaders:
+		if envelope.Headers == nil {
+			envelope.Headers = make(map[string]string)
+		}
+		return decodeMapEntry(envelope.Headers, value)
+	}
+
+	return nil
+}
+
+func decodeVarintField(envelope *types.MessageEnvelope, number protowire.Number, value uint64) {
+	switch number {
+	case protobufErrorCode:
+		envelope.ErrorCode = int(int64(value))
+	case protobufSequence:
+		envelope.Sequence = int(int64(value))
+	case protobufEndOfStream:
+		envelope.EndOfStream = protowire.DecodeBool(value)
+	case protobufDeadline:
+		envelope.Deadline = int64(value)
+	}
+}
+
+func decodeMapEntry(entries map[string]string, data []byte) error {
+	var key, value string
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf map entry: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if wireType != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(number, wireType, data); n < 0 {
+				return fmt.Errorf("invalid protobuf map entry: %w", protowire.ParseError(n))
+			}
+			data = data[n:]
+			continue
+		}
+
+		field, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf map entry: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch number {
+		case protobufMapKey:
+			key = string(field)
+		case protobufMapValue:
+			value = string(field)
+		}
+	}
+
+	entries[key] = value
+	return nil
+}
+
+// appendString appends the string field unless empty, as proto3 does.
+func appendString(data []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return data
+	}
+
+	data = protowire.AppendTag(data, number, protowire.BytesType)
+	return protowire.AppendString(data, value)
+}
+
+// appendVarint appends the varint field unless 0, as proto3 does.
+func appendVarint(data []byte, number protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return data
+	}
+
+	data = protowire.AppendTag(data, number, protowire.VarintType)
+	return protowire.AppendVarint(data, value)
+}
+
+// appendMap appends an entry message per key/value pair of the map field.
+func appendMap(data []byte, number protowire.Number, entries map[string]string) []byte {
+	for key, value := range entries {
+		var entry []byte
+		entry = appendString(entry, protobufMapKey, key)
+		entry = appendString(entry, protobufMapValue, value)
+
+		data = protowire.AppendTag(data, number, protowire.BytesType)
+		data = protowire.AppendBytes(data, entry)
+	}
+
+	return data
+}
@@ -0,0 +1,175 @@
+package amqp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// amqpWrapper implements AMQPClient and uses the 'amqp091-go' library to communicate with the broker.
+//
+// This functionality was abstracted out from Client so that unit testing can be done easily with a fake.
+type amqpWrapper struct {
+	connection        *amqp.Connection
+	publisher         *amqp.Channel
+	publisherConfirms bool
+	publishMutex      sync.Mutex
+
+	// prefetch is the number of deliveries a consumer may have unacknowledged, unlimited when 0
+	prefetch int
+
+	// Each consumer has its own channel so that cancelling it doesn't affect the others
+	consumers      map[string]*amqp.Channel
+	consumersMutex sync.Mutex
+}
+
+// NewAMQPClientWrapper creates an AMQPClient implementation which uses an 'amqp091-go' connection to achieve the
+// necessary functionality.
+func NewAMQPClientWrapper(config ClientConfig, tlsConfig *tls.Config) (AMQPClient, error) {
+	brokerURL, err := url.Parse(config.BrokerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Username != "" {
+		brokerURL.User = url.UserPassword(config.Username, config.Password)
+	}
+
+	connection, err := amqp.DialConfig(brokerURL.String(), amqp.Config{
+		TLSClientConfig: tlsConfig,
+		Dial:            amqp.DefaultDial(time.Duration(config.ConnectTimeout) * time.Second),
+		Properties:      amqp.Table{"connection_name": config.ClientId},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := connection.Channel()
+	if err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+
+	if config.PublisherConfirms {
+		if err = publisher.Confirm(false); err != nil {
+			_ = connection.Close()
+			return nil, fmt.Errorf("unable to enable publisher confirms: %w", err)
+		}
+	}
+
+	return &amqpWrapper{
+		connection:        connection,
+		publisher:         publisher,
+		publisherConfirms: config.PublisherConfirms,
+		prefetch:          config.Prefetch,
+		consumers:         make(map[string]*amqp.Channel),
+	}, nil
+}
+
+// DeclareExchange declares the durable topic exchange the messages are published to.
+func (w *amqpWrapper) DeclareExchange(exchange string) error {
+	w.publishMutex.Lock()
+	defer w.publishMutex.Unlock()
+
+	return w.publisher.ExchangeDeclare(exchange, amqp.ExchangeTopic, true, false, false, false, nil)
+}
+
+// Publish sends the message to the exchange with the routing key, waiting for the broker to confirm it when
+// publisher confirms are enabled, until the context is done.
+func (w *amqpWrapper) Publish(ctx context.Context, exchange string, routingKey string, message amqp.Publishing) error {
+	w.publishMutex.Lock()
+	confirmation, err := w.publisher.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey,
+		false, false, message)
+	w.publishMutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if !w.publisherConfirms {
+		return nil
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("message was not confirmed by the broker")
+	}
+
+	return nil
+}
+
+// Consume declares the queue, binds it to the exchange with the binding key and starts consuming it on its own channel.
+func (w *amqpWrapper) Consume(queue QueueOptions, exchange string, bindingKey string) (string, <-chan amqp.Delivery, <-chan *amqp.Error, error) {
+	channel, err := w.connection.Channel()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// Without a limit the broker pushes the whole queue to the consumer, which only hands one message at a time over
+	if w.prefetch > 0 {
+		if err = channel.Qos(w.prefetch, 0, false); err != nil {
+			_ = channel.Close()
+			return "", nil, nil, fmt.Errorf("unable to set the prefetch count: %w", err)
+		}
+	}
+
+	declared, err := channel.QueueDeclare(queue.Name, queue.Durable, queue.AutoDelete, queue.Exclusive, false, nil)
+	if err != nil {
+		_ = channel.Close()
+		return "", nil, nil, fmt.Errorf("unable to declare queue '%s': %w", queue.Name, err)
+	}
+
+	if err = channel.QueueBind(declared.Name, bindingKey, exchange, false, nil); err != nil {
+		_ = channel.Close()
+		return "", nil, nil, fmt.Errorf("unable to bind queue '%s' to '%s': %w", declared.Name, bindingKey, err)
+	}
+
+	// Registered before consuming, the error is then sent to it before the deliveries are closed
+	closed := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	consumerTag := uuid.NewString()
+	deliveries, err := channel.Consume(declared.Name, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		_ = channel.Close()
+		return "", nil, nil, fmt.Errorf("unable to consume queue '%s': %w", declared.Name, err)
+	}
+
+	w.consumersMutex.Lock()
+	w.consumers[consumerTag] = channel
+	w.consumersMutex.Unlock()
+
+	return consumerTag, deliveries, closed, nil
+}
+
+// Cancel stops the consumer and closes its channel, the messages delivered but not acknowledged are requeued.
+func (w *amqpWrapper) Cancel(consumerTag string) error {
+	w.consumersMutex.Lock()
+	channel, exists := w.consumers[consumerTag]
+	delete(w.consumers, consumerTag)
+	w.consumersMutex.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	return channel.Close()
+}
+
+// Close closes the connection, which closes all its channels.
+func (w *amqpWrapper) Close() error {
+	w.consumersMutex.Lock()
+	w.consumers = make(map[string]*amqp.Channel)
+	w.consumersMutex.Unlock()
+
+	return w.connection.Close()
+}
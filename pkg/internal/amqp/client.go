@@ -0,0 +1,374 @@
+package amqp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	StandardTopicSeparator  = "/"
+	AMQPRoutingKeySeparator = "."
+	StandardWildcard        = "#"
+	SingleLevelWildcard     = "+"
+	AMQPWildcard            = "#"
+	AMQPSingleLevelWildcard = "*"
+
+	// reservedExchangePrefix is the prefix of the exchanges pre-declared by the broker, which can't be declared
+	reservedExchangePrefix = "amq."
+)
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages using an
+// AMQP 0-9-1 broker such as RabbitMQ. The topics are mapped onto the routing keys of a topic exchange.
+type Client struct {
+	config      ClientConfig
+	codec       codec.Codec
+	creator     AMQPClientCreator
+	tlsConfig   *tls.Config
+	amqpClient  AMQPClient
+	clientMutex sync.RWMutex
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+}
+
+// subscription tracks the consumer of a topic.
+type subscription struct {
+	*internal.Delivery
+
+	topic       string
+	consumerTag string
+	codec       codec.Codec
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	return NewClientWithCreator(messageBusConfig, NewAMQPClientWrapper, tls.X509KeyPair, tls.LoadX509KeyPair,
+		x509.ParseCertificate, os.ReadFile, pem.Decode)
+}
+
+// NewClientWithCreator creates a new Client based on the provided configuration while allowing more control on the
+// creation of the underlying entities such as certs, keys, and AMQP clients.
+func NewClientWithCreator(
+	messageBusConfig types.MessageBusConfig,
+	creator AMQPClientCreator,
+	pairCreator internal.X509KeyPairCreator,
+	keyLoader internal.X509KeyLoader,
+	caCertCreator internal.X509CaCertCreator,
+	caCertLoader internal.X509CaCertLoader,
+	pemDecoder internal.PEMDecoder) (*Client, error) {
+
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := internal.GenerateTLSForClientClientOptions(
+		config.BrokerURL,
+		config.TlsConfigurationOptions,
+		pairCreator,
+		keyLoader,
+		caCertCreator,
+		caCertLoader,
+		pemDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:                config,
+		codec:                 envelopeCodec,
+		creator:               creator,
+		tlsConfig:             tlsConfig,
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+	}, nil
+}
+
+// Connect establishes the connection to the AMQP broker and declares the exchange, unless it is one of the
+// pre-declared "amq." exchanges.
+func (c *Client) Connect() error {
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	if c.amqpClient != nil {
+		return nil
+	}
+
+	amqpClient, err := c.creator(c.config, c.tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(c.config.Exchange, reservedExchangePrefix) {
+		if err = amqpClient.DeclareExchange(c.config.Exchange); err != nil {
+			_ = amqpClient.Close()
+			return fmt.Errorf("unable to declare exchange '%s': %w", c.config.Exchange, err)
+		}
+	}
+
+	c.amqpClient = amqpClient
+
+	return nil
+}
+
+// Publish sends the provided message to the exchange with the routing key mapped from the topic.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which stops waiting for the broker to confirm the message when the context is done.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	amqpClient := c.connection()
+	if amqpClient == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to publish with a disconnected client")
+	}
+
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	body, err := c.codec.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	// Messages routed to durable queues must be persistent to survive a broker restart
+	deliveryMode := amqp.Transient
+	if c.config.Durable != "" {
+		deliveryMode = amqp.Persistent
+	}
+
+	return amqpClient.Publish(ctx, c.config.Exchange, TopicToRoutingKey(topic), amqp.Publishing{
+		ContentType:   c.codec.ContentType(),
+		CorrelationId: message.CorrelationID,
+		MessageId:     message.RequestID,
+		DeliveryMode:  deliveryMode,
+		Timestamp:     time.Now(),
+		Body:          body,
+	})
+}
+
+// Subscribe creates a queue bound to the exchange with the binding key mapped from each topic and consumes it. The
+// queue is exclusive to the subscription unless Durable or QueueGroup is configured, in which case the clients with
+// the same name compete for the messages.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, false)
+}
+
+// subscribe creates the subscriptions, to the response topics of requests when response is set.
+func (c *Client) subscribe(topics []types.TopicChannel, messageErrors chan error, response bool) error {
+	amqpClient := c.connection()
+	if amqpClient == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to subscribe with a disconnected client")
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		bindingKey := TopicToRoutingKey(topic.Topic)
+		queue := c.config.queueOptions(bindingKey, response)
+		consumerTag, deliveries, closed, err := amqpClient.Consume(queue, c.config.Exchange, bindingKey)
+		if err != nil {
+			return fmt.Errorf("unable to subscribe to '%s' topic: %w", topic.Topic, err)
+		}
+
+		s := &subscription{
+			Delivery:    internal.NewDelivery(topic.Messages, messageErrors),
+			topic:       topic.Topic,
+			consumerTag: consumerTag,
+			codec:       c.codec,
+		}
+		c.existingSubscriptions[topic.Topic] = s
+
+		go s.consume(deliveries, closed)
+	}
+
+	return nil
+}
+
+// SubscribeContext is Subscribe which gives up declaring the queues once the context is done, the subscriptions are
+// then removed.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return internal.SubscribeWithContext(ctx, c.Subscribe, c.Unsubscribe, topics, messageErrors)
+}
+
+// subscribeResponse is SubscribeContext for the response topic of a request, which is consumed with an exclusive queue.
+func (c *Client) subscribeResponse(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	subscribe := func(topics []types.TopicChannel, messageErrors chan error) error {
+		return c.subscribe(topics, messageErrors, true)
+	}
+
+	return internal.SubscribeWithContext(ctx, subscribe, c.Unsubscribe, topics, messageErrors)
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe cancels the consumers of the specified topics. Once returned no more messages are sent to the channels
+// of these subscriptions.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	amqpClient := c.connection()
+
+	var errs []string
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+
+		if amqpClient == nil {
+			continue
+		}
+
+		if err := amqpClient.Cancel(s.consumerTag); err != nil {
+			errs = append(errs, fmt.Sprintf("unable to unsubscribe from '%s' topic: %v", topic, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// Disconnect stops all the subscriptions and closes the connection to the AMQP broker.
+func (c *Client) Disconnect() error {
+	c.subscriptionMutex.Lock()
+	for topic, s := range c.existingSubscriptions {
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	if c.amqpClient == nil {
+		return nil
+	}
+
+	err := c.amqpClient.Close()
+	c.amqpClient = nil
+
+	return err
+}
+
+func (c *Client) connection() AMQPClient {
+	c.clientMutex.RLock()
+	defer c.clientMutex.RUnlock()
+
+	return c.amqpClient
+}
+
+// consume propagates the deliveries to the subscription's channel and acknowledges them once handed over. Deliveries
+// which can't be handed over because the subscription is stopped are requeued for another consumer. The loss of the
+// consumer's channel, e.g. along with the connection, is reported since no more messages are received.
+func (s *subscription) consume(deliveries <-chan amqp.Delivery, closed <-chan *amqp.Error) {
+	for delivery := range deliveries {
+		message := types.MessageEnvelope{}
+		if err := codec.Decode(delivery.Body, &message, s.codec); err != nil {
+			// The message can never be processed, so it is dropped rather than requeued forever
+			_ = delivery.Reject(false)
+			s.SendError(fmt.Errorf("unable to unmarshal message: %w", err))
+			continue
+		}
+
+		message.ReceivedTopic = RoutingKeyToTopic(delivery.RoutingKey)
+
+		if !s.Send(message) {
+			_ = delivery.Nack(false, true)
+			continue
+		}
+
+		_ = delivery.Ack(false)
+	}
+
+	if s.Stopped() {
+		return
+	}
+
+	var reason error = errors.New("channel closed")
+	select {
+	case closeErr, ok := <-closed:
+		if ok && closeErr != nil {
+			reason = closeErr
+		}
+	default:
+	}
+
+	s.SendError(fmt.Errorf("consumer of '%s' topic stopped, unsubscribe and subscribe again once reconnected: %w",
+		s.topic, reason))
+}
+
+// TopicToRoutingKey converts the standard MQTT style topic scheme of "/", "#" & "+" to the AMQP topic exchange scheme
+// of ".", "#" & "*".
+func TopicToRoutingKey(topic string) string {
+	routingKey := strings.Replace(topic, StandardTopicSeparator, AMQPRoutingKeySeparator, -1)
+	routingKey = strings.Replace(routingKey, SingleLevelWildcard, AMQPSingleLevelWildcard, -1)
+	routingKey = strings.Replace(routingKey, StandardWildcard, AMQPWildcard, -1)
+
+	return routingKey
+}
+
+// RoutingKeyToTopic converts the AMQP topic exchange scheme of ".", "#" & "*" to the standard MQTT style topic scheme
+// of "/", "#" & "+".
+func RoutingKeyToTopic(routingKey string) string {
+	topic := strings.Replace(routingKey, AMQPRoutingKeySeparator, StandardTopicSeparator, -1)
+	topic = strings.Replace(topic, AMQPSingleLevelWildcard, SingleLevelWildcard, -1)
+	topic = strings.Replace(topic, AMQPWildcard, StandardWildcard, -1)
+
+	return topic
+}
@@ -0,0 +1,309 @@
+package amqp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+func newTestClient(t *testing.T, broker *fakeBroker, optional map[string]string) *Client {
+	client, err := NewClientWithCreator(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"},
+		Type:     "amqp",
+		Optional: optional,
+	}, broker.creator(), tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate, os.ReadFile, pem.Decode)
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func assertNoMessage(t *testing.T, messages chan types.MessageEnvelope) {
+	select {
+	case message := <-messages:
+		assert.Fail(t, "unexpected message", message.CorrelationID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTopicRoutingKeyConversion(t *testing.T) {
+	tests := []struct {
+		topic      string
+		routingKey string
+	}{
+		{"edgex/events", "edgex.events"},
+		{"edgex/events/#", "edgex.events.#"},
+		{"edgex/+/device1", "edgex.*.device1"},
+		{"#", "#"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			assert.Equal(t, tt.routingKey, TopicToRoutingKey(tt.topic))
+			assert.Equal(t, tt.topic, RoutingKeyToTopic(tt.routingKey))
+		})
+	}
+}
+
+func TestClientConnectDeclaresExchange(t *testing.T) {
+	broker := newFakeBroker()
+
+	newTestClient(t, broker, nil)
+	assert.Empty(t, broker.exchanges, "pre-declared exchanges must not be declared")
+
+	newTestClient(t, broker, map[string]string{"Exchange": "edgex"})
+	assert.True(t, broker.exchanges["edgex"])
+}
+
+func TestClientConnectError(t *testing.T) {
+	client, err := NewClientWithCreator(types.MessageBusConfig{
+		Broker: types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"},
+	}, func(ClientConfig, *tls.Config) (AMQPClient, error) {
+		return nil, errors.New("connection refused")
+	}, tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate, os.ReadFile, pem.Decode)
+	require.NoError(t, err)
+
+	require.Error(t, client.Connect())
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	broker := newFakeBroker()
+	client := newTestClient(t, broker, nil)
+
+	messages := make(chan types.MessageEnvelope)
+	wildcardMessages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{
+		{Topic: "edgex/events/device1", Messages: messages},
+		{Topic: "edgex/+/device1", Messages: wildcardMessages},
+	}, make(chan error)))
+
+	expected := types.NewMessageEnvelope([]byte("payload"), context.Background())
+	require.NoError(t, client.Publish(expected, "edgex/events/device1"))
+
+	for _, channel := range []chan types.MessageEnvelope{messages, wildcardMessages} {
+		message := testutil.ReceiveMessage(t, channel, testTimeout)
+		assert.Equal(t, expected.CorrelationID, message.CorrelationID)
+		assert.Equal(t, expected.Payload, message.Payload)
+		assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+	}
+
+	require.Len(t, broker.published, 1)
+	assert.Equal(t, amqp.Transient, broker.published[0].DeliveryMode)
+	assert.Equal(t, expected.CorrelationID, broker.published[0].CorrelationId)
+
+	// The deliveries are acknowledged once handed over
+	assert.Eventually(t, func() bool {
+		return broker.ackState(1) == "ack" && broker.ackState(2) == "ack"
+	}, testTimeout, 10*time.Millisecond)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/commands/device2"))
+	assertNoMessage(t, messages)
+	assertNoMessage(t, wildcardMessages)
+}
+
+func TestClientPublishInvalidTopic(t *testing.T) {
+	client := newTestClient(t, newFakeBroker(), nil)
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, ""))
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/#"))
+}
+
+func TestClientPublishNotConfirmed(t *testing.T) {
+	broker := newFakeBroker()
+	broker.publishErr = errors.New("nack")
+	client := newTestClient(t, broker, nil)
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+}
+
+func TestClientSubscribeDuplicateTopic(t *testing.T) {
+	client := newTestClient(t, newFakeBroker(), nil)
+
+	topics := []types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestClientSubscribeInvalidMessage(t *testing.T) {
+	broker := newFakeBroker()
+	client := newTestClient(t, broker, nil)
+
+	messageErrors := make(chan error)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, messageErrors))
+
+	raw, _ := broker.creator()(ClientConfig{}, nil)
+	require.NoError(t, raw.Publish(context.Background(), DefaultExchange, "edgex.events", amqp.Publishing{Body: []byte("not json")}))
+
+	select {
+	case err := <-messageErrors:
+		assert.Error(t, err)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for error")
+	}
+
+	// The undecodable message is dropped instead of being redelivered forever
+	assert.Eventually(t, func() bool {
+		return broker.ackState(1) == "reject requeue=false"
+	}, testTimeout, 10*time.Millisecond)
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	broker := newFakeBroker()
+	client := newTestClient(t, broker, nil)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.Len(t, broker.queues, 1)
+
+	require.NoError(t, client.Unsubscribe("edgex/events"))
+	close(messages)
+
+	// The exclusive queue is deleted and publishing after the channel is closed doesn't panic
+	assert.Empty(t, broker.queues)
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+
+	// The topic can be subscribed again
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+}
+
+func TestClientDurableQueue(t *testing.T) {
+	broker := newFakeBroker()
+	optional := map[string]string{"Durable": "service"}
+
+	client := newTestClient(t, broker, optional)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, client.Disconnect())
+
+	queue := broker.queue("service.edgex.events")
+	require.NotNil(t, queue, "durable queue must survive the disconnect")
+	assert.True(t, queue.options.Durable)
+
+	publisher := newTestClient(t, broker, optional)
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "while-down"}, "edgex/events"))
+	assert.Equal(t, amqp.Persistent, broker.published[0].DeliveryMode)
+
+	restarted := newTestClient(t, broker, optional)
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, restarted.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	message := testutil.ReceiveMessage(t, messages, testTimeout)
+	assert.Equal(t, "while-down", message.CorrelationID)
+}
+
+func TestClientQueueGroup(t *testing.T) {
+	broker := newFakeBroker()
+	messages := make(chan types.MessageEnvelope, 20)
+
+	for i := 0; i < 2; i++ {
+		client := newTestClient(t, broker, map[string]string{"QueueGroup": "group"})
+		require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	}
+
+	publisher := newTestClient(t, broker, nil)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: strconv.Itoa(i)}, "edgex/events"))
+	}
+
+	received := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		received[testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID]++
+	}
+	assert.Len(t, received, 10)
+	assertNoMessage(t, messages)
+
+	queue := broker.queue("group.edgex.events")
+	require.NotNil(t, queue)
+	assert.Len(t, queue.consumers, 2)
+}
+
+func TestClientRequest(t *testing.T) {
+	broker := newFakeBroker()
+	responder := newTestClient(t, broker, nil)
+	requester := newTestClient(t, broker, nil)
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, fmt.Sprintf("edgex/response/%s", request.RequestID))
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+}
+
+func TestClientRequestDurable(t *testing.T) {
+	broker := newFakeBroker()
+	responder := newTestClient(t, broker, nil)
+	requester := newTestClient(t, broker, map[string]string{"Durable": "service"})
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, fmt.Sprintf("edgex/response/%s", request.RequestID))
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	_, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+
+	// The response is consumed from an exclusive queue which is deleted along with its consumer
+	assert.Nil(t, broker.queue(fmt.Sprintf("service.edgex.response.%s", request.RequestID)))
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	for name, queue := range broker.queues {
+		assert.False(t, queue.options.Durable, "durable queue %s left behind", name)
+	}
+}
+
+func TestClientConsumerLost(t *testing.T) {
+	broker := newFakeBroker()
+	client := newTestClient(t, broker, nil)
+
+	messageErrors := make(chan error)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, messageErrors))
+
+	client.connection().(*fakeClient).lose(amqp.ErrClosed)
+
+	select {
+	case err := <-messageErrors:
+		assert.ErrorIs(t, err, amqp.ErrClosed)
+		assert.Contains(t, err.Error(), "edgex/events")
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestClientDisconnected(t *testing.T) {
+	client := newTestClient(t, newFakeBroker(), nil)
+	require.NoError(t, client.Disconnect())
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	require.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, client.Disconnect())
+}
@@ -0,0 +1,104 @@
+package amqp
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net/url"
+)
+
+const (
+	// DefaultExchange is the topic exchange which exists on every AMQP broker.
+	DefaultExchange = "amq.topic"
+
+	// DefaultConnectTimeout is the number of seconds to wait for the connection when not configured.
+	DefaultConnectTimeout = 30
+	// DefaultPrefetch is the number of deliveries a subscription may have unacknowledged when not configured.
+	DefaultPrefetch = 100
+)
+
+// ClientConfig contains all the configurations for the AMQP client.
+type ClientConfig struct {
+	BrokerURL string
+	ClientOptions
+	internal.TlsConfigurationOptions
+}
+
+// ClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type ClientOptions struct {
+	// Client Identifiers
+	Username string
+	Password string
+	ClientId string
+
+	// Routing
+	Exchange string // Topic exchange the topics are mapped onto
+
+	// Queues, without Durable nor QueueGroup each subscription gets an exclusive queue deleted on unsubscribe
+	Durable    string // Name of the durable queues, which survive restarts and are shared by the clients of the same name
+	QueueGroup string // Name of the transient queues shared by the clients of the group
+
+	PublisherConfirms bool   // Wait for the broker to confirm each published message
+	Prefetch          int    // Deliveries a subscription may have unacknowledged, unlimited when 0
+	ConnectTimeout    int    // Seconds
+	Format            string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+}
+
+// NewClientConfiguration creates a ClientConfig based on the provided MessageBusConfig.
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	brokerURL := messageBusConfig.Broker.GetHostURL()
+	if _, err := url.Parse(brokerURL); err != nil {
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("Failed to parse broker: %v", err))
+	}
+
+	options := ClientOptions{
+		Exchange:          DefaultExchange,
+		PublisherConfirms: true,
+		Prefetch:          DefaultPrefetch,
+		ConnectTimeout:    DefaultConnectTimeout,
+	}
+	if err := internal.Load(messageBusConfig.Optional, &options); err != nil {
+		return ClientConfig{}, err
+	}
+
+	if options.Exchange == "" {
+		return ClientConfig{}, internal.NewMissingConfigurationErr(internal.Exchange, "Exchange can't be empty")
+	}
+
+	if options.Prefetch < 0 {
+		return ClientConfig{}, fmt.Errorf("%s must not be negative", internal.Prefetch)
+	}
+
+	if _, err := codec.Lookup(options.Format); err != nil {
+		return ClientConfig{}, err
+	}
+
+	tlsConfig := internal.CreateDefaultTlsConfigurationOptions()
+	if err := internal.Load(messageBusConfig.Optional, &tlsConfig); err != nil {
+		return ClientConfig{}, err
+	}
+
+	return ClientConfig{
+		BrokerURL:               brokerURL,
+		ClientOptions:           options,
+		TlsConfigurationOptions: tlsConfig,
+	}, nil
+}
+
+// queueOptions returns the options of the queue consuming the messages matching the binding key. The response topic
+// of a request gets an exclusive queue even with Durable, since it is never subscribed again and a durable queue would
+// be left on the broker.
+func (c ClientConfig) queueOptions(bindingKey string, response bool) QueueOptions {
+	switch {
+	case response:
+		return QueueOptions{Exclusive: true, AutoDelete: true}
+	case c.Durable != "":
+		return QueueOptions{Name: c.Durable + "." + bindingKey, Durable: true}
+	case c.QueueGroup != "":
+		return QueueOptions{Name: c.QueueGroup + "." + bindingKey, AutoDelete: true}
+	default:
+		// Named by the broker
+		return QueueOptions{Exclusive: true, AutoDelete: true}
+	}
+}
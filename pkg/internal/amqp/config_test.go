@@ -0,0 +1,119 @@
+package amqp
+
+import (
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		want    ClientOptions
+		wantErr bool
+	}{
+		{
+			name:   "Defaults",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"}},
+			want:   ClientOptions{Exchange: DefaultExchange, PublisherConfirms: true, Prefetch: DefaultPrefetch, ConnectTimeout: DefaultConnectTimeout},
+		},
+		{
+			name: "All options",
+			config: types.MessageBusConfig{
+				Broker: types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"},
+				Optional: map[string]string{
+					"Username":          "user",
+					"Password":          "secret",
+					"ClientId":          "client",
+					"Exchange":          "edgex",
+					"Durable":           "service",
+					"QueueGroup":        "group",
+					"PublisherConfirms": "false",
+					"Prefetch":          "10",
+					"ConnectTimeout":    "5",
+				},
+			},
+			want: ClientOptions{
+				Username:          "user",
+				Password:          "secret",
+				ClientId:          "client",
+				Exchange:          "edgex",
+				Durable:           "service",
+				QueueGroup:        "group",
+				PublisherConfirms: false,
+				Prefetch:          10,
+				ConnectTimeout:    5,
+			},
+		},
+		{
+			name: "Empty exchange",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"},
+				Optional: map[string]string{"Exchange": ""},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative Prefetch",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"},
+				Optional: map[string]string{"Prefetch": "-1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid ConnectTimeout",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"},
+				Optional: map[string]string{"ConnectTimeout": "soon"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientConfiguration(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "amqp://localhost:5672", got.BrokerURL)
+			assert.Equal(t, tt.want, got.ClientOptions)
+		})
+	}
+}
+
+func TestNewClientConfigurationMissingExchange(t *testing.T) {
+	_, err := NewClientConfiguration(types.MessageBusConfig{Optional: map[string]string{"Exchange": ""}})
+
+	var missing internal.MissingConfigurationErr
+	require.ErrorAs(t, err, &missing)
+}
+
+func TestQueueOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  ClientOptions
+		response bool
+		want     QueueOptions
+	}{
+		{"Exclusive", ClientOptions{}, false, QueueOptions{Exclusive: true, AutoDelete: true}},
+		{"Durable", ClientOptions{Durable: "service", QueueGroup: "group"}, false, QueueOptions{Name: "service.edgex.#", Durable: true}},
+		{"QueueGroup", ClientOptions{QueueGroup: "group"}, false, QueueOptions{Name: "group.edgex.#", AutoDelete: true}},
+		{"Durable response", ClientOptions{Durable: "service"}, true, QueueOptions{Exclusive: true, AutoDelete: true}},
+		{"QueueGroup response", ClientOptions{QueueGroup: "group"}, true, QueueOptions{Exclusive: true, AutoDelete: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClientConfig{ClientOptions: tt.options}.queueOptions("edgex.#", tt.response))
+		})
+	}
+}
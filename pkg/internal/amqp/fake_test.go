@@ -0,0 +1,244 @@
+package amqp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"messaging/pkg/internal"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeBroker is an in-memory AMQP broker implementing the topic exchange semantics needed by the unit tests.
+type fakeBroker struct {
+	mutex      sync.Mutex
+	exchanges  map[string]bool
+	queues     map[string]*fakeQueue
+	published  []amqp.Publishing
+	acks       map[uint64]string
+	nextQueue  int
+	nextTag    uint64
+	publishErr error
+}
+
+type fakeQueue struct {
+	options    QueueOptions
+	exchange   string
+	bindingKey string
+	consumers  []*fakeConsumer
+	next       int
+	backlog    []amqp.Delivery
+}
+
+type fakeConsumer struct {
+	tag        string
+	queue      *fakeQueue
+	deliveries chan amqp.Delivery
+	closed     chan *amqp.Error
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		exchanges: make(map[string]bool),
+		queues:    make(map[string]*fakeQueue),
+		acks:      make(map[uint64]string),
+	}
+}
+
+// creator returns an AMQPClientCreator creating clients of the fake broker.
+func (b *fakeBroker) creator() AMQPClientCreator {
+	return func(config ClientConfig, tlsConfig *tls.Config) (AMQPClient, error) {
+		return &fakeClient{broker: b, consumers: make(map[string]*fakeConsumer)}, nil
+	}
+}
+
+func (b *fakeBroker) queue(name string) *fakeQueue {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.queues[name]
+}
+
+func (b *fakeBroker) ackState(deliveryTag uint64) string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.acks[deliveryTag]
+}
+
+// route delivers the message to the queues bound to the exchange with a matching binding key. Must be called with the
+// mutex held.
+func (b *fakeBroker) route(exchange string, routingKey string, message amqp.Publishing) {
+	for _, queue := range b.queues {
+		if queue.exchange == exchange && internal.TopicMatches(RoutingKeyToTopic(queue.bindingKey), RoutingKeyToTopic(routingKey)) {
+			b.nextTag++
+			b.enqueue(queue, amqp.Delivery{
+				Acknowledger:  b,
+				DeliveryTag:   b.nextTag,
+				Exchange:      exchange,
+				RoutingKey:    routingKey,
+				ContentType:   message.ContentType,
+				CorrelationId: message.CorrelationId,
+				DeliveryMode:  message.DeliveryMode,
+				Body:          message.Body,
+			})
+		}
+	}
+}
+
+// enqueue hands the delivery to the next consumer of the queue in a round-robin fashion, or keeps it until a consumer
+// shows up. Must be called with the mutex held.
+func (b *fakeBroker) enqueue(queue *fakeQueue, delivery amqp.Delivery) {
+	if len(queue.consumers) == 0 {
+		queue.backlog = append(queue.backlog, delivery)
+		return
+	}
+
+	consumer := queue.consumers[queue.next%len(queue.consumers)]
+	queue.next++
+	consumer.deliveries <- delivery
+}
+
+func (b *fakeBroker) Ack(tag uint64, _ bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.acks[tag] = "ack"
+	return nil
+}
+
+func (b *fakeBroker) Nack(tag uint64, _ bool, requeue bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.acks[tag] = fmt.Sprintf("nack requeue=%v", requeue)
+	return nil
+}
+
+func (b *fakeBroker) Reject(tag uint64, requeue bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.acks[tag] = fmt.Sprintf("reject requeue=%v", requeue)
+	return nil
+}
+
+// fakeClient implements AMQPClient on top of the fake broker.
+type fakeClient struct {
+	broker    *fakeBroker
+	consumers map[string]*fakeConsumer
+}
+
+func (c *fakeClient) DeclareExchange(exchange string) error {
+	c.broker.mutex.Lock()
+	defer c.broker.mutex.Unlock()
+
+	c.broker.exchanges[exchange] = true
+	return nil
+}
+
+func (c *fakeClient) Publish(_ context.Context, exchange string, routingKey string, message amqp.Publishing) error {
+	c.broker.mutex.Lock()
+	defer c.broker.mutex.Unlock()
+
+	if c.broker.publishErr != nil {
+		return c.broker.publishErr
+	}
+
+	c.broker.published = append(c.broker.published, message)
+	c.broker.route(exchange, routingKey, message)
+	return nil
+}
+
+func (c *fakeClient) Consume(options QueueOptions, exchange string, bindingKey string) (string, <-chan amqp.Delivery, <-chan *amqp.Error, error) {
+	c.broker.mutex.Lock()
+	defer c.broker.mutex.Unlock()
+
+	if options.Name == "" {
+		c.broker.nextQueue++
+		options.Name = fmt.Sprintf("amq.gen-%d", c.broker.nextQueue)
+	}
+
+	queue, exists := c.broker.queues[options.Name]
+	if !exists {
+		queue = &fakeQueue{options: options, exchange: exchange, bindingKey: bindingKey}
+		c.broker.queues[options.Name] = queue
+	} else if queue.options.Exclusive {
+		return "", nil, nil, fmt.Errorf("queue '%s' is exclusive", options.Name)
+	}
+
+	c.broker.nextQueue++
+	consumer := &fakeConsumer{
+		tag:        fmt.Sprintf("ctag-%d", c.broker.nextQueue),
+		queue:      queue,
+		deliveries: make(chan amqp.Delivery, 1000),
+		closed:     make(chan *amqp.Error, 1),
+	}
+	queue.consumers = append(queue.consumers, consumer)
+	c.consumers[consumer.tag] = consumer
+
+	backlog := queue.backlog
+	queue.backlog = nil
+	for _, delivery := range backlog {
+		c.broker.enqueue(queue, delivery)
+	}
+
+	return consumer.tag, consumer.deliveries, consumer.closed, nil
+}
+
+func (c *fakeClient) Cancel(consumerTag string) error {
+	c.broker.mutex.Lock()
+	defer c.broker.mutex.Unlock()
+
+	c.cancel(consumerTag)
+	return nil
+}
+
+// lose closes the consumers as when the connection to the broker is lost.
+func (c *fakeClient) lose(reason *amqp.Error) {
+	c.broker.mutex.Lock()
+	defer c.broker.mutex.Unlock()
+
+	for tag, consumer := range c.consumers {
+		consumer.closed <- reason
+		c.cancel(tag)
+	}
+}
+
+func (c *fakeClient) Close() error {
+	c.broker.mutex.Lock()
+	defer c.broker.mutex.Unlock()
+
+	for tag := range c.consumers {
+		c.cancel(tag)
+	}
+	return nil
+}
+
+// cancel removes the consumer and deletes its queue when not durable and no longer consumed. Must be called with the
+// mutex held.
+func (c *fakeClient) cancel(consumerTag string) {
+	consumer, exists := c.consumers[consumerTag]
+	if !exists {
+		return
+	}
+	delete(c.consumers, consumerTag)
+
+	queue := consumer.queue
+	for i, existing := range queue.consumers {
+		if existing == consumer {
+			queue.consumers = append(queue.consumers[:i], queue.consumers[i+1:]...)
+			break
+		}
+	}
+	close(consumer.deliveries)
+
+	if len(queue.consumers) == 0 && (queue.options.AutoDelete || queue.options.Exclusive) {
+		for name, existing := range c.broker.queues {
+			if existing == queue {
+				delete(c.broker.queues, name)
+			}
+		}
+	}
+}
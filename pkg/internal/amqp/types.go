@@ -0,0 +1,43 @@
+package amqp
+
+import (
+	"context"
+	"crypto/tls"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AMQPClientCreator type alias for functions which create AMQPClient implementation.
+//
+// This is mostly used for testing purposes so that we can easily inject fakes.
+type AMQPClientCreator func(config ClientConfig, tlsConfig *tls.Config) (AMQPClient, error)
+
+// QueueOptions describes the queue consuming the messages of a subscription.
+type QueueOptions struct {
+	// Name of the queue, the broker generates one when empty
+	Name       string
+	Durable    bool
+	Exclusive  bool
+	AutoDelete bool
+}
+
+// AMQPClient provides functionality needed to publish and consume messages with an AMQP 0-9-1 broker.
+//
+// The main reason for this interface is to abstract out the underlying connection from Client so that it can be faked
+// and allow for easy unit testing without requiring a running broker.
+type AMQPClient interface {
+	// DeclareExchange declares the durable topic exchange the messages are published to.
+	DeclareExchange(exchange string) error
+	// Publish sends the message to the exchange with the routing key. When publisher confirms are enabled it waits for
+	// the broker to confirm the message, or until the context is done.
+	Publish(ctx context.Context, exchange string, routingKey string, message amqp.Publishing) error
+	// Consume declares the queue, binds it to the exchange with the binding key and starts consuming it with manual
+	// acknowledgements. The returned consumer tag identifies the consumer when cancelling it. The returned close
+	// channel receives the error closing the consumer's channel when closed by the broker or lost along with the
+	// connection, after which the delivery channel is closed.
+	Consume(queue QueueOptions, exchange string, bindingKey string) (string, <-chan amqp.Delivery, <-chan *amqp.Error, error)
+	// Cancel stops the consumer, after which its delivery channel is closed.
+	Cancel(consumerTag string) error
+	// Close cleans up the channels and the connection.
+	Close() error
+}
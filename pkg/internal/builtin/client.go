@@ -0,0 +1,471 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messaging/pkg/broker"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// responseTimeout is the maximum time to wait for the broker to acknowledge a frame.
+	responseTimeout = 30 * time.Second
+	// reconnectInterval is the time to wait between the attempts to reconnect once the connection is lost.
+	reconnectInterval = time.Second
+)
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages through the
+// built-in broker provided by the broker package.
+//
+// The broker disconnects the clients too slow to keep up. The client then reconnects and restores its subscriptions,
+// unless AutoReconnect is disabled, in which case the subscriptions are removed. The subscriptions are notified of the
+// loss of the connection either way, the messages routed in the meantime are missed.
+type Client struct {
+	config ClientConfig
+	codec  codec.Codec
+
+	conn       net.Conn
+	done       chan struct{}
+	connMutex  sync.Mutex
+	writeMutex sync.Mutex
+
+	// disconnected is closed by Disconnect to stop reconnecting, nil while disconnected
+	disconnected chan struct{}
+
+	nextID       uint64
+	pending      map[uint64]chan broker.Frame
+	pendingMutex sync.Mutex
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+}
+
+type subscription struct {
+	*internal.Subscription
+	errors chan error
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:                config,
+		codec:                 envelopeCodec,
+		pending:               make(map[uint64]chan broker.Frame),
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+	}, nil
+}
+
+// Connect establishes the connection to the broker, restoring the existing subscriptions.
+func (c *Client) Connect() error {
+	c.connMutex.Lock()
+	if c.conn != nil {
+		c.connMutex.Unlock()
+		return nil
+	}
+
+	if err := c.connect(); err != nil {
+		c.connMutex.Unlock()
+		return err
+	}
+
+	if c.disconnected == nil {
+		c.disconnected = make(chan struct{})
+	}
+	c.connMutex.Unlock()
+
+	return c.resubscribe()
+}
+
+// Publish sends the provided message to the broker which routes it to the subscriptions matching the topic.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which stops waiting for the broker to acknowledge the message when the context is done.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	envelope, data, err := internal.EncodeFrameEnvelope(message, c.codec)
+	if err != nil {
+		return err
+	}
+
+	return c.request(ctx, broker.Frame{Type: broker.FramePublish, Topic: topic, Envelope: envelope, Data: data})
+}
+
+// Subscribe creates subscriptions for the specified topics. The MQTT '+' and '#' wildcards are supported.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which stops waiting for the broker to acknowledge the subscriptions when the context is
+// done. The subscriptions are then removed, messages the broker still routes for them are dropped.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	c.subscriptionMutex.Lock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	filters := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			c.subscriptionMutex.Unlock()
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			c.subscriptionMutex.Unlock()
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+
+		filters = append(filters, topic.Topic)
+	}
+
+	// The subscriptions must be in place before the broker starts routing messages for them
+	for _, topic := range topics {
+		c.existingSubscriptions[topic.Topic] = &subscription{
+			Subscription: internal.NewBoundedSubscription(topic.Topic, topic.Messages, c.config.MaxPending),
+			errors:       messageErrors,
+		}
+	}
+
+	// The lock is released while waiting for the broker since dispatching the received messages needs it
+	c.subscriptionMutex.Unlock()
+
+	if err := c.request(ctx, broker.Frame{Type: broker.FrameSubscribe, Topics: filters}); err != nil {
+		c.subscriptionMutex.Lock()
+		c.removeSubscriptions(filters)
+		c.subscriptionMutex.Unlock()
+
+		return fmt.Errorf("unable to subscribe: %w", err)
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe removes the subscriptions for the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	filters := c.removeSubscriptions(topics)
+	c.subscriptionMutex.Unlock()
+
+	if len(filters) == 0 || !c.isConnected() {
+		return nil
+	}
+
+	return c.request(context.Background(), broker.Frame{Type: broker.FrameUnsubscribe, Topics: filters})
+}
+
+// Disconnect removes all the subscriptions and closes the connection to the broker.
+func (c *Client) Disconnect() error {
+	c.subscriptionMutex.Lock()
+	topics := make([]string, 0, len(c.existingSubscriptions))
+	for topic := range c.existingSubscriptions {
+		topics = append(topics, topic)
+	}
+	c.removeSubscriptions(topics)
+	c.subscriptionMutex.Unlock()
+
+	c.connMutex.Lock()
+	conn, done := c.conn, c.done
+	c.conn = nil
+	if c.disconnected != nil {
+		close(c.disconnected)
+		c.disconnected = nil
+	}
+	c.connMutex.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	err := conn.Close()
+	<-done
+
+	return err
+}
+
+// connect dials the broker and starts reading the frames it sends. Must be called with the connMutex held.
+func (c *Client) connect() error {
+	conn, err := net.DialTimeout(c.config.Network, c.config.Address, time.Duration(c.config.ConnectTimeout)*time.Second)
+	if err != nil {
+		return fmt.Errorf("unable to connect to the broker at %s: %w", c.config.Address, err)
+	}
+
+	c.conn = conn
+	c.done = make(chan struct{})
+	go c.read(conn, c.done)
+
+	return nil
+}
+
+// resubscribe restores the existing subscriptions on the broker once connected again.
+func (c *Client) resubscribe() error {
+	c.subscriptionMutex.Lock()
+	filters := make([]string, 0, len(c.existingSubscriptions))
+	for topic := range c.existingSubscriptions {
+		filters = append(filters, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	if err := c.request(context.Background(), broker.Frame{Type: broker.FrameSubscribe, Topics: filters}); err != nil {
+		return fmt.Errorf("unable to restore the subscriptions: %w", err)
+	}
+
+	return nil
+}
+
+// reconnect attempts to connect again until it succeeds, restoring the subscriptions, or the client is disconnected.
+func (c *Client) reconnect(disconnected chan struct{}) {
+	ticker := time.NewTicker(reconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case <-ticker.C:
+		}
+
+		c.connMutex.Lock()
+		// Disconnected, or connected again with Connect, in the meantime
+		if c.disconnected != disconnected || c.conn != nil {
+			c.connMutex.Unlock()
+			return
+		}
+		err := c.connect()
+		c.connMutex.Unlock()
+
+		if err != nil {
+			continue
+		}
+
+		// Losing the connection again starts reconnecting again
+		if err = c.resubscribe(); err != nil {
+			c.reportError(err)
+		}
+		return
+	}
+}
+
+// removeSubscriptions stops the existing subscriptions of the topics and returns their topics. Must be called with the
+// subscriptionMutex held.
+func (c *Client) removeSubscriptions(topics []string) []string {
+	var removed []string
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+		removed = append(removed, topic)
+	}
+
+	return removed
+}
+
+// request sends the frame to the broker and waits for it to be acknowledged, or until the context is done.
+func (c *Client) request(ctx context.Context, frame broker.Frame) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.connMutex.Lock()
+	conn, done := c.conn, c.done
+	c.connMutex.Unlock()
+
+	if conn == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to send to the broker with a disconnected client")
+	}
+
+	frame.ID = atomic.AddUint64(&c.nextID, 1)
+	response := make(chan broker.Frame, 1)
+
+	c.pendingMutex.Lock()
+	c.pending[frame.ID] = response
+	c.pendingMutex.Unlock()
+
+	defer func() {
+		c.pendingMutex.Lock()
+		delete(c.pending, frame.ID)
+		c.pendingMutex.Unlock()
+	}()
+
+	c.writeMutex.Lock()
+	err := broker.WriteFrame(conn, frame)
+	c.writeMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(responseTimeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-response:
+		if result.Type == broker.FrameError {
+			return errors.New(result.Error)
+		}
+		return nil
+	case <-done:
+		return errors.New("connection to the broker closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for the broker to acknowledge the %s", frame.Type)
+	}
+}
+
+// read processes the frames received from the broker until the connection is closed.
+func (c *Client) read(conn net.Conn, done chan struct{}) {
+	defer close(done)
+
+	for {
+		frame, err := broker.ReadFrame(conn)
+		if err != nil {
+			c.connectionLost(conn, err)
+			return
+		}
+
+		switch frame.Type {
+		case broker.FrameMessage:
+			c.dispatch(frame)
+		case broker.FrameAck, broker.FrameError:
+			c.pendingMutex.Lock()
+			response, exists := c.pending[frame.ID]
+			c.pendingMutex.Unlock()
+			if exists {
+				response <- frame
+			}
+		}
+	}
+}
+
+// dispatch queues a copy of the message for every subscription matching its topic. The subscriptions whose queue is
+// full drop the message, which is reported without waiting for the subscriber, as are the messages which can't be
+// decoded.
+func (c *Client) dispatch(frame broker.Frame) {
+	message, err := internal.DecodeFrameEnvelope(frame.Envelope, frame.Data, c.codec)
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, s := range c.existingSubscriptions {
+		if !s.Matches(frame.Topic) {
+			continue
+		}
+
+		if err != nil {
+			s.reportError(fmt.Errorf("unable to decode the message published to '%s': %w", frame.Topic, err))
+			continue
+		}
+
+		if !s.Enqueue(internal.CopyEnvelope(*message, frame.Topic)) {
+			s.reportError(fmt.Errorf("subscription queue of '%s' full, message published to '%s' dropped", s.Filter(), frame.Topic))
+		}
+	}
+}
+
+// connectionLost reports the loss of the connection to the subscriptions unless the client has been disconnected,
+// then reconnects in the background. Without AutoReconnect the subscriptions are removed instead, so that they can be
+// created again once connected again.
+func (c *Client) connectionLost(conn net.Conn, err error) {
+	c.connMutex.Lock()
+	lost := c.conn == conn
+	if lost {
+		c.conn = nil
+	}
+	disconnected := c.disconnected
+	c.connMutex.Unlock()
+
+	if !lost {
+		return
+	}
+
+	_ = conn.Close()
+
+	if !c.config.AutoReconnect {
+		c.subscriptionMutex.Lock()
+		for topic, s := range c.existingSubscriptions {
+			s.reportError(fmt.Errorf("connection to the broker lost, subscription removed: %w", err))
+			s.Stop()
+			delete(c.existingSubscriptions, topic)
+		}
+		c.subscriptionMutex.Unlock()
+
+		return
+	}
+
+	c.reportError(fmt.Errorf("connection to the broker lost, reconnecting: %w", err))
+	go c.reconnect(disconnected)
+}
+
+// reportError reports the error to all the subscriptions.
+func (c *Client) reportError(err error) {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, s := range c.existingSubscriptions {
+		s.reportError(err)
+	}
+}
+
+// reportError reports the error without waiting for the subscriber, which may be blocked on a call needing the
+// subscriptionMutex. Must be called with the subscriptionMutex held, so that the errors channel is still open.
+func (s *subscription) reportError(err error) {
+	select {
+	case s.errors <- err:
+	default:
+	}
+}
+
+func (c *Client) isConnected() bool {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+
+	return c.conn != nil
+}
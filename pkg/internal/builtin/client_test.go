@@ -0,0 +1,302 @@
+package builtin
+
+import (
+	"context"
+	"messaging/pkg/broker"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+func startBroker(t *testing.T, network string, address string) net.Listener {
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+
+	b := broker.NewBroker()
+	go func() { _ = b.Serve(listener) }()
+	t.Cleanup(func() { _ = b.Close() })
+
+	return listener
+}
+
+func tcpBrokerConfig(t *testing.T) types.MessageBusConfig {
+	listener := startBroker(t, "tcp", "127.0.0.1:0")
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return types.MessageBusConfig{Broker: types.HostInfo{Host: host, Port: portNumber, Protocol: ProtocolTCP}}
+}
+
+func newConnectedClient(t *testing.T, config types.MessageBusConfig) *Client {
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	config := tcpBrokerConfig(t)
+	publisher := newConnectedClient(t, config)
+	subscriber := newConnectedClient(t, config)
+
+	exact := make(chan types.MessageEnvelope, 1)
+	wildcard := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{
+		{Topic: "edgex/events/device1", Messages: exact},
+		{Topic: "edgex/+/#", Messages: wildcard},
+	}, make(chan error)))
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "123", Payload: []byte("data")}, "edgex/events/device1"))
+
+	for _, messages := range []chan types.MessageEnvelope{exact, wildcard} {
+		message := testutil.ReceiveMessage(t, messages, testTimeout)
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, []byte("data"), message.Payload)
+		assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+	}
+}
+
+func TestClientFormats(t *testing.T) {
+	config := tcpBrokerConfig(t)
+	jsonClient := newConnectedClient(t, config)
+	config.Optional = map[string]string{"Format": "cbor"}
+	cborClient := newConnectedClient(t, config)
+
+	messages := map[*Client]chan types.MessageEnvelope{
+		jsonClient: make(chan types.MessageEnvelope, 2),
+		cborClient: make(chan types.MessageEnvelope, 2),
+	}
+	for client, received := range messages {
+		require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: received}}, make(chan error)))
+	}
+
+	// Each client receives the messages of both formats
+	require.NoError(t, cborClient.Publish(types.MessageEnvelope{CorrelationID: "cbor", Payload: []byte("data")}, "edgex/cbor"))
+	require.NoError(t, jsonClient.Publish(types.MessageEnvelope{CorrelationID: "json", Payload: []byte("data")}, "edgex/json"))
+
+	for _, received := range messages {
+		for _, format := range []string{"cbor", "json"} {
+			message := testutil.ReceiveMessage(t, received, testTimeout)
+			assert.Equal(t, format, message.CorrelationID)
+			assert.Equal(t, []byte("data"), message.Payload)
+			assert.Equal(t, "edgex/"+format, message.ReceivedTopic)
+		}
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "broker.sock")
+	startBroker(t, "unix", socket)
+	client := newConnectedClient(t, types.MessageBusConfig{Broker: types.HostInfo{Host: socket, Protocol: ProtocolUnix}})
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "unix"}, "test"))
+
+	assert.Equal(t, "unix", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientRequest(t *testing.T) {
+	config := tcpBrokerConfig(t)
+	responder := newConnectedClient(t, config)
+	requester := newConnectedClient(t, config)
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+}
+
+func TestClientRequestContext(t *testing.T) {
+	requester := newConnectedClient(t, tcpBrokerConfig(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := requester.RequestContext(ctx, types.NewMessageEnvelopeForRequest(nil, nil), "edgex/nobody", "edgex/response")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, requester.existingSubscriptions)
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	config := tcpBrokerConfig(t)
+	client := newConnectedClient(t, config)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Unsubscribe("test"))
+	close(messages)
+
+	// Publishing after the channel is closed must not panic
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+
+	// The topic can be subscribed again
+	messages = make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "again"}, "test"))
+	assert.Equal(t, "again", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientInvalidOperations(t *testing.T) {
+	config := tcpBrokerConfig(t)
+
+	disconnected, err := NewClient(config)
+	require.NoError(t, err)
+	require.Error(t, disconnected.Publish(types.MessageEnvelope{}, "test"))
+	require.Error(t, disconnected.Subscribe([]types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, disconnected.Disconnect())
+
+	client := newConnectedClient(t, config)
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "test/#"))
+	require.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "test/#/invalid", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+
+	topics := []types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestClientConnectionLost(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := broker.NewBroker()
+	go func() { _ = b.Serve(listener) }()
+
+	address := listener.Addr().(*net.TCPAddr)
+	client := newConnectedClient(t, types.MessageBusConfig{Broker: types.HostInfo{Host: address.IP.String(), Port: address.Port}})
+
+	messageErrors := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}, messageErrors))
+
+	require.NoError(t, b.Close())
+
+	select {
+	case err = <-messageErrors:
+		assert.Error(t, err)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for the connection lost error")
+	}
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "test"))
+}
+
+func TestClientReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := broker.NewBroker()
+	go func() { _ = b.Serve(listener) }()
+
+	address := listener.Addr().(*net.TCPAddr)
+	config := types.MessageBusConfig{Broker: types.HostInfo{Host: address.IP.String(), Port: address.Port}}
+	client := newConnectedClient(t, config)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	messageErrors := make(chan error, 10)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/#", Messages: messages}}, messageErrors))
+
+	require.NoError(t, b.Close())
+	select {
+	case err = <-messageErrors:
+		assert.Error(t, err)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for the connection lost error")
+	}
+
+	// The broker restarted on the same address
+	startBroker(t, "tcp", listener.Addr().String())
+	publisher := newConnectedClient(t, config)
+
+	// The subscription is restored once reconnected
+	require.Eventually(t, func() bool {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "123"}, "test/reconnect"))
+		select {
+		case message := <-messages:
+			return message.CorrelationID == "123"
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, testTimeout, reconnectInterval/10)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test/reconnected"))
+	assert.Equal(t, "test/reconnected", testutil.ReceiveMessage(t, messages, testTimeout).ReceivedTopic)
+}
+
+func TestClientConnectionLostWithoutReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := broker.NewBroker()
+	go func() { _ = b.Serve(listener) }()
+
+	address := listener.Addr().(*net.TCPAddr)
+	config := types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: address.IP.String(), Port: address.Port},
+		Optional: map[string]string{"AutoReconnect": "false"},
+	}
+	client := newConnectedClient(t, config)
+
+	topics := []types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope, 1)}}
+	messageErrors := make(chan error, 1)
+	require.NoError(t, client.Subscribe(topics, messageErrors))
+
+	require.NoError(t, b.Close())
+	select {
+	case err = <-messageErrors:
+		assert.Error(t, err)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for the connection lost error")
+	}
+
+	// The subscriptions are removed, so they can be created again once connected again
+	startBroker(t, "tcp", listener.Addr().String())
+	require.NoError(t, client.Connect())
+	require.NoError(t, client.Subscribe(topics, make(chan error, 1)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	testutil.ReceiveMessage(t, topics[0].Messages, testTimeout)
+}
+
+func TestClientSubscriptionsReceiveCopies(t *testing.T) {
+	client := newConnectedClient(t, tcpBrokerConfig(t))
+
+	exact := make(chan types.MessageEnvelope, 1)
+	wildcard := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{
+		{Topic: "test/copy", Messages: exact},
+		{Topic: "test/#", Messages: wildcard},
+	}, make(chan error, 1)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{Payload: []byte("payload")}, "test/copy"))
+
+	// Changing the payload received by one subscription doesn't affect the other
+	received := testutil.ReceiveMessage(t, exact, testTimeout)
+	received.Payload[0] = 'P'
+	assert.Equal(t, "payload", string(testutil.ReceiveMessage(t, wildcard, testTimeout).Payload))
+}
+
+func TestClientConnectFailure(t *testing.T) {
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: filepath.Join(t.TempDir(), "missing.sock"), Protocol: ProtocolUnix}})
+	require.NoError(t, err)
+	require.Error(t, client.Connect())
+}
@@ -0,0 +1,82 @@
+package builtin
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolTCP connects to the broker over TCP using the Broker's Host and Port.
+	ProtocolTCP = "tcp"
+	// ProtocolUnix connects to the broker over a Unix socket, the Broker's Host is the path of the socket.
+	ProtocolUnix = "unix"
+
+	// DefaultConnectTimeout is the number of seconds to wait for the connection when not configured.
+	DefaultConnectTimeout = 30
+)
+
+// ClientConfig contains the settings needed to connect to the built-in broker.
+type ClientConfig struct {
+	Network string
+	Address string
+	ClientOptions
+}
+
+// ClientOptions contains the client options which are loaded via the MessageBus.Optional's field.
+type ClientOptions struct {
+	ConnectTimeout int    // Seconds
+	AutoReconnect  bool   // Reconnect and restore the subscriptions once the connection is lost, true when not set
+	MaxPending     int    // Messages queued for each subscription, internal.DefaultMaxPending when not set and unbounded when 0
+	Format         string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+}
+
+// NewClientConfiguration creates a ClientConfig based on the MessageBus configuration, the Broker's Protocol selects
+// between TCP, the default, and Unix sockets.
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	config := ClientConfig{
+		ClientOptions: ClientOptions{
+			ConnectTimeout: DefaultConnectTimeout,
+			AutoReconnect:  true,
+			MaxPending:     internal.DefaultMaxPending,
+		},
+	}
+
+	if err := internal.Load(messageBusConfig.Optional, &config.ClientOptions); err != nil {
+		return ClientConfig{}, err
+	}
+
+	if config.MaxPending < 0 {
+		return ClientConfig{}, fmt.Errorf("%s must not be negative", internal.MaxPending)
+	}
+
+	if _, err := codec.Lookup(config.Format); err != nil {
+		return ClientConfig{}, err
+	}
+
+	broker := messageBusConfig.Broker
+	if broker.Host == "" {
+		return ClientConfig{}, internal.NewBrokerURLErr("Host is required")
+	}
+
+	switch strings.ToLower(broker.Protocol) {
+	case "", ProtocolTCP:
+		if broker.Port == 0 {
+			return ClientConfig{}, internal.NewBrokerURLErr("Port is required for the tcp protocol")
+		}
+		config.Network = ProtocolTCP
+		config.Address = net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port))
+	case ProtocolUnix:
+		config.Network = ProtocolUnix
+		config.Address = broker.Host
+	default:
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("unsupported protocol '%s', must be '%s' or '%s'",
+			broker.Protocol, ProtocolTCP, ProtocolUnix))
+	}
+
+	return config, nil
+}
@@ -0,0 +1,113 @@
+package builtin
+
+import (
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		want    ClientConfig
+		wantErr bool
+	}{
+		{
+			name:   "Default protocol is TCP",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 5563}},
+			want:   ClientConfig{Network: ProtocolTCP, Address: "localhost:5563", ClientOptions: ClientOptions{ConnectTimeout: DefaultConnectTimeout, AutoReconnect: true, MaxPending: internal.DefaultMaxPending}},
+		},
+		{
+			name: "TCP with options",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "::1", Port: 5563, Protocol: "TCP"},
+				Optional: map[string]string{"ConnectTimeout": "5"},
+			},
+			want: ClientConfig{Network: ProtocolTCP, Address: "[::1]:5563", ClientOptions: ClientOptions{ConnectTimeout: 5, AutoReconnect: true, MaxPending: internal.DefaultMaxPending}},
+		},
+		{
+			name: "Reconnect and queue options",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5563},
+				Optional: map[string]string{"AutoReconnect": "false", "MaxPending": "0"},
+			},
+			want: ClientConfig{Network: ProtocolTCP, Address: "localhost:5563", ClientOptions: ClientOptions{ConnectTimeout: DefaultConnectTimeout}},
+		},
+		{
+			name: "Negative MaxPending",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5563},
+				Optional: map[string]string{"MaxPending": "-1"},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Unix socket",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "/run/broker.sock", Protocol: "unix"}},
+			want:   ClientConfig{Network: ProtocolUnix, Address: "/run/broker.sock", ClientOptions: ClientOptions{ConnectTimeout: DefaultConnectTimeout, AutoReconnect: true, MaxPending: internal.DefaultMaxPending}},
+		},
+		{
+			name:    "Missing host",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Port: 5563}},
+			wantErr: true,
+		},
+		{
+			name:    "Missing TCP port",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost"}},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported protocol",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 5563, Protocol: "udp"}},
+			wantErr: true,
+		},
+		{
+			name: "JSON format",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5563},
+				Optional: map[string]string{"Format": "JSON"},
+			},
+			want: ClientConfig{Network: ProtocolTCP, Address: "localhost:5563", ClientOptions: ClientOptions{ConnectTimeout: DefaultConnectTimeout, AutoReconnect: true, MaxPending: internal.DefaultMaxPending, Format: "JSON"}},
+		},
+		{
+			name: "CBOR format",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5563},
+				Optional: map[string]string{"Format": "cbor"},
+			},
+			want: ClientConfig{Network: ProtocolTCP, Address: "localhost:5563", ClientOptions: ClientOptions{ConnectTimeout: DefaultConnectTimeout, AutoReconnect: true, MaxPending: internal.DefaultMaxPending, Format: "cbor"}},
+		},
+		{
+			name: "Unknown format",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5563},
+				Optional: map[string]string{"Format": "xml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid option",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 5563},
+				Optional: map[string]string{"ConnectTimeout": "soon"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientConfiguration(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"context"
+	"messaging/pkg/types"
+	"strings"
+	"time"
+)
+
+// cancelTopicLevel is the topic level following the response topic prefix in the topic of the cancel notices.
+const cancelTopicLevel = "cancel"
+
+// CancelNoticeTimeout bounds publishing the cancel notice of an abandoned request.
+const CancelNoticeTimeout = time.Second
+
+// CancelTopic returns the topic the cancel notices of the requests answered under the response topic prefix are
+// published to, i.e. <responseTopicPrefix>/cancel. A single topic is shared by all the requests, rather than one per
+// request, so that the backends keeping state for each topic, e.g. a Kafka topic or a Redis stream, don't accumulate
+// it for every abandoned request.
+func CancelTopic(responseTopicPrefix string) string {
+	return strings.Join([]string{responseTopicPrefix, cancelTopicLevel}, "/")
+}
+
+// PublishCancel notifies the responders that the request has been abandoned by publishing a cancel notice to the
+// CancelTopic, the notice carries the RequestID, which identifies the request, and the CorrelationID of the request. The notice is published in the background so that the
+// request returns right away, it is best effort and the publish error is ignored.
+func PublishCancel(
+	publish func(ctx context.Context, message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	responseTopicPrefix string) {
+	notice := types.MessageEnvelope{
+		ApiVersion:    types.ApiVersion,
+		RequestID:     requestMessage.RequestID,
+		CorrelationID: requestMessage.CorrelationID,
+		ContentType:   types.ContentTypeJSON,
+		QueryParams:   make(map[string]string),
+	}
+	topic := CancelTopic(responseTopicPrefix)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), CancelNoticeTimeout)
+		defer cancel()
+
+		_ = publish(ctx, notice, topic)
+	}()
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelTopic(t *testing.T) {
+	assert.Equal(t, "edgex/response/cancel", CancelTopic("edgex/response"))
+}
+
+func TestPublishCancel(t *testing.T) {
+	published := make(chan types.MessageEnvelope, 2)
+	topics := make(chan string, 2)
+	publish := func(_ context.Context, message types.MessageEnvelope, topic string) error {
+		published <- message
+		topics <- topic
+		return nil
+	}
+	subscribe := func(context.Context, []types.TopicChannel, chan error) error { return nil }
+	unsubscribe := func(...string) error { return nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	request := types.MessageEnvelope{RequestID: "123", CorrelationID: "456", Payload: []byte("request")}
+	_, err := DoRequestContext(ctx, subscribe, unsubscribe, publish, request, "test/request", "test/response")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The request, then its cancel notice
+	<-published
+	assert.Equal(t, "test/request", <-topics)
+
+	notice := <-published
+	assert.Equal(t, "test/response/cancel", <-topics)
+	assert.Equal(t, "123", notice.RequestID)
+	assert.Equal(t, "456", notice.CorrelationID)
+	assert.Empty(t, notice.Payload)
+}
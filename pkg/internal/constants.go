@@ -12,6 +12,12 @@ const (
 	ConnectTimeout = "ConnectTimeout"
 	AutoReconnect  = "AutoReconnect"
 
+	// Request configuration names
+	RequestMode = "RequestMode"
+
+	// Encoding configuration names
+	Format = "Format"
+
 	// TLS configuration names
 	SkipCertVerify = "SkipCertVerify"
 	CertFile       = "CertFile"
@@ -29,7 +35,6 @@ const (
 
 	// NATS specifics
 	RetryOnFailedConnect = "RetryOnFailedConnect"
-	Format               = "Format"
 	QueueGroup           = "QueueGroup"
 
 	// NATS JetStream specifics
@@ -38,4 +43,39 @@ const (
 	AutoProvision           = "AutoProvision"
 	Deliver                 = "Deliver"
 	DefaultPubRetryAttempts = "DefaultPubRetryAttempts"
+	AckWait                 = "AckWait"
+
+	// Redis Streams specifics
+	MaxLen       = "MaxLen"
+	ClaimMinIdle = "ClaimMinIdle"
+
+	// Redis Sentinel and Cluster specifics
+	SentinelAddrs    = "SentinelAddrs"
+	MasterName       = "MasterName"
+	SentinelPassword = "SentinelPassword"
+	ClusterAddrs     = "ClusterAddrs"
+
+	// AMQP specifics
+	Exchange          = "Exchange"
+	PublisherConfirms = "PublisherConfirms"
+	Prefetch          = "Prefetch"
+
+	// Kafka specifics
+	PartitionKey = "PartitionKey"
+
+	// Webhook specifics
+	Webhooks     = "Webhooks"
+	Secret       = "Secret"
+	MaxRetries   = "MaxRetries"
+	RetryBackoff = "RetryBackoff"
+
+	// WebSocket specifics
+	Path = "Path"
+
+	// SQLite specifics
+	MaxAge       = "MaxAge"
+	PollInterval = "PollInterval"
+
+	// Subscription queue specifics of the memory, builtin and webhook clients
+	MaxPending = "MaxPending"
 )
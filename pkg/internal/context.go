@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"context"
+	"messaging/pkg/types"
+)
+
+// RunWithContext runs the operation, which can't be cancelled itself, until the context is done. When the context is
+// done first its error is returned while the operation completes in the background, its outcome being ignored.
+func RunWithContext(ctx context.Context, operation func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Contexts which are never done don't need the extra go routine
+	if ctx.Done() == nil {
+		return operation()
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- operation() }()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SubscribeWithContext runs the subscribe operation, which can't be cancelled itself, until the context is done. When
+// the context is done first its error is returned and the subscriptions are removed once the operation completes.
+func SubscribeWithContext(
+	ctx context.Context,
+	subscribe func(topics []types.TopicChannel, messageErrors chan error) error,
+	unsubscribe func(topics ...string) error,
+	topics []types.TopicChannel,
+	messageErrors chan error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if ctx.Done() == nil {
+		return subscribe(topics, messageErrors)
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- subscribe(topics, messageErrors) }()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		go func() {
+			if err := <-result; err == nil {
+				names := make([]string, 0, len(topics))
+				for _, topic := range topics {
+					names = append(names, topic.Topic)
+				}
+				_ = unsubscribe(names...)
+			}
+		}()
+
+		return ctx.Err()
+	}
+}
+
+// WithDeadline returns the message with its Deadline set to the deadline of the context, unless it is already set to
+// an earlier time, so that the receivers learn when nobody waits for the message anymore.
+func WithDeadline(ctx context.Context, message types.MessageEnvelope) types.MessageEnvelope {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return message
+	}
+
+	if current, set := message.DeadlineTime(); !set || deadline.Before(current) {
+		message.Deadline = deadline.UnixMilli()
+	}
+
+	return message
+}
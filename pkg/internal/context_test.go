@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithContext(t *testing.T) {
+	expected := errors.New("operation error")
+
+	err := RunWithContext(context.Background(), func() error { return expected })
+	assert.Equal(t, expected, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = RunWithContext(ctx, func() error { return nil })
+	assert.NoError(t, err)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	err = RunWithContext(cancelled, func() error { ran = true; return nil })
+	require.ErrorIs(t, err, context.Canceled)
+	assert.False(t, ran, "operation must not run once the context is done")
+
+	// The operation blocks until after the deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	err = RunWithContext(ctx, func() error { <-release; return nil })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSubscribeWithContext(t *testing.T) {
+	topics := []types.TopicChannel{{Topic: "test/topic"}, {Topic: "test/other"}}
+
+	subscribe := func(_ []types.TopicChannel, _ chan error) error { return nil }
+	unsubscribe := func(_ ...string) error {
+		require.Fail(t, "unexpected unsubscribe")
+		return nil
+	}
+
+	err := SubscribeWithContext(context.Background(), subscribe, unsubscribe, topics, nil)
+	require.NoError(t, err)
+
+	// The subscribe completes after the context is done, so the subscriptions are removed
+	unsubscribed := make(chan []string, 1)
+	release := make(chan struct{})
+	subscribe = func(_ []types.TopicChannel, _ chan error) error {
+		<-release
+		return nil
+	}
+	unsubscribe = func(topics ...string) error {
+		unsubscribed <- topics
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err = SubscribeWithContext(ctx, subscribe, unsubscribe, topics, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	select {
+	case names := <-unsubscribed:
+		assert.Equal(t, []string{"test/topic", "test/other"}, names)
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for unsubscribe")
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	message := WithDeadline(context.Background(), types.MessageEnvelope{})
+	assert.Zero(t, message.Deadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	message = WithDeadline(ctx, types.MessageEnvelope{})
+	assert.Equal(t, deadline.UnixMilli(), message.Deadline)
+
+	// An earlier deadline already set is kept
+	earlier := time.Now().Add(time.Second).UnixMilli()
+	message = WithDeadline(ctx, types.MessageEnvelope{Deadline: earlier})
+	assert.Equal(t, earlier, message.Deadline)
+
+	later := deadline.Add(time.Hour).UnixMilli()
+	message = WithDeadline(ctx, types.MessageEnvelope{Deadline: later})
+	assert.Equal(t, deadline.UnixMilli(), message.Deadline)
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"messaging/pkg/types"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Delivery hands the messages and errors of a subscription over to the subscriber's channels, from the go routine
+// consuming them from the backend, until it is stopped. Unlike Subscription it doesn't queue the messages, so the
+// consumer only moves on, e.g. acknowledges the message, once the subscriber has taken it.
+type Delivery struct {
+	messages chan<- types.MessageEnvelope
+	errors   chan<- error
+
+	abort  chan struct{}
+	mutex  sync.Mutex
+	closed bool
+}
+
+// NewDelivery creates a Delivery to the subscriber's channels.
+func NewDelivery(messages chan<- types.MessageEnvelope, errors chan<- error) *Delivery {
+	return &Delivery{
+		messages: messages,
+		errors:   errors,
+		abort:    make(chan struct{}),
+	}
+}
+
+// Send delivers the message unless the delivery is stopped. Returns false if the message was not delivered.
+func (d *Delivery) Send(message types.MessageEnvelope) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return false
+	}
+
+	select {
+	case d.messages <- message:
+		return true
+	case <-d.abort:
+		return false
+	}
+}
+
+// SendError reports the error unless the delivery is stopped.
+func (d *Delivery) SendError(err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	select {
+	case d.errors <- err:
+	case <-d.abort:
+	}
+}
+
+// ReportError reports the error unless it is the same error as the previous one, which is returned along with the
+// error to pass to the next call. An error repeating on every attempt, e.g. while the backend can't be reached, is
+// thereby reported once.
+func (d *Delivery) ReportError(err error, previousErr error) error {
+	if previousErr != nil && reflect.DeepEqual(err, previousErr) {
+		return previousErr
+	}
+
+	d.SendError(err)
+
+	return err
+}
+
+// Stop aborts any in progress delivery and waits for it to complete, after which the subscriber's channels are no
+// longer used and can safely be closed.
+func (d *Delivery) Stop() {
+	close(d.abort)
+
+	d.mutex.Lock()
+	d.closed = true
+	d.mutex.Unlock()
+}
+
+// Stopped reports whether the delivery has been stopped.
+func (d *Delivery) Stopped() bool {
+	select {
+	case <-d.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done returns a channel which is closed when the delivery is stopped.
+func (d *Delivery) Done() <-chan struct{} {
+	return d.abort
+}
+
+// Wait waits for the duration or the delivery to be stopped.
+func (d *Delivery) Wait(duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-d.abort:
+	}
+}
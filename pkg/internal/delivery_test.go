@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"errors"
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliverySend(t *testing.T) {
+	messages := make(chan types.MessageEnvelope, 1)
+	d := NewDelivery(messages, make(chan error))
+
+	require.True(t, d.Send(types.MessageEnvelope{CorrelationID: "123"}))
+	assert.Equal(t, "123", (<-messages).CorrelationID)
+	assert.False(t, d.Stopped())
+}
+
+func TestDeliveryStopAbortsSend(t *testing.T) {
+	messages := make(chan types.MessageEnvelope)
+	d := NewDelivery(messages, make(chan error))
+
+	sent := make(chan bool)
+	go func() { sent <- d.Send(types.MessageEnvelope{}) }()
+
+	// Gives the send the time to block on the channel nobody receives from
+	time.Sleep(50 * time.Millisecond)
+	d.Stop()
+
+	select {
+	case ok := <-sent:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		require.Fail(t, "send not aborted")
+	}
+
+	// The channels are no longer used once stopped, so closing them is safe
+	close(messages)
+	assert.True(t, d.Stopped())
+	assert.False(t, d.Send(types.MessageEnvelope{}))
+	d.SendError(errors.New("ignored"))
+	d.Wait(time.Minute)
+}
+
+func TestDeliveryReportError(t *testing.T) {
+	errs := make(chan error, 3)
+	d := NewDelivery(make(chan types.MessageEnvelope), errs)
+
+	previousErr := d.ReportError(errors.New("failed"), nil)
+	previousErr = d.ReportError(errors.New("failed"), previousErr)
+	_ = d.ReportError(errors.New("other"), previousErr)
+
+	require.Len(t, errs, 2)
+	assert.EqualError(t, <-errs, "failed")
+	assert.EqualError(t, <-errs, "other")
+}
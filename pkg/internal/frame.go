@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"errors"
+	"messaging/pkg/codec"
+	"messaging/pkg/types"
+)
+
+// EncodeFrameEnvelope returns how the JSON frames of the broker and WebSocket protocols carry the envelope: as is
+// with the JSON codec, or else as its encoding with the codec.
+func EncodeFrameEnvelope(envelope types.MessageEnvelope, c codec.Codec) (*types.MessageEnvelope, []byte, error) {
+	if c == nil || c == codec.JSON {
+		return &envelope, nil, nil
+	}
+
+	data, err := c.Encode(envelope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, data, nil
+}
+
+// DecodeFrameEnvelope returns the envelope carried by a frame, either as is or as its encoding, which is decoded with
+// the codec detected from it or else with the fallback codec.
+func DecodeFrameEnvelope(envelope *types.MessageEnvelope, data []byte, fallback codec.Codec) (*types.MessageEnvelope, error) {
+	if envelope != nil {
+		return envelope, nil
+	}
+
+	if data == nil {
+		return nil, errors.New("frame is missing the envelope")
+	}
+
+	decoded := &types.MessageEnvelope{}
+	if err := codec.Decode(data, decoded, fallback); err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
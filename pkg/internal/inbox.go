@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// inboxBufferSize is the number of responses buffered by the subscription of a ReplyInbox.
+const inboxBufferSize = 64
+
+// ReplyInbox implements the Request API with one long-lived wildcard subscription to <prefix>/# per response topic
+// prefix, instead of subscribing to the response topic of each request. The responses are demultiplexed by RequestID
+// to the requests waiting for them, the others are dropped.
+type ReplyInbox struct {
+	subscribe   func(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error
+	unsubscribe func(topics ...string) error
+	publish     func(ctx context.Context, message types.MessageEnvelope, topic string) error
+
+	subscriptions map[string]*inboxSubscription
+	closed        bool
+	mutex         sync.Mutex
+}
+
+// inboxSubscription is the subscription of a response topic prefix and the requests waiting for their response.
+type inboxSubscription struct {
+	prefix   string
+	messages chan types.MessageEnvelope
+	errors   chan error
+
+	// ready is closed once subscribed, err then holds the error of the subscription
+	ready chan struct{}
+	err   error
+
+	// done is closed once unsubscribed. The channels of the subscription are left open instead, since the backend may
+	// still be sending to them.
+	done chan struct{}
+
+	// waiting is guarded by the mutex of the ReplyInbox
+	waiting map[string]chan inboxResult
+}
+
+type inboxResult struct {
+	response *types.MessageEnvelope
+	err      error
+}
+
+// NewReplyInbox creates a ReplyInbox using the operations of a client.
+func NewReplyInbox(
+	subscribe func(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error,
+	unsubscribe func(topics ...string) error,
+	publish func(ctx context.Context, message types.MessageEnvelope, topic string) error) *ReplyInbox {
+	return &ReplyInbox{
+		subscribe:     subscribe,
+		unsubscribe:   unsubscribe,
+		publish:       publish,
+		subscriptions: make(map[string]*inboxSubscription),
+	}
+}
+
+// Request publishes a request containing a RequestID to the specified topic and waits until the context is done for
+// the response published to <responseTopicPrefix>/<RequestID>. The subscription to the prefix is created by the first
+// request using it and is kept until the inbox is closed.
+func (i *ReplyInbox) Request(
+	ctx context.Context,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	if len(strings.TrimSpace(requestMessage.RequestID)) == 0 {
+		requestMessage.RequestID = uuid.NewString()
+	}
+	requestMessage = WithDeadline(ctx, requestMessage)
+
+	subscription, err := i.subscription(ctx, responseTopicPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create response subscription: %w", err)
+	}
+
+	result, err := i.wait(subscription, requestMessage.RequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The entry must be removed however the request ends, so that responses arriving late are dropped
+	defer func() {
+		i.mutex.Lock()
+		delete(subscription.waiting, requestMessage.RequestID)
+		i.mutex.Unlock()
+	}()
+
+	err = i.publish(ctx, requestMessage, requestTopic)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create publish request to %s: %w", requestTopic, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		PublishCancel(i.publish, requestMessage, responseTopicPrefix)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			responseTopic := strings.Join([]string{responseTopicPrefix, requestMessage.RequestID}, "/")
+			return nil, fmt.Errorf("timed out waiting for response on %s topic: %w", responseTopic, ctx.Err())
+		}
+		return nil, fmt.Errorf("request to %s cancelled while waiting for response: %w", requestTopic, ctx.Err())
+
+	case r := <-result:
+		if r.err != nil {
+			return nil, fmt.Errorf("encountered error waiting for response to %s: %v", requestTopic, r.err)
+		}
+		return r.response, nil
+	}
+}
+
+// Close removes the subscriptions of the inbox, the requests still waiting fail.
+func (i *ReplyInbox) Close() error {
+	i.mutex.Lock()
+	i.closed = true
+	subscriptions := i.subscriptions
+	i.subscriptions = make(map[string]*inboxSubscription)
+	i.mutex.Unlock()
+
+	var errs []error
+	for _, subscription := range subscriptions {
+		<-subscription.ready
+		if subscription.err != nil {
+			continue
+		}
+
+		if err := i.unsubscribe(subscription.topic()); err != nil {
+			errs = append(errs, err)
+		}
+
+		close(subscription.done)
+	}
+
+	return errors.Join(errs...)
+}
+
+// subscription returns the subscription of the prefix once subscribed, subscribing when it doesn't exist yet.
+func (i *ReplyInbox) subscription(ctx context.Context, prefix string) (*inboxSubscription, error) {
+	i.mutex.Lock()
+	if i.closed {
+		i.mutex.Unlock()
+		return nil, errors.New("reply inbox is closed")
+	}
+
+	subscription, exists := i.subscriptions[prefix]
+	if !exists {
+		subscription = &inboxSubscription{
+			prefix:   prefix,
+			messages: make(chan types.MessageEnvelope, inboxBufferSize),
+			errors:   make(chan error, 1),
+			ready:    make(chan struct{}),
+			done:     make(chan struct{}),
+			waiting:  make(map[string]chan inboxResult),
+		}
+		i.subscriptions[prefix] = subscription
+
+		// The subscription outlives the request creating it, so it isn't bound to its context
+		go i.start(subscription)
+	}
+	i.mutex.Unlock()
+
+	select {
+	case <-subscription.ready:
+		return subscription, subscription.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// start subscribes to the prefix and then demultiplexes the responses until the subscription is closed. The
+// subscription is forgotten when it fails, so that the next request subscribes again.
+func (i *ReplyInbox) start(subscription *inboxSubscription) {
+	topics := []types.TopicChannel{{Topic: subscription.topic(), Messages: subscription.messages}}
+	subscription.err = i.subscribe(WithResponseSubscription(context.Background()), topics, subscription.errors)
+	if subscription.err != nil {
+		i.mutex.Lock()
+		if i.subscriptions[subscription.prefix] == subscription {
+			delete(i.subscriptions, subscription.prefix)
+		}
+		i.mutex.Unlock()
+
+		close(subscription.ready)
+		return
+	}
+
+	close(subscription.ready)
+
+	for {
+		var message types.MessageEnvelope
+		select {
+		case <-subscription.done:
+			i.fail(subscription, errors.New("reply inbox is closed"))
+			return
+		case err := <-subscription.errors:
+			i.fail(subscription, err)
+			continue
+		case message = <-subscription.messages:
+		}
+
+		requestID := message.RequestID
+		if requestID == "" {
+			requestID = strings.TrimPrefix(message.ReceivedTopic, subscription.prefix+"/")
+		}
+
+		i.mutex.Lock()
+		result, exists := subscription.waiting[requestID]
+		delete(subscription.waiting, requestID)
+		i.mutex.Unlock()
+
+		if exists {
+			result <- inboxResult{response: &message}
+		}
+	}
+}
+
+// wait registers the request as waiting for its response, which is sent to the returned channel.
+func (i *ReplyInbox) wait(subscription *inboxSubscription, requestID string) (chan inboxResult, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if _, exists := subscription.waiting[requestID]; exists {
+		return nil, fmt.Errorf("request with RequestID '%s' is already waiting for its response", requestID)
+	}
+
+	// Buffered since the entry is removed when sending to it, so at most one result is sent
+	result := make(chan inboxResult, 1)
+	subscription.waiting[requestID] = result
+
+	return result, nil
+}
+
+// fail ends all the requests waiting for a response of the subscription with the error.
+func (i *ReplyInbox) fail(subscription *inboxSubscription, err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for requestID, result := range subscription.waiting {
+		result <- inboxResult{err: err}
+		delete(subscription.waiting, requestID)
+	}
+}
+
+func (s *inboxSubscription) topic() string {
+	return s.prefix + "/#"
+}
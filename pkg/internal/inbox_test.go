@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"messaging/pkg/types"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInboxBus responds to each request on the subscription of the inbox, unless respond is false.
+type fakeInboxBus struct {
+	subscribed   int32
+	subscribeErr error
+	respond      bool
+	topics       []types.TopicChannel
+	errors       chan error
+	unsubscribed []string
+	mutex        sync.Mutex
+}
+
+func (b *fakeInboxBus) subscribe(_ context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	atomic.AddInt32(&b.subscribed, 1)
+	if b.subscribeErr != nil {
+		return b.subscribeErr
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.topics = topics
+	b.errors = messageErrors
+
+	return nil
+}
+
+func (b *fakeInboxBus) unsubscribe(topics ...string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.unsubscribed = append(b.unsubscribed, topics...)
+
+	return nil
+}
+
+func (b *fakeInboxBus) publish(_ context.Context, message types.MessageEnvelope, _ string) error {
+	if !b.respond {
+		return nil
+	}
+
+	b.mutex.Lock()
+	messages := b.topics[0].Messages
+	b.mutex.Unlock()
+
+	messages <- types.MessageEnvelope{RequestID: message.RequestID, Payload: message.Payload}
+
+	return nil
+}
+
+func TestReplyInboxRequest(t *testing.T) {
+	bus := &fakeInboxBus{respond: true}
+	inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, bus.publish)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(payload string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			response, err := inbox.Request(ctx, types.MessageEnvelope{Payload: []byte(payload)}, "test/request", "test/response")
+			require.NoError(t, err)
+			assert.NotEmpty(t, response.RequestID)
+			assert.Equal(t, payload, string(response.Payload))
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&bus.subscribed), "one subscription shared by all the requests")
+	assert.Equal(t, "test/response/#", bus.topics[0].Topic)
+
+	require.NoError(t, inbox.Close())
+	assert.Equal(t, []string{"test/response/#"}, bus.unsubscribed)
+
+	// The channels are left open for the backend still sending once unsubscribed
+	bus.topics[0].Messages <- types.MessageEnvelope{}
+	bus.errors <- errors.New("late error")
+
+	_, err := inbox.Request(context.Background(), types.MessageEnvelope{}, "test/request", "test/response")
+	require.Error(t, err)
+}
+
+func TestReplyInboxRequestTopicFallback(t *testing.T) {
+	bus := &fakeInboxBus{}
+	inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, func(_ context.Context, message types.MessageEnvelope, _ string) error {
+		// Responses without RequestID are routed with the topic they are received on
+		bus.topics[0].Messages <- types.MessageEnvelope{ReceivedTopic: "test/response/" + message.RequestID, Payload: []byte("ok")}
+		return nil
+	})
+	defer func() { _ = inbox.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	response, err := inbox.Request(ctx, types.MessageEnvelope{RequestID: "123"}, "test/request", "test/response")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(response.Payload))
+}
+
+func TestReplyInboxRequestErrors(t *testing.T) {
+	t.Run("Timeout removes the waiting request", func(t *testing.T) {
+		bus := &fakeInboxBus{}
+		inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, bus.publish)
+		defer func() { _ = inbox.Close() }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := inbox.Request(ctx, types.MessageEnvelope{RequestID: "123"}, "test/request", "test/response")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "timed out waiting for response on test/response/123")
+
+		inbox.mutex.Lock()
+		assert.Empty(t, inbox.subscriptions["test/response"].waiting)
+		inbox.mutex.Unlock()
+
+		// The late response is dropped
+		bus.topics[0].Messages <- types.MessageEnvelope{RequestID: "123"}
+	})
+
+	t.Run("Subscribe error", func(t *testing.T) {
+		bus := &fakeInboxBus{subscribeErr: errors.New("subscribe error")}
+		inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, bus.publish)
+
+		_, err := inbox.Request(context.Background(), types.MessageEnvelope{}, "test/request", "test/response")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "subscribe error")
+
+		// The next request subscribes again
+		bus.subscribeErr = nil
+		bus.respond = true
+		_, err = inbox.Request(context.Background(), types.MessageEnvelope{}, "test/request", "test/response")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&bus.subscribed))
+		require.NoError(t, inbox.Close())
+	})
+
+	t.Run("Subscription error", func(t *testing.T) {
+		bus := &fakeInboxBus{}
+		inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, func(ctx context.Context, _ types.MessageEnvelope, _ string) error {
+			bus.errors <- errors.New("connection lost")
+			return nil
+		})
+		defer func() { _ = inbox.Close() }()
+
+		_, err := inbox.Request(context.Background(), types.MessageEnvelope{}, "test/request", "test/response")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connection lost")
+	})
+
+	t.Run("Duplicate RequestID", func(t *testing.T) {
+		bus := &fakeInboxBus{}
+		published := make(chan struct{})
+		inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, func(_ context.Context, _ types.MessageEnvelope, topic string) error {
+			if topic == "test/request" {
+				close(published)
+			}
+			return nil
+		})
+		defer func() { _ = inbox.Close() }()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_, _ = inbox.Request(ctx, types.MessageEnvelope{RequestID: "123"}, "test/request", "test/response")
+		}()
+		<-published
+
+		_, err := inbox.Request(context.Background(), types.MessageEnvelope{RequestID: "123"}, "test/request", "test/response")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already waiting")
+	})
+
+	t.Run("Close fails the waiting requests", func(t *testing.T) {
+		bus := &fakeInboxBus{}
+		inbox := NewReplyInbox(bus.subscribe, bus.unsubscribe, bus.publish)
+
+		result := make(chan error, 1)
+		go func() {
+			_, err := inbox.Request(context.Background(), types.MessageEnvelope{}, "test/request", "test/response")
+			result <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			inbox.mutex.Lock()
+			defer inbox.mutex.Unlock()
+			subscription, exists := inbox.subscriptions["test/response"]
+			return exists && len(subscription.waiting) == 1
+		}, time.Second, time.Millisecond)
+		require.NoError(t, inbox.Close())
+
+		select {
+		case err := <-result:
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "closed")
+		case <-time.After(time.Second):
+			require.Fail(t, "request not failed by Close")
+		}
+	})
+}
@@ -0,0 +1,543 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+const (
+	StandardTopicSeparator = "/"
+	KafkaTopicSeparator    = "."
+	StandardWildcard       = "#"
+	SingleLevelWildcard    = "+"
+
+	// topicsRefreshInterval is how often the topics matching a wildcard subscription are discovered.
+	topicsRefreshInterval = 5 * time.Second
+	// metadataMinAge is how often the metadata is refreshed at most, e.g. while a subscribed topic doesn't exist yet.
+	metadataMinAge = 100 * time.Millisecond
+)
+
+// validTopic matches the names Kafka accepts for topics.
+var validTopic = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages using Kafka.
+// The topics are mapped onto Kafka topics by replacing the "/" separators with ".", so each topic published to is a
+// Kafka topic of its own.
+type Client struct {
+	config    ClientConfig
+	codec     codec.Codec
+	tlsConfig *tls.Config
+
+	producer    *kgo.Client
+	clientMutex sync.RWMutex
+
+	refreshInterval time.Duration
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+
+	// consumers tracks the consuming go routines, including those of unsubscribed topics still finishing up
+	consumers *sync.WaitGroup
+}
+
+// subscription tracks the consumer of a topic, each subscription has its own Kafka client so that it can consume
+// with its own group and be stopped independently.
+type subscription struct {
+	*internal.Delivery
+
+	group    string
+	consumer *kgo.Client
+
+	// responseTopic is the Kafka topic of a Request's response, deleted once the subscription is stopped
+	responseTopic string
+
+	// ctx is cancelled when the subscription is stopped, so that the poll in progress returns
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	return NewClientWithCreator(messageBusConfig, tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate,
+		os.ReadFile, pem.Decode)
+}
+
+// NewClientWithCreator creates a new Client based on the provided configuration while allowing more control on the
+// creation of the certs and keys.
+func NewClientWithCreator(
+	messageBusConfig types.MessageBusConfig,
+	pairCreator internal.X509KeyPairCreator,
+	keyLoader internal.X509KeyLoader,
+	caCertCreator internal.X509CaCertCreator,
+	caCertLoader internal.X509CaCertLoader,
+	pemDecoder internal.PEMDecoder) (*Client, error) {
+
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := internal.GenerateTLSForClientClientOptions(
+		config.BrokerURL,
+		config.TlsConfigurationOptions,
+		pairCreator,
+		keyLoader,
+		caCertCreator,
+		caCertLoader,
+		pemDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:                config,
+		codec:                 envelopeCodec,
+		tlsConfig:             tlsConfig,
+		refreshInterval:       topicsRefreshInterval,
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+		consumers:             new(sync.WaitGroup),
+	}, nil
+}
+
+// Connect creates the producer and verifies the brokers can be reached.
+func (c *Client) Connect() error {
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	if c.producer != nil {
+		return nil
+	}
+
+	options, err := c.clientOptions(kgo.RecordDeliveryTimeout(c.connectTimeout()))
+	if err != nil {
+		return err
+	}
+
+	producer, err := kgo.NewClient(options...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout())
+	defer cancel()
+
+	if err = producer.Ping(ctx); err != nil {
+		producer.Close()
+		return fmt.Errorf("unable to connect to the Kafka brokers: %w", err)
+	}
+
+	c.producer = producer
+
+	return nil
+}
+
+// Publish sends the provided message to the Kafka topic mapped from the topic and waits for the brokers to
+// acknowledge it. The record's key is taken from the envelope field selected by PartitionKey, so that the messages
+// with the same key are kept in order on the same partition.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which stops waiting for the brokers to acknowledge the message when the context is done.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	producer := c.connection()
+	if producer == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to publish with a disconnected client")
+	}
+
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	kafkaTopic := ConvertToKafkaTopic(topic)
+	if !validTopic.MatchString(kafkaTopic) {
+		return internal.NewInvalidTopicErr(topic, "Kafka topics may only contain letters, digits, '.', '_' and '-'")
+	}
+
+	body, err := c.codec.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	record := &kgo.Record{
+		Topic: kafkaTopic,
+		Key:   c.config.partitionKey(message),
+		Value: body,
+	}
+
+	return producer.ProduceSync(ctx, record).FirstErr()
+}
+
+// Subscribe creates background processes which consume the Kafka topics matching the topics and send the messages
+// to the provided channels. When consuming with a group the offsets of the messages are committed once they have
+// been handed over to the channel, so that a restarted client resumes where it left off and the clients of the same
+// QueueGroup share the partitions.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which gives up listing the existing Kafka topics once the context is done.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(ctx, topics, messageErrors, false)
+}
+
+// subscribeResponse subscribes to the response topic of a Request. The response is consumed without a group, and
+// the topic, created for this single Request, is deleted once unsubscribed.
+func (c *Client) subscribeResponse(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(ctx, topics, messageErrors, true)
+}
+
+func (c *Client) subscribe(ctx context.Context, topics []types.TopicChannel, messageErrors chan error, response bool) error {
+	producer := c.connection()
+	if producer == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to subscribe with a disconnected client")
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	subscribed := time.Now()
+	existingTopics, err := c.existingTopics(ctx, producer)
+	if err != nil {
+		return err
+	}
+
+	for _, topic := range topics {
+		group := c.config.groupName(topic.Topic)
+		if response {
+			group = ""
+		}
+		expression := regexp.MustCompile(TopicFilterToRegex(topic.Topic))
+
+		consumerOptions := []kgo.Opt{
+			kgo.MetadataMinAge(metadataMinAge),
+			kgo.MetadataMaxAge(c.refreshInterval),
+			kgo.ConsumeResetOffset(c.config.startOffset(subscribed, matchesAny(expression, existingTopics))),
+		}
+		if isWildcardTopic(topic.Topic) {
+			consumerOptions = append(consumerOptions, kgo.ConsumeRegex(), kgo.ConsumeTopics(expression.String()))
+		} else {
+			consumerOptions = append(consumerOptions, kgo.ConsumeTopics(ConvertToKafkaTopic(topic.Topic)))
+		}
+		if group != "" {
+			consumerOptions = append(consumerOptions, kgo.ConsumerGroup(group), kgo.DisableAutoCommit())
+		}
+
+		options, err := c.clientOptions(consumerOptions...)
+		if err != nil {
+			return err
+		}
+
+		consumer, err := kgo.NewClient(options...)
+		if err != nil {
+			return fmt.Errorf("unable to subscribe to '%s' topic: %w", topic.Topic, err)
+		}
+
+		consumeCtx, cancel := context.WithCancel(context.Background())
+		s := &subscription{
+			Delivery: internal.NewDelivery(topic.Messages, messageErrors),
+			group:    group,
+			consumer: consumer,
+			ctx:      consumeCtx,
+			cancel:   cancel,
+		}
+		if response {
+			s.responseTopic = ConvertToKafkaTopic(topic.Topic)
+		}
+		c.existingSubscriptions[topic.Topic] = s
+
+		c.consumers.Add(1)
+		go c.consume(s)
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID. The
+// response topic is consumed without a consumer group and deleted once the Request finishes.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe stops consuming the specified topics. Once returned no more messages are sent to the channels of these
+// subscriptions. The consumers leave their groups in the background.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		s.stop()
+		delete(c.existingSubscriptions, topic)
+	}
+
+	return nil
+}
+
+// Disconnect stops all the subscriptions and closes the producer once the background processes have exited.
+func (c *Client) Disconnect() error {
+	c.subscriptionMutex.Lock()
+	for topic, s := range c.existingSubscriptions {
+		s.stop()
+		delete(c.existingSubscriptions, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	c.consumers.Wait()
+
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	if c.producer != nil {
+		c.producer.Close()
+		c.producer = nil
+	}
+
+	return nil
+}
+
+func (c *Client) connection() *kgo.Client {
+	c.clientMutex.RLock()
+	defer c.clientMutex.RUnlock()
+
+	return c.producer
+}
+
+func (c *Client) connectTimeout() time.Duration {
+	return time.Duration(c.config.ConnectTimeout) * time.Second
+}
+
+// clientOptions returns the options common to the producer and the consumers followed by the specified ones.
+func (c *Client) clientOptions(options ...kgo.Opt) ([]kgo.Opt, error) {
+	brokerURL, err := url.Parse(c.config.BrokerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	common := []kgo.Opt{
+		kgo.SeedBrokers(brokerURL.Host),
+		kgo.DialTimeout(c.connectTimeout()),
+	}
+	if c.config.ClientId != "" {
+		common = append(common, kgo.ClientID(c.config.ClientId))
+	}
+	if c.tlsConfig != nil {
+		common = append(common, kgo.DialTLSConfig(c.tlsConfig))
+	}
+	if c.config.Username != "" {
+		common = append(common, kgo.SASL(plain.Auth{User: c.config.Username, Pass: c.config.Password}.AsMechanism()))
+	}
+	if c.config.AutoProvision {
+		common = append(common, kgo.AllowAutoTopicCreation())
+	}
+
+	return append(common, options...), nil
+}
+
+// existingTopics returns the names of the Kafka topics which currently exist.
+func (c *Client) existingTopics(ctx context.Context, producer *kgo.Client) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.connectTimeout())
+	defer cancel()
+
+	// Without topics the metadata of all the topics is returned
+	response, err := kmsg.NewPtrMetadataRequest().RequestWith(ctx, producer)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the Kafka topics: %w", err)
+	}
+
+	topics := make([]string, 0, len(response.Topics))
+	for _, topic := range response.Topics {
+		if topic.Topic != nil && topic.ErrorCode == 0 {
+			topics = append(topics, *topic.Topic)
+		}
+	}
+
+	return topics, nil
+}
+
+// deleteTopic deletes the Kafka topic, ignoring the failures as the topic may never have been created.
+func (c *Client) deleteTopic(topic string) {
+	producer := c.connection()
+	if producer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout())
+	defer cancel()
+
+	request := kmsg.NewPtrDeleteTopicsRequest()
+	request.TimeoutMillis = int32(c.connectTimeout().Milliseconds())
+	request.TopicNames = []string{topic}
+	requestTopic := kmsg.NewDeleteTopicsRequestTopic()
+	requestTopic.Topic = kmsg.StringPtr(topic)
+	request.Topics = []kmsg.DeleteTopicsRequestTopic{requestTopic}
+
+	_, _ = request.RequestWith(ctx, producer)
+}
+
+// consume polls the records of the subscription until it is stopped.
+func (c *Client) consume(s *subscription) {
+	defer c.consumers.Done()
+	if s.responseTopic != "" {
+		defer c.deleteTopic(s.responseTopic)
+	}
+	defer s.consumer.Close()
+
+	for {
+		fetches := s.consumer.PollFetches(s.ctx)
+		if s.ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+
+		fetches.EachError(func(topic string, _ int32, err error) {
+			if !errors.Is(err, context.Canceled) {
+				s.SendError(fmt.Errorf("unable to consume '%s' Kafka topic: %w", topic, err))
+			}
+		})
+
+		var delivered []*kgo.Record
+		for records := fetches.RecordIter(); !records.Done(); {
+			record := records.Next()
+
+			message := types.MessageEnvelope{}
+			if err := codec.Decode(record.Value, &message, c.codec); err != nil {
+				// The record can never be processed, so it is committed with the delivered ones to move past it
+				delivered = append(delivered, record)
+				s.SendError(fmt.Errorf("unable to unmarshal message: %w", err))
+				continue
+			}
+
+			message.ReceivedTopic = ConvertFromKafkaTopic(record.Topic)
+
+			// The remaining records are not committed, so they are delivered again to the group
+			if !s.Send(message) {
+				break
+			}
+			delivered = append(delivered, record)
+		}
+
+		if s.group == "" || len(delivered) == 0 {
+			continue
+		}
+
+		// Committed even when stopping so that the delivered records are not delivered again
+		ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout())
+		if err := s.consumer.CommitRecords(ctx, delivered...); err != nil {
+			s.SendError(fmt.Errorf("unable to commit the offsets of '%s' group: %w", s.group, err))
+		}
+		cancel()
+	}
+}
+
+// stop cancels the poll in progress and stops the delivery.
+func (s *subscription) stop() {
+	s.cancel()
+	s.Stop()
+}
+
+func matchesAny(expression *regexp.Regexp, topics []string) bool {
+	for _, topic := range topics {
+		if expression.MatchString(topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isWildcardTopic(topic string) bool {
+	return strings.ContainsAny(topic, StandardWildcard+SingleLevelWildcard)
+}
+
+// ConvertToKafkaTopic converts the standard MQTT style topic scheme of "/" separated levels to the Kafka topic name
+// of "." separated levels.
+func ConvertToKafkaTopic(topic string) string {
+	return strings.Replace(topic, StandardTopicSeparator, KafkaTopicSeparator, -1)
+}
+
+// ConvertFromKafkaTopic converts the Kafka topic name of "." separated levels to the standard MQTT style topic scheme
+// of "/" separated levels.
+func ConvertFromKafkaTopic(kafkaTopic string) string {
+	return strings.Replace(kafkaTopic, KafkaTopicSeparator, StandardTopicSeparator, -1)
+}
+
+// TopicFilterToRegex converts the standard MQTT style topic filter with "#" & "+" wildcards to the regular expression
+// matching the corresponding Kafka topic names.
+func TopicFilterToRegex(topic string) string {
+	levels := strings.Split(topic, StandardTopicSeparator)
+
+	var expression strings.Builder
+	for i, level := range levels {
+		switch {
+		case level == StandardWildcard && i == 0:
+			expression.WriteString(".*")
+			continue
+		case level == StandardWildcard:
+			// The multi-level wildcard also matches the parent level
+			expression.WriteString("(" + regexp.QuoteMeta(KafkaTopicSeparator) + ".*)?")
+			continue
+		case i > 0:
+			expression.WriteString(regexp.QuoteMeta(KafkaTopicSeparator))
+		}
+
+		if level == SingleLevelWildcard {
+			expression.WriteString(`[^.]*`)
+		} else {
+			expression.WriteString(regexp.QuoteMeta(level))
+		}
+	}
+
+	return "^" + expression.String() + "$"
+}
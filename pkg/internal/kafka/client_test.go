@@ -0,0 +1,334 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+const testTimeout = 10 * time.Second
+
+func startFakeCluster(t *testing.T) *kfake.Cluster {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.AllowAutoTopicCreation(), kfake.DefaultNumPartitions(3))
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+
+	return cluster
+}
+
+func brokerInfo(t *testing.T, cluster *kfake.Cluster) types.HostInfo {
+	host, port, err := net.SplitHostPort(cluster.ListenAddrs()[0])
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return types.HostInfo{Host: host, Port: portNumber, Protocol: "kafka"}
+}
+
+func newTestClient(t *testing.T, cluster *kfake.Cluster, optional map[string]string) *Client {
+	client, err := NewClient(types.MessageBusConfig{Broker: brokerInfo(t, cluster), Type: "kafka", Optional: optional})
+	require.NoError(t, err)
+	client.refreshInterval = 100 * time.Millisecond
+
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func newRawTestClient(t *testing.T, cluster *kfake.Cluster, options ...kgo.Opt) *kgo.Client {
+	client, err := kgo.NewClient(append([]kgo.Opt{kgo.SeedBrokers(cluster.ListenAddrs()...)}, options...)...)
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func assertNoMessage(t *testing.T, messages chan types.MessageEnvelope) {
+	select {
+	case message := <-messages:
+		assert.Fail(t, "unexpected message", message.CorrelationID)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestTopicConversion(t *testing.T) {
+	assert.Equal(t, "edgex.events.device1", ConvertToKafkaTopic("edgex/events/device1"))
+	assert.Equal(t, "edgex/events/device1", ConvertFromKafkaTopic("edgex.events.device1"))
+}
+
+func TestTopicFilterToRegex(t *testing.T) {
+	tests := []struct {
+		filter     string
+		matches    []string
+		nonMatches []string
+	}{
+		{"edgex/events", []string{"edgex.events"}, []string{"edgex.events.device1", "edgexXevents"}},
+		{"edgex/events/#", []string{"edgex.events", "edgex.events.device1", "edgex.events.a.b"}, []string{"edgex.eventsX", "edgex.commands"}},
+		{"edgex/+/device1", []string{"edgex.events.device1", "edgex..device1"}, []string{"edgex.events.device2", "edgex.a.b.device1"}},
+		{"#", []string{"edgex", "edgex.events"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expression := TopicFilterToRegex(tt.filter)
+			for _, topic := range tt.matches {
+				assert.Regexp(t, expression, topic)
+			}
+			for _, topic := range tt.nonMatches {
+				assert.NotRegexp(t, expression, topic)
+			}
+		})
+	}
+}
+
+func TestClientConnectError(t *testing.T) {
+	client, err := NewClient(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: "127.0.0.1", Port: 1, Protocol: "kafka"},
+		Optional: map[string]string{"ConnectTimeout": "1"},
+	})
+	require.NoError(t, err)
+
+	require.Error(t, client.Connect())
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	cluster := startFakeCluster(t)
+	client := newTestClient(t, cluster, nil)
+
+	// Messages published before subscribing to an existing topic are not delivered
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "old"}, "edgex/events"))
+	time.Sleep(10 * time.Millisecond)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	expected := types.NewMessageEnvelope([]byte("payload"), context.Background())
+	require.NoError(t, client.Publish(expected, "edgex/events"))
+
+	message := testutil.ReceiveMessage(t, messages, testTimeout)
+	assert.Equal(t, expected.CorrelationID, message.CorrelationID)
+	assert.Equal(t, expected.Payload, message.Payload)
+	assert.Equal(t, "edgex/events", message.ReceivedTopic)
+}
+
+func TestClientPublishInvalidTopic(t *testing.T) {
+	client := newTestClient(t, startFakeCluster(t), nil)
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, ""))
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/#"))
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events:1"))
+}
+
+func TestClientWildcardSubscription(t *testing.T) {
+	cluster := startFakeCluster(t)
+	client := newTestClient(t, cluster, nil)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events/#", Messages: messages}}, make(chan error)))
+
+	// Topics created after subscribing are discovered and read from their beginning
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "new"}, "edgex/events/device1"))
+	message := testutil.ReceiveMessage(t, messages, testTimeout)
+	assert.Equal(t, "new", message.CorrelationID)
+	assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "other"}, "edgex/commands/device1"))
+	assertNoMessage(t, messages)
+}
+
+func TestClientPartitionKey(t *testing.T) {
+	cluster := startFakeCluster(t)
+
+	tests := []struct {
+		partitionKey string
+		message      types.MessageEnvelope
+		expectedKey  string
+	}{
+		{PartitionKeyCorrelationID, types.MessageEnvelope{CorrelationID: "correlation"}, "correlation"},
+		{PartitionKeyRequestID, types.MessageEnvelope{RequestID: "request"}, "request"},
+		{"QueryParams.device", types.MessageEnvelope{QueryParams: map[string]string{"device": "device1"}}, "device1"},
+		{"QueryParams.device", types.MessageEnvelope{}, ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.partitionKey, func(t *testing.T) {
+			topic := fmt.Sprintf("edgex/keys%d", i)
+			client := newTestClient(t, cluster, map[string]string{"PartitionKey": tt.partitionKey})
+
+			// The messages with the same key are kept on the same partition
+			for j := 0; j < 5; j++ {
+				require.NoError(t, client.Publish(tt.message, topic))
+			}
+
+			consumer := newRawTestClient(t, cluster, kgo.ConsumeTopics(ConvertToKafkaTopic(topic)),
+				kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+
+			var records []*kgo.Record
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+			for len(records) < 5 && ctx.Err() == nil {
+				records = append(records, consumer.PollFetches(ctx).Records()...)
+			}
+			require.Len(t, records, 5)
+
+			for _, record := range records {
+				assert.Equal(t, tt.expectedKey, string(record.Key))
+				if tt.expectedKey != "" {
+					assert.Equal(t, records[0].Partition, record.Partition)
+				}
+			}
+		})
+	}
+}
+
+func TestClientResumesFromCommittedOffsets(t *testing.T) {
+	cluster := startFakeCluster(t)
+	optional := map[string]string{"ClientId": "service"}
+	publisher := newTestClient(t, cluster, nil)
+
+	client := newTestClient(t, cluster, optional)
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "delivered"}, "edgex/events"))
+	assert.Equal(t, "delivered", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	require.NoError(t, client.Disconnect())
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "while-down"}, "edgex/events"))
+
+	restarted := newTestClient(t, cluster, optional)
+	messages = make(chan types.MessageEnvelope)
+	require.NoError(t, restarted.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	// The delivered message was committed so only the one published while down is delivered
+	assert.Equal(t, "while-down", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	assertNoMessage(t, messages)
+}
+
+func TestClientQueueGroup(t *testing.T) {
+	cluster := startFakeCluster(t)
+	messages := make(chan types.MessageEnvelope, 20)
+
+	for i := 0; i < 2; i++ {
+		client := newTestClient(t, cluster, map[string]string{"QueueGroup": "group", "Deliver": "all"})
+		require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	}
+
+	publisher := newTestClient(t, cluster, map[string]string{"PartitionKey": PartitionKeyCorrelationID})
+	for i := 0; i < 10; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: strconv.Itoa(i)}, "edgex/events"))
+	}
+
+	// The group members share the partitions, a rebalance may deliver a message again but none is lost
+	received := make(map[string]bool)
+	for len(received) < 10 {
+		received[testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID] = true
+	}
+}
+
+func TestClientRequest(t *testing.T) {
+	cluster := startFakeCluster(t)
+	responder := newTestClient(t, cluster, nil)
+	requester := newTestClient(t, cluster, map[string]string{"ClientId": "requester"})
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+
+	// Neither the response topic nor a consumer group of the requester are left behind
+	raw := newRawTestClient(t, cluster)
+	assert.Eventually(t, func() bool {
+		topics, err := requester.existingTopics(context.Background(), raw)
+		return err == nil && !slices.Contains(topics, "edgex.response."+request.RequestID)
+	}, testTimeout, 10*time.Millisecond)
+	groups, err := kmsg.NewPtrListGroupsRequest().RequestWith(context.Background(), raw)
+	require.NoError(t, err)
+	assert.Empty(t, groups.Groups)
+
+	// The cancel notices of the abandoned requests are delivered through the topic shared by the requests
+	notices := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/response/cancel", Messages: notices}}, make(chan error)))
+	internal.PublishCancel(requester.PublishContext, request, "edgex/response")
+	assert.Equal(t, request.RequestID, testutil.ReceiveMessage(t, notices, testTimeout).RequestID)
+
+	topics, err := requester.existingTopics(context.Background(), raw)
+	require.NoError(t, err)
+	assert.NotContains(t, topics, "edgex.response.cancel."+request.RequestID)
+}
+
+func TestClientSubscribeInvalidMessage(t *testing.T) {
+	cluster := startFakeCluster(t)
+	client := newTestClient(t, cluster, nil)
+
+	messageErrors := make(chan error)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, messageErrors))
+
+	raw := newRawTestClient(t, cluster, kgo.AllowAutoTopicCreation())
+	require.NoError(t, raw.ProduceSync(context.Background(), &kgo.Record{Topic: "edgex.events", Value: []byte("not json")}).FirstErr())
+
+	select {
+	case err := <-messageErrors:
+		assert.Error(t, err)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestClientSubscribeDuplicateTopic(t *testing.T) {
+	client := newTestClient(t, startFakeCluster(t), nil)
+
+	topics := []types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	client := newTestClient(t, startFakeCluster(t), nil)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Unsubscribe("edgex/events"))
+	close(messages)
+
+	// Publishing after the channel is closed must not panic the consuming go routine
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	time.Sleep(100 * time.Millisecond)
+
+	// The topic can be subscribed again
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+}
+
+func TestClientDisconnected(t *testing.T) {
+	client := newTestClient(t, startFakeCluster(t), nil)
+	require.NoError(t, client.Disconnect())
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	require.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, client.Disconnect())
+}
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	// DefaultConnectTimeout is the number of seconds to wait for the brokers when not configured.
+	DefaultConnectTimeout = 30
+
+	// PartitionKeyCorrelationID selects the envelope's CorrelationID as partition key.
+	PartitionKeyCorrelationID = "CorrelationID"
+	// PartitionKeyRequestID selects the envelope's RequestID as partition key.
+	PartitionKeyRequestID = "RequestID"
+	// PartitionKeyQueryParamPrefix prefixes the name of the envelope's QueryParams entry selected as partition key,
+	// e.g. "QueryParams.deviceName".
+	PartitionKeyQueryParamPrefix = "QueryParams."
+
+	// DeliverNew starts new consumer groups at the messages published after subscribing.
+	DeliverNew = "new"
+	// DeliverAll starts new consumer groups at the oldest messages retained by the topics.
+	DeliverAll = "all"
+)
+
+// ClientConfig contains all the configurations for the Kafka client.
+type ClientConfig struct {
+	BrokerURL string
+	ClientOptions
+	internal.TlsConfigurationOptions
+}
+
+// ClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type ClientOptions struct {
+	// Client Identifiers, the Username and Password are used for SASL PLAIN authentication
+	Username string
+	Password string
+	ClientId string
+
+	// Consumer groups, the offsets of the messages delivered are committed to the group. Without QueueGroup the
+	// ClientId is the group, and without either the subscriptions read the topics without a group.
+	QueueGroup string
+	Deliver    string // Where new consumer groups start, "new" or "all"
+
+	PartitionKey   string // Envelope field used as partition key, "CorrelationID", "RequestID" or "QueryParams.<name>"
+	AutoProvision  bool   // Create the topics when missing, provided the brokers allow it
+	ConnectTimeout int    // Seconds, also bounds how long a publish waits for the brokers
+	Format         string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+}
+
+// NewClientConfiguration creates a ClientConfig based on the provided MessageBusConfig.
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	brokerURL := messageBusConfig.Broker.GetHostURL()
+	if _, err := url.Parse(brokerURL); err != nil {
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("Failed to parse broker: %v", err))
+	}
+
+	options := ClientOptions{
+		Deliver:        DeliverNew,
+		AutoProvision:  true,
+		ConnectTimeout: DefaultConnectTimeout,
+	}
+	if err := internal.Load(messageBusConfig.Optional, &options); err != nil {
+		return ClientConfig{}, err
+	}
+
+	if options.Deliver != DeliverNew && options.Deliver != DeliverAll {
+		return ClientConfig{}, fmt.Errorf("invalid %s '%s', must be '%s' or '%s'", internal.Deliver, options.Deliver,
+			DeliverNew, DeliverAll)
+	}
+
+	switch {
+	case options.PartitionKey == "", options.PartitionKey == PartitionKeyCorrelationID,
+		options.PartitionKey == PartitionKeyRequestID:
+	case strings.HasPrefix(options.PartitionKey, PartitionKeyQueryParamPrefix) &&
+		len(options.PartitionKey) > len(PartitionKeyQueryParamPrefix):
+	default:
+		return ClientConfig{}, fmt.Errorf("invalid %s '%s', must be '%s', '%s' or '%s<name>'", internal.PartitionKey,
+			options.PartitionKey, PartitionKeyCorrelationID, PartitionKeyRequestID, PartitionKeyQueryParamPrefix)
+	}
+
+	if _, err := codec.Lookup(options.Format); err != nil {
+		return ClientConfig{}, err
+	}
+
+	tlsConfig := internal.CreateDefaultTlsConfigurationOptions()
+	if err := internal.Load(messageBusConfig.Optional, &tlsConfig); err != nil {
+		return ClientConfig{}, err
+	}
+
+	return ClientConfig{
+		BrokerURL:               brokerURL,
+		ClientOptions:           options,
+		TlsConfigurationOptions: tlsConfig,
+	}, nil
+}
+
+// partitionKey returns the key of the record carrying the message, nil when no partition key is configured or the
+// selected field is empty, in which case the records are spread over the partitions.
+func (c ClientConfig) partitionKey(message types.MessageEnvelope) []byte {
+	var key string
+	switch {
+	case c.PartitionKey == PartitionKeyCorrelationID:
+		key = message.CorrelationID
+	case c.PartitionKey == PartitionKeyRequestID:
+		key = message.RequestID
+	case strings.HasPrefix(c.PartitionKey, PartitionKeyQueryParamPrefix):
+		key = message.QueryParams[strings.TrimPrefix(c.PartitionKey, PartitionKeyQueryParamPrefix)]
+	}
+
+	if key == "" {
+		return nil
+	}
+
+	return []byte(key)
+}
+
+// groupName returns the consumer group of the subscription to the topic, empty when not consuming with a group.
+// Each topic has its own group so that overlapping subscriptions of the same client each receive the messages.
+func (c ClientConfig) groupName(topic string) string {
+	group := c.QueueGroup
+	if group == "" {
+		group = c.ClientId
+	}
+	if group == "" {
+		return ""
+	}
+
+	return group + ":" + topic
+}
+
+// startOffset returns where the subscriptions made at the specified time start when their group has no committed
+// offset yet, depending on whether topics matching the subscription already existed.
+func (c ClientConfig) startOffset(subscribed time.Time, topicsExist bool) kgo.Offset {
+	// All the messages of topics created after subscribing are new, such as the response topics of the Request API.
+	if c.Deliver == DeliverAll || !topicsExist {
+		return kgo.NewOffset().AtStart()
+	}
+
+	// Starting at the time of the subscription rather than at the end of the partitions ensures the messages
+	// published right after subscribing are not missed while the consumer starts.
+	return kgo.NewOffset().AfterMilli(subscribed.UnixMilli())
+}
@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestNewClientConfiguration(t *testing.T) {
+	broker := types.HostInfo{Host: "localhost", Port: 9092, Protocol: "kafka"}
+
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		want    ClientOptions
+		wantErr bool
+	}{
+		{
+			name:   "Defaults",
+			config: types.MessageBusConfig{Broker: broker},
+			want:   ClientOptions{Deliver: DeliverNew, AutoProvision: true, ConnectTimeout: DefaultConnectTimeout},
+		},
+		{
+			name: "All options",
+			config: types.MessageBusConfig{
+				Broker: broker,
+				Optional: map[string]string{
+					"Username":       "user",
+					"Password":       "secret",
+					"ClientId":       "client",
+					"QueueGroup":     "group",
+					"Deliver":        "all",
+					"PartitionKey":   "QueryParams.device",
+					"AutoProvision":  "false",
+					"ConnectTimeout": "5",
+				},
+			},
+			want: ClientOptions{
+				Username:       "user",
+				Password:       "secret",
+				ClientId:       "client",
+				QueueGroup:     "group",
+				Deliver:        DeliverAll,
+				PartitionKey:   "QueryParams.device",
+				AutoProvision:  false,
+				ConnectTimeout: 5,
+			},
+		},
+		{
+			name:    "Invalid Deliver",
+			config:  types.MessageBusConfig{Broker: broker, Optional: map[string]string{"Deliver": "last"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid PartitionKey",
+			config:  types.MessageBusConfig{Broker: broker, Optional: map[string]string{"PartitionKey": "Payload"}},
+			wantErr: true,
+		},
+		{
+			name:    "PartitionKey without query parameter name",
+			config:  types.MessageBusConfig{Broker: broker, Optional: map[string]string{"PartitionKey": "QueryParams."}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid ConnectTimeout",
+			config:  types.MessageBusConfig{Broker: broker, Optional: map[string]string{"ConnectTimeout": "soon"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientConfiguration(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "kafka://localhost:9092", got.BrokerURL)
+			assert.Equal(t, tt.want, got.ClientOptions)
+		})
+	}
+}
+
+func TestGroupName(t *testing.T) {
+	assert.Equal(t, "group:edgex/#", ClientConfig{ClientOptions: ClientOptions{QueueGroup: "group", ClientId: "client"}}.groupName("edgex/#"))
+	assert.Equal(t, "client:edgex/#", ClientConfig{ClientOptions: ClientOptions{ClientId: "client"}}.groupName("edgex/#"))
+	assert.Empty(t, ClientConfig{}.groupName("edgex/#"))
+}
+
+func TestStartOffset(t *testing.T) {
+	subscribed := time.UnixMilli(1000)
+
+	assert.Equal(t, kgo.NewOffset().AtStart(), ClientConfig{ClientOptions: ClientOptions{Deliver: DeliverAll}}.startOffset(subscribed, true))
+	assert.Equal(t, kgo.NewOffset().AtStart(), ClientConfig{ClientOptions: ClientOptions{Deliver: DeliverNew}}.startOffset(subscribed, false))
+	assert.Equal(t, kgo.NewOffset().AfterMilli(1000), ClientConfig{ClientOptions: ClientOptions{Deliver: DeliverNew}}.startOffset(subscribed, true))
+}
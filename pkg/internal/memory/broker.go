@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"sync"
+)
+
+var (
+	brokers      = make(map[string]*broker)
+	brokersMutex sync.Mutex
+)
+
+// broker routes the messages published by all the clients sharing the same bus name within the process.
+type broker struct {
+	subscriptions map[*subscription]bool
+	mutex         sync.RWMutex
+}
+
+// getBroker returns the broker for the bus name, creating it on first use.
+func getBroker(name string) *broker {
+	brokersMutex.Lock()
+	defer brokersMutex.Unlock()
+
+	b, exists := brokers[name]
+	if !exists {
+		b = &broker{subscriptions: make(map[*subscription]bool)}
+		brokers[name] = b
+	}
+
+	return b
+}
+
+func (b *broker) add(s *subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscriptions[s] = true
+}
+
+func (b *broker) remove(s *subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.subscriptions, s)
+}
+
+// publish queues a copy of the message for every subscription whose topic filter matches the topic. The subscriptions
+// whose queue is full drop the message, which is reported without waiting for the subscriber. The errors channel is
+// still open since the subscriptions are removed before being stopped.
+func (b *broker) publish(message types.MessageEnvelope, topic string) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for s := range b.subscriptions {
+		if s.Matches(topic) && !s.Enqueue(internal.CopyEnvelope(message, topic)) {
+			select {
+			case s.errors <- fmt.Errorf("subscription queue of '%s' full, message published to '%s' dropped", s.Filter(), topic):
+			default:
+			}
+		}
+	}
+}
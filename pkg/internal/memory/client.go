@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"sync"
+	"time"
+)
+
+// DefaultBusName is the name of the bus used when the Broker Host is not set.
+const DefaultBusName = "default"
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages through an
+// in-process broker. All the clients created with the same bus name, i.e. Broker Host, within a process share the
+// same broker.
+//
+// Publishing never blocks on a slow subscriber, the messages are queued for it instead. Up to MaxPending messages are
+// queued for each subscription, the messages published while its queue is full are dropped and reported to its
+// errors channel.
+type Client struct {
+	broker     *broker
+	maxPending int
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+}
+
+// ClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type ClientOptions struct {
+	MaxPending int // Messages queued for each subscription, internal.DefaultMaxPending when not set and unbounded when 0
+}
+
+// subscription is a subscription of the client along with the channel its dropped messages are reported to.
+type subscription struct {
+	*internal.Subscription
+	errors chan<- error
+}
+
+// NewClient creates a new Client attached to the in-process broker named by the Broker Host.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	options := ClientOptions{MaxPending: internal.DefaultMaxPending}
+	if err := internal.Load(messageBusConfig.Optional, &options); err != nil {
+		return nil, err
+	}
+
+	if options.MaxPending < 0 {
+		return nil, fmt.Errorf("%s must not be negative", internal.MaxPending)
+	}
+
+	busName := messageBusConfig.Broker.Host
+	if busName == "" {
+		busName = DefaultBusName
+	}
+
+	return &Client{
+		broker:                getBroker(busName),
+		maxPending:            options.MaxPending,
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+	}, nil
+}
+
+// Connect noop as there is no connection to establish.
+func (c *Client) Connect() error {
+	return nil
+}
+
+// Publish sends the provided message to all the subscriptions of the bus whose topic matches.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which fails with the context's error when the context is already done, publishing to the
+// in-process broker never blocks.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	c.broker.publish(message, topic)
+
+	return nil
+}
+
+// Subscribe creates subscriptions for the specified topics. The MQTT '+' and '#' wildcards are supported.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which fails with the context's error when the context is already done, subscribing to
+// the in-process broker never blocks.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		s := &subscription{
+			Subscription: internal.NewBoundedSubscription(topic.Topic, topic.Messages, c.maxPending),
+			errors:       messageErrors,
+		}
+		c.existingSubscriptions[topic.Topic] = s
+		c.broker.add(s)
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe removes the subscriptions for the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		c.broker.remove(s)
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+	}
+
+	return nil
+}
+
+// Disconnect removes all the subscriptions of this client from the bus.
+func (c *Client) Disconnect() error {
+	c.subscriptionMutex.Lock()
+	topics := make([]string, 0, len(c.existingSubscriptions))
+	for topic := range c.existingSubscriptions {
+		topics = append(topics, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	return c.Unsubscribe(topics...)
+}
@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"context"
+	"messaging/pkg/internal"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient creates a client on a bus which is unique to the test so tests can't interfere with each other.
+func newTestClient(t *testing.T, busName string) *Client {
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: busName}})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() {
+		_ = client.Disconnect()
+	})
+
+	return client
+}
+
+func expectNoMessage(t *testing.T, messages chan types.MessageEnvelope) {
+	select {
+	case message := <-messages:
+		require.Failf(t, "unexpected message received", "received on %s", message.ReceivedTopic)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	tests := []struct {
+		name           string
+		subscribeTopic string
+		publishTopic   string
+		expectMessage  bool
+	}{
+		{"Exact topic", "test/exact", "test/exact", true},
+		{"Single level wildcard", "test/+/single", "test/device/single", true},
+		{"Single level wildcard no match", "test/+/nomatch", "test/a/b/nomatch", false},
+		{"Multi level wildcard", "test/multi/#", "test/multi/a/b/c", true},
+		{"Multi level wildcard parent level", "test/parent/#", "test/parent", true},
+		{"No match", "test/other", "test/something", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			busName := uuid.NewString()
+			publisher := newTestClient(t, busName)
+			subscriber := newTestClient(t, busName)
+
+			messages := make(chan types.MessageEnvelope)
+			require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: tt.subscribeTopic, Messages: messages}}, make(chan error)))
+
+			expected := types.NewMessageEnvelopeForRequest([]byte("payload"), map[string]string{"key": "value"})
+			require.NoError(t, publisher.Publish(expected, tt.publishTopic))
+
+			if !tt.expectMessage {
+				expectNoMessage(t, messages)
+				return
+			}
+
+			expected.ReceivedTopic = tt.publishTopic
+			assert.Equal(t, expected, testutil.ReceiveMessage(t, messages, time.Second))
+		})
+	}
+}
+
+func TestClient_SeparateBuses(t *testing.T) {
+	publisher := newTestClient(t, uuid.NewString())
+	subscriber := newTestClient(t, uuid.NewString())
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "#", Messages: messages}}, make(chan error)))
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "test"))
+	expectNoMessage(t, messages)
+}
+
+func TestClient_CopiesMessages(t *testing.T) {
+	client := newTestClient(t, uuid.NewString())
+
+	first := make(chan types.MessageEnvelope, 1)
+	second := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{
+		{Topic: "test/copy", Messages: first},
+		{Topic: "test/#", Messages: second},
+	}, make(chan error)))
+
+	payload := []byte("payload")
+	require.NoError(t, client.Publish(types.MessageEnvelope{
+		Payload:     payload,
+		QueryParams: map[string]string{"a": "b"},
+		Headers:     map[string]string{"c": "d"},
+	}, "test/copy"))
+
+	firstMessage := testutil.ReceiveMessage(t, first, time.Second)
+	secondMessage := testutil.ReceiveMessage(t, second, time.Second)
+	firstMessage.Payload[0] = 'X'
+	firstMessage.QueryParams["a"] = "changed"
+	firstMessage.Headers["c"] = "changed"
+
+	assert.Equal(t, "payload", string(payload))
+	assert.Equal(t, "payload", string(secondMessage.Payload))
+	assert.Equal(t, "b", secondMessage.QueryParams["a"])
+	assert.Equal(t, "d", secondMessage.Headers["c"])
+}
+
+func TestClient_OrderingWithSlowSubscriber(t *testing.T) {
+	client := newTestClient(t, uuid.NewString())
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/order", Messages: messages}}, make(chan error)))
+
+	// Publishing must not block even though nobody is reading the unbuffered channel yet
+	for i := 0; i < 100; i++ {
+		require.NoError(t, client.Publish(types.MessageEnvelope{ErrorCode: i}, "test/order"))
+	}
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, i, testutil.ReceiveMessage(t, messages, time.Second).ErrorCode)
+	}
+}
+
+func TestClient_MaxPending(t *testing.T) {
+	busName := uuid.NewString()
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: busName}, Optional: map[string]string{internal.MaxPending: "2"}})
+	require.NoError(t, err)
+	defer func() { _ = client.Disconnect() }()
+
+	messages := make(chan types.MessageEnvelope)
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/pending", Messages: messages}}, errs))
+
+	// The subscriber is slow, so the messages published once the queue is full are dropped
+	for i := 0; i < 10; i++ {
+		require.NoError(t, client.Publish(types.MessageEnvelope{ErrorCode: i}, "test/pending"))
+	}
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "message published to 'test/pending' dropped")
+	case <-time.After(time.Second):
+		require.Fail(t, "dropped message not reported")
+	}
+
+	assert.Equal(t, 0, testutil.ReceiveMessage(t, messages, time.Second).ErrorCode)
+	assert.Equal(t, 1, testutil.ReceiveMessage(t, messages, time.Second).ErrorCode)
+	// The third one is queued as well when the delivery of the first one started before it was published
+	select {
+	case message := <-messages:
+		assert.Equal(t, 2, message.ErrorCode)
+	case <-time.After(50 * time.Millisecond):
+	}
+	expectNoMessage(t, messages)
+
+	_, err = NewClient(types.MessageBusConfig{Optional: map[string]string{internal.MaxPending: "-1"}})
+	require.Error(t, err)
+}
+
+func TestClient_InvalidTopics(t *testing.T) {
+	client := newTestClient(t, uuid.NewString())
+
+	assert.IsType(t, internal.InvalidTopicErr{}, client.Publish(types.MessageEnvelope{}, ""))
+	assert.IsType(t, internal.InvalidTopicErr{}, client.Publish(types.MessageEnvelope{}, "test/#"))
+	assert.IsType(t, internal.InvalidTopicErr{}, client.Subscribe([]types.TopicChannel{{Topic: "test/#/bad"}}, nil))
+
+	topics := []types.TopicChannel{{Topic: "test/duplicate", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, nil))
+	require.Error(t, client.Subscribe(topics, nil))
+}
+
+func TestClient_Unsubscribe(t *testing.T) {
+	client := newTestClient(t, uuid.NewString())
+
+	topic := "test/unsubscribe"
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error)))
+
+	// Queue a message which is never read, unsubscribe must still return and discard it
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, topic))
+	require.NoError(t, client.Unsubscribe(topic, "test/not-subscribed"))
+	assert.Empty(t, client.existingSubscriptions)
+
+	// Closing the channel is safe once unsubscribed
+	close(messages)
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, topic))
+
+	messages = make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, topic))
+	testutil.ReceiveMessage(t, messages, time.Second)
+}
+
+func TestClient_Disconnect(t *testing.T) {
+	busName := uuid.NewString()
+	publisher := newTestClient(t, busName)
+	subscriber := newTestClient(t, busName)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "#", Messages: messages}}, make(chan error)))
+	require.NoError(t, subscriber.Disconnect())
+	assert.Empty(t, subscriber.broker.subscriptions)
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "test"))
+	expectNoMessage(t, messages)
+}
+
+func TestClient_Request(t *testing.T) {
+	busName := uuid.NewString()
+	requester := newTestClient(t, busName)
+	responder := newTestClient(t, busName)
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "test/request/+", Messages: requests}}, make(chan error)))
+
+	go func() {
+		for request := range requests {
+			response, _ := types.NewMessageEnvelopeForResponse(request.Payload, request.RequestID, request.CorrelationID, types.ContentTypeText)
+			_ = responder.Publish(response, "test/response/"+request.RequestID)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request := types.NewMessageEnvelopeForRequest([]byte(uuid.NewString()), nil)
+			response, err := requester.Request(request, "test/request/echo", "test/response", time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, request.RequestID, response.RequestID)
+			assert.Equal(t, request.Payload, response.Payload)
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, requester.existingSubscriptions)
+
+	_, err := requester.Request(types.NewMessageEnvelopeForRequest(nil, nil), "test/nobody", "test/response", 10*time.Millisecond)
+	require.Error(t, err)
+}
+
+func TestClient_Context(t *testing.T) {
+	client := newTestClient(t, uuid.NewString())
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.PublishContext(cancelled, types.MessageEnvelope{}, "test/topic")
+	require.ErrorIs(t, err, context.Canceled)
+
+	err = client.SubscribeContext(cancelled, []types.TopicChannel{{Topic: "test/topic", Messages: make(chan types.MessageEnvelope)}}, make(chan error))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, client.existingSubscriptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = client.RequestContext(ctx, types.NewMessageEnvelopeForRequest(nil, nil), "test/nobody", "test/response")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, client.existingSubscriptions)
+}
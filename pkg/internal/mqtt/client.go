@@ -0,0 +1,378 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"os"
+	"sync"
+	"time"
+
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ClientCreator defines the function signature for creating an MQTT client.
+//
+// This is mostly used for testing purposes so that the underlying client can be configured or replaced.
+type ClientCreator func(config MQTTClientConfig, handler pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error)
+
+// MessageMarshaller defines the function signature for marshaling structs into []byte.
+type MessageMarshaller func(v interface{}) ([]byte, error)
+
+// MessageUnmarshaller defines the function signature for unmarshaling []byte into structs.
+type MessageUnmarshaller func(data []byte, v interface{}) error
+
+const (
+	ConnectOperation     = "connect"
+	PublishOperation     = "publish"
+	SubscribeOperation   = "subscribe"
+	UnsubscribeOperation = "unsubscribe"
+
+	// disconnectQuiesce is the number of milliseconds to wait for existing work to complete on Disconnect.
+	disconnectQuiesce = 250
+)
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages using
+// an MQTT 3.1.1 broker.
+type Client struct {
+	mqttClient   pahoMqtt.Client
+	options      MQTTClientOptions
+	marshaller   MessageMarshaller
+	unmarshaller MessageUnmarshaller
+
+	// Used to avoid multiple subscriptions to the same topic and to restore subscriptions after a reconnect
+	activeSubscriptions map[string]activeSubscription
+	subscriptionMutex   *sync.Mutex
+}
+
+type activeSubscription struct {
+	*internal.Delivery
+
+	qos     byte
+	handler pahoMqtt.MessageHandler
+	errors  chan error
+}
+
+// NewMQTTClient constructs a new MQTT client based on the provided configuration. The envelopes are encoded with the
+// codec of the configured Format, see codec.Lookup.
+func NewMQTTClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	clientConfiguration, err := CreateMQTTClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(clientConfiguration.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMQTTClientWithCreator(messageBusConfig, codecMarshaller(envelopeCodec), codecUnmarshaller(envelopeCodec),
+		DefaultClientCreator())
+}
+
+// NewMQTTClientWithCreator constructs a new MQTT client based on the provided configuration while allowing more
+// control on the marshaling and the creation of the underlying MQTT client.
+func NewMQTTClientWithCreator(
+	messageBusConfig types.MessageBusConfig,
+	marshaller MessageMarshaller,
+	unmarshaller MessageUnmarshaller,
+	creator ClientCreator) (*Client, error) {
+
+	clientConfiguration, err := CreateMQTTClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		options:             clientConfiguration.MQTTClientOptions,
+		marshaller:          marshaller,
+		unmarshaller:        unmarshaller,
+		activeSubscriptions: make(map[string]activeSubscription),
+		subscriptionMutex:   new(sync.Mutex),
+	}
+
+	client.mqttClient, err = creator(clientConfiguration, client.onConnectHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// DefaultClientCreator returns a default function for creating MQTT clients.
+func DefaultClientCreator() ClientCreator {
+	return func(clientConfiguration MQTTClientConfig, handler pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error) {
+		clientOptions, err := createClientOptions(clientConfiguration, tls.X509KeyPair, tls.LoadX509KeyPair,
+			x509.ParseCertificate, os.ReadFile, pem.Decode)
+		if err != nil {
+			return nil, err
+		}
+
+		clientOptions.OnConnect = handler
+
+		return pahoMqtt.NewClient(clientOptions), nil
+	}
+}
+
+// Connect establishes a connection to a MQTT server.
+// This must be called before any other functionality provided by the Client.
+func (mc *Client) Connect() error {
+	// Avoid reconnecting if already connected.
+	if mc.mqttClient.IsConnected() {
+		return nil
+	}
+
+	return getTokenError(
+		mc.mqttClient.Connect(),
+		mc.connectTimeout(),
+		ConnectOperation,
+		"Unable to connect")
+}
+
+// Publish sends a message to the connected MQTT server.
+func (mc *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return mc.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which stops waiting for the publish to complete when the context is done.
+func (mc *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if topic == "" {
+		// Empty topics are not allowed for MQTT
+		return internal.NewInvalidTopicErr("", "Unable to publish to the invalid topic")
+	}
+
+	marshaledMessage, err := mc.marshaller(message)
+	if err != nil {
+		return NewOperationErr(PublishOperation, err.Error())
+	}
+
+	return getTokenErrorContext(
+		ctx,
+		mc.mqttClient.Publish(
+			topic,
+			byte(mc.options.Qos),
+			mc.options.Retained,
+			marshaledMessage),
+		mc.connectTimeout(),
+		PublishOperation,
+		"Unable to publish message")
+}
+
+// Subscribe creates a subscription for the specified topics. The MQTT '+' and '#' wildcards are supported.
+func (mc *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return mc.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which stops waiting for the subscriptions to complete when the context is done. The
+// subscriptions created by then are kept, use Unsubscribe to remove them.
+func (mc *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mc.subscriptionMutex.Lock()
+	defer mc.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if _, exists := mc.activeSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		delivery := internal.NewDelivery(topic.Messages, messageErrors)
+		subscription := activeSubscription{
+			Delivery: delivery,
+			qos:      byte(mc.options.Qos),
+			handler:  newMessageHandler(mc.unmarshaller, delivery),
+			errors:   messageErrors,
+		}
+
+		err := getTokenErrorContext(
+			ctx,
+			mc.mqttClient.Subscribe(topic.Topic, subscription.qos, subscription.handler),
+			mc.connectTimeout(),
+			SubscribeOperation,
+			"Failed to create subscription")
+		if err != nil {
+			delivery.Stop()
+			return err
+		}
+
+		mc.activeSubscriptions[topic.Topic] = subscription
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (mc *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return mc.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (mc *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, mc.SubscribeContext, mc.Unsubscribe, mc.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (mc *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, mc.SubscribeContext, mc.Unsubscribe, mc.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe removes the subscriptions for the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions, even when unsubscribing from the broker fails.
+func (mc *Client) Unsubscribe(topics ...string) error {
+	mc.subscriptionMutex.Lock()
+	defer mc.subscriptionMutex.Unlock()
+
+	// Stopped first since paho doesn't wait for the handlers already running, and a handler blocked on a full channel
+	// would hold up the delivery of all the messages in ordered mode
+	for _, topic := range topics {
+		if subscription, exists := mc.activeSubscriptions[topic]; exists {
+			subscription.Stop()
+			delete(mc.activeSubscriptions, topic)
+		}
+	}
+
+	return getTokenError(
+		mc.mqttClient.Unsubscribe(topics...),
+		mc.connectTimeout(),
+		UnsubscribeOperation,
+		"Failed to unsubscribe")
+}
+
+// Disconnect stops all subscriptions and closes the connection to the connected MQTT server.
+func (mc *Client) Disconnect() error {
+	mc.subscriptionMutex.Lock()
+	for topic, subscription := range mc.activeSubscriptions {
+		subscription.Stop()
+		delete(mc.activeSubscriptions, topic)
+	}
+	mc.subscriptionMutex.Unlock()
+
+	// Specify a wait time so that any queued processing is allowed to complete before disconnecting.
+	mc.mqttClient.Disconnect(disconnectQuiesce)
+
+	return nil
+}
+
+// onConnectHandler restores the active subscriptions once a connection has been (re)established. When the session
+// is clean the broker will have discarded them, so they must be re-created. The failures are reported to the
+// subscribers ready to receive them, the others miss them.
+func (mc *Client) onConnectHandler(client pahoMqtt.Client) {
+	mc.subscriptionMutex.Lock()
+	defer mc.subscriptionMutex.Unlock()
+
+	for topic, subscription := range mc.activeSubscriptions {
+		err := getTokenError(
+			client.Subscribe(topic, subscription.qos, subscription.handler),
+			mc.connectTimeout(),
+			SubscribeOperation,
+			fmt.Sprintf("Failed to re-create subscription for topic '%s'", topic))
+		if err != nil {
+			// Not waiting for the subscriber, which may be blocked on a call needing the subscriptionMutex
+			select {
+			case subscription.errors <- err:
+			default:
+			}
+		}
+	}
+}
+
+func (mc *Client) connectTimeout() time.Duration {
+	return time.Duration(mc.options.ConnectTimeout) * time.Second
+}
+
+// codecMarshaller returns the MessageMarshaller encoding the envelopes with the codec.
+func codecMarshaller(envelopeCodec codec.Codec) MessageMarshaller {
+	return func(v interface{}) ([]byte, error) {
+		envelope, ok := v.(types.MessageEnvelope)
+		if !ok {
+			return nil, fmt.Errorf("unable to encode %T, only MessageEnvelope is supported", v)
+		}
+
+		return envelopeCodec.Encode(envelope)
+	}
+}
+
+// codecUnmarshaller returns the MessageUnmarshaller decoding the envelopes with the codec detected from the data, or
+// else with the codec.
+func codecUnmarshaller(envelopeCodec codec.Codec) MessageUnmarshaller {
+	return func(data []byte, v interface{}) error {
+		envelope, ok := v.(*types.MessageEnvelope)
+		if !ok {
+			return fmt.Errorf("unable to decode into %T, only *MessageEnvelope is supported", v)
+		}
+
+		return codec.Decode(data, envelope, envelopeCodec)
+	}
+}
+
+// newMessageHandler creates a function which propagates the received messages with the subscription's delivery.
+func newMessageHandler(unmarshaler MessageUnmarshaller, delivery *internal.Delivery) pahoMqtt.MessageHandler {
+	return func(client pahoMqtt.Client, message pahoMqtt.Message) {
+		var messageEnvelope types.MessageEnvelope
+		payload := message.Payload()
+		err := unmarshaler(payload, &messageEnvelope)
+		if err != nil {
+			delivery.SendError(fmt.Errorf("unable to unmarshal payload received on topic '%s': %w", message.Topic(), err))
+			return
+		}
+
+		messageEnvelope.ReceivedTopic = message.Topic()
+
+		delivery.Send(messageEnvelope)
+	}
+}
+
+// getTokenError determines if a Token is in an errored state and if so returns the proper error message. Otherwise,
+// nil is returned.
+//
+// If the token failed to complete within the timeout duration an TimeoutErr will be returned.
+func getTokenError(token pahoMqtt.Token, timeout time.Duration, operation string, defaultTimeoutMessage string) error {
+	return getTokenErrorContext(context.Background(), token, timeout, operation, defaultTimeoutMessage)
+}
+
+// getTokenErrorContext is getTokenError which stops waiting for the token when the context is done, returning the
+// context's error.
+func getTokenErrorContext(ctx context.Context, token pahoMqtt.Token, timeout time.Duration, operation string, defaultTimeoutMessage string) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	hasTimedOut := false
+	select {
+	case <-token.Done():
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		hasTimedOut = true
+	}
+
+	if hasTimedOut && token.Error() != nil {
+		return NewTimeoutError(operation, token.Error().Error())
+	}
+
+	if hasTimedOut && token.Error() == nil {
+		return NewTimeoutError(operation, defaultTimeoutMessage)
+	}
+
+	if token.Error() != nil {
+		return NewOperationErr(operation, token.Error().Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package mqtt
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net/url"
+	"time"
+
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// MQTTClientConfig contains all the configurations for the MQTT client.
+type MQTTClientConfig struct {
+	BrokerURL string
+	MQTTClientOptions
+	internal.TlsConfigurationOptions
+}
+
+// MQTTClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type MQTTClientOptions struct {
+	// Client Identifiers
+	Username string
+	Password string
+	ClientId string
+
+	// Connection information
+	Qos            int
+	KeepAlive      int // Seconds
+	Retained       bool
+	AutoReconnect  bool
+	CleanSession   bool // MQTT Default is true if never set
+	ConnectTimeout int  // Seconds
+
+	Format string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+}
+
+// CreateMQTTClientConfiguration constructs a MQTTClientConfig based on the provided MessageBusConfig.
+func CreateMQTTClientConfiguration(messageBusConfig types.MessageBusConfig) (MQTTClientConfig, error) {
+	brokerURL := messageBusConfig.Broker.GetHostURL()
+	_, err := url.Parse(brokerURL)
+	if err != nil {
+		return MQTTClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("Failed to parse broker: %v", err))
+	}
+
+	mqttClientOptions := CreateMQTTClientOptionsWithDefaults()
+	err = internal.Load(messageBusConfig.Optional, &mqttClientOptions)
+	if err != nil {
+		return MQTTClientConfig{}, err
+	}
+
+	if mqttClientOptions.Qos < 0 || mqttClientOptions.Qos > 2 {
+		return MQTTClientConfig{}, fmt.Errorf("invalid %s value '%d', must be 0, 1 or 2", internal.Qos, mqttClientOptions.Qos)
+	}
+
+	if _, err = codec.Lookup(mqttClientOptions.Format); err != nil {
+		return MQTTClientConfig{}, err
+	}
+
+	tlsConfig := internal.CreateDefaultTlsConfigurationOptions()
+	err = internal.Load(messageBusConfig.Optional, &tlsConfig)
+	if err != nil {
+		return MQTTClientConfig{}, err
+	}
+
+	return MQTTClientConfig{
+		BrokerURL:               brokerURL,
+		MQTTClientOptions:       mqttClientOptions,
+		TlsConfigurationOptions: tlsConfig,
+	}, nil
+}
+
+// CreateMQTTClientOptionsWithDefaults constructs MQTTClientOptions instance with defaults.
+func CreateMQTTClientOptionsWithDefaults() MQTTClientOptions {
+	return MQTTClientOptions{
+		Username: "",
+		Password: "",
+		// Client ID is required or else can cause unexpected errors. This was observed with Eclipse's Mosquito MQTT server.
+		ClientId:       uuid.NewString(),
+		Qos:            0,
+		KeepAlive:      30,
+		Retained:       false,
+		AutoReconnect:  true,
+		CleanSession:   true,
+		ConnectTimeout: 30,
+	}
+}
+
+// createClientOptions constructs mqtt.ClientOptions based on the provided MQTTClientConfig.
+func createClientOptions(
+	clientConfiguration MQTTClientConfig,
+	certCreator internal.X509KeyPairCreator,
+	certLoader internal.X509KeyLoader,
+	caCertCreator internal.X509CaCertCreator,
+	caCertLoader internal.X509CaCertLoader,
+	pemDecoder internal.PEMDecoder) (*pahoMqtt.ClientOptions, error) {
+
+	clientOptions := pahoMqtt.NewClientOptions()
+	clientOptions.AddBroker(clientConfiguration.BrokerURL)
+	clientOptions.SetUsername(clientConfiguration.Username)
+	clientOptions.SetPassword(clientConfiguration.Password)
+	clientOptions.SetClientID(clientConfiguration.ClientId)
+	clientOptions.SetKeepAlive(time.Duration(clientConfiguration.KeepAlive) * time.Second)
+	clientOptions.SetAutoReconnect(clientConfiguration.AutoReconnect)
+	clientOptions.SetCleanSession(clientConfiguration.CleanSession)
+	clientOptions.SetConnectTimeout(time.Duration(clientConfiguration.ConnectTimeout) * time.Second)
+
+	tlsConfiguration, err := internal.GenerateTLSForClientClientOptions(
+		clientConfiguration.BrokerURL,
+		clientConfiguration.TlsConfigurationOptions,
+		certCreator,
+		certLoader,
+		caCertCreator,
+		caCertLoader,
+		pemDecoder)
+	if err != nil {
+		return clientOptions, err
+	}
+
+	clientOptions.SetTLSConfig(tlsConfiguration)
+
+	return clientOptions, nil
+}
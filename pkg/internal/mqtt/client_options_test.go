@@ -0,0 +1,145 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var TestHostInfo = types.HostInfo{
+	Host:     "localhost",
+	Port:     1883,
+	Protocol: "tcp",
+}
+
+func TestCreateMQTTClientConfiguration(t *testing.T) {
+	tests := []struct {
+		name     string
+		optional map[string]string
+		want     MQTTClientOptions
+		wantErr  bool
+	}{
+		{
+			name:     "Defaults",
+			optional: nil,
+			want: MQTTClientOptions{
+				KeepAlive:      30,
+				AutoReconnect:  true,
+				CleanSession:   true,
+				ConnectTimeout: 30,
+			},
+		},
+		{
+			name: "All options",
+			optional: map[string]string{
+				internal.Username:       "user",
+				internal.Password:       "pass",
+				internal.ClientId:       "client",
+				internal.Qos:            "1",
+				internal.KeepAlive:      "5",
+				internal.Retained:       "true",
+				internal.AutoReconnect:  "false",
+				internal.CleanSession:   "false",
+				internal.ConnectTimeout: "2",
+			},
+			want: MQTTClientOptions{
+				Username:       "user",
+				Password:       "pass",
+				ClientId:       "client",
+				Qos:            1,
+				KeepAlive:      5,
+				Retained:       true,
+				AutoReconnect:  false,
+				CleanSession:   false,
+				ConnectTimeout: 2,
+			},
+		},
+		{
+			name:     "Invalid Qos value",
+			optional: map[string]string{internal.Qos: "3"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid Qos type",
+			optional: map[string]string{internal.Qos: "high"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid TLS option",
+			optional: map[string]string{internal.SkipCertVerify: "NotABool"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := CreateMQTTClientConfiguration(types.MessageBusConfig{Broker: TestHostInfo, Optional: tt.optional})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "tcp://localhost:1883", config.BrokerURL)
+			if tt.want.ClientId == "" {
+				// A unique client id is generated when none is provided
+				assert.NotEmpty(t, config.ClientId)
+				tt.want.ClientId = config.ClientId
+			}
+			assert.Equal(t, tt.want, config.MQTTClientOptions)
+		})
+	}
+}
+
+func TestCreateClientOptions(t *testing.T) {
+	config, err := CreateMQTTClientConfiguration(types.MessageBusConfig{
+		Broker: TestHostInfo,
+		Optional: map[string]string{
+			internal.ClientId:       "client",
+			internal.KeepAlive:      "5",
+			internal.ConnectTimeout: "2",
+			internal.CleanSession:   "false",
+		},
+	})
+	require.NoError(t, err)
+
+	options, err := createClientOptions(config, tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate,
+		func(string) ([]byte, error) { return nil, nil }, pem.Decode)
+	require.NoError(t, err)
+
+	assert.Equal(t, "client", options.ClientID)
+	assert.Equal(t, int64(5), options.KeepAlive)
+	assert.Equal(t, 2*time.Second, options.ConnectTimeout)
+	assert.False(t, options.CleanSession)
+	assert.Nil(t, options.TLSConfig)
+	require.Len(t, options.Servers, 1)
+	assert.Equal(t, "tcp://localhost:1883", options.Servers[0].String())
+}
+
+func TestCreateClientOptionsTLS(t *testing.T) {
+	config, err := CreateMQTTClientConfiguration(types.MessageBusConfig{
+		Broker: types.HostInfo{Host: "localhost", Port: 8883, Protocol: "tls"},
+		Optional: map[string]string{
+			internal.CertPEMBlock: "cert",
+			internal.KeyPEMBlock:  "key",
+		},
+	})
+	require.NoError(t, err)
+
+	certCreator := func([]byte, []byte) (tls.Certificate, error) { return tls.Certificate{}, nil }
+	options, err := createClientOptions(config, certCreator, tls.LoadX509KeyPair, x509.ParseCertificate, nil, pem.Decode)
+	require.NoError(t, err)
+	require.NotNil(t, options.TLSConfig)
+	assert.Len(t, options.TLSConfig.Certificates, 1)
+
+	failingCertCreator := func([]byte, []byte) (tls.Certificate, error) { return tls.Certificate{}, errors.New("test error") }
+	_, err = createClientOptions(config, failingCertCreator, tls.LoadX509KeyPair, x509.ParseCertificate, nil, pem.Decode)
+	require.Error(t, err)
+}
@@ -0,0 +1,324 @@
+package mqtt
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net"
+	"testing"
+	"time"
+
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestBroker starts an in-process MQTT broker listening on a random local port and returns its HostInfo.
+func startTestBroker(t *testing.T) types.HostInfo {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := mochi.New(&mochi.Options{
+		InlineClient: false,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+	require.NoError(t, server.AddListener(listeners.NewNet("test", listener)))
+	go func() {
+		_ = server.Serve()
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	return types.HostInfo{
+		Host:     "127.0.0.1",
+		Port:     listener.Addr().(*net.TCPAddr).Port,
+		Protocol: "tcp",
+	}
+}
+
+func newConnectedClient(t *testing.T, hostInfo types.HostInfo, optional map[string]string) *Client {
+	client, err := NewMQTTClient(types.MessageBusConfig{Broker: hostInfo, Optional: optional})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() {
+		_ = client.Disconnect()
+	})
+
+	return client
+}
+
+func TestNewMQTTClient(t *testing.T) {
+	_, err := NewMQTTClient(types.MessageBusConfig{Broker: TestHostInfo})
+	require.NoError(t, err)
+
+	_, err = NewMQTTClient(types.MessageBusConfig{Broker: TestHostInfo, Optional: map[string]string{internal.Qos: "bad"}})
+	require.Error(t, err)
+
+	_, err = NewMQTTClientWithCreator(types.MessageBusConfig{Broker: TestHostInfo}, nil, nil,
+		func(MQTTClientConfig, pahoMqtt.OnConnectHandler) (pahoMqtt.Client, error) {
+			return nil, errors.New("test error")
+		})
+	require.Error(t, err)
+}
+
+func TestClient_ConnectError(t *testing.T) {
+	client, err := NewMQTTClient(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: "127.0.0.1", Port: 1, Protocol: "tcp"},
+		Optional: map[string]string{internal.ConnectTimeout: "1"},
+	})
+	require.NoError(t, err)
+	require.Error(t, client.Connect())
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	hostInfo := startTestBroker(t)
+
+	tests := []struct {
+		name           string
+		subscribeTopic string
+		publishTopic   string
+		qos            string
+		expectMessage  bool
+	}{
+		{"Exact topic", "test/exact", "test/exact", "0", true},
+		{"Exact topic QoS 1", "test/qos1", "test/qos1", "1", true},
+		{"Exact topic QoS 2", "test/qos2", "test/qos2", "2", true},
+		{"Single level wildcard", "test/+/single", "test/device/single", "0", true},
+		{"Single level wildcard no match", "test/+/nomatch", "test/a/b/nomatch", "0", false},
+		{"Multi level wildcard", "test/multi/#", "test/multi/a/b/c", "0", true},
+		{"Multi level wildcard parent level", "test/parent/#", "test/parent", "0", true},
+		{"No match", "test/other", "test/something", "0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newConnectedClient(t, hostInfo, map[string]string{internal.Qos: tt.qos})
+
+			messages := make(chan types.MessageEnvelope, 1)
+			errs := make(chan error, 1)
+			err := client.Subscribe([]types.TopicChannel{{Topic: tt.subscribeTopic, Messages: messages}}, errs)
+			require.NoError(t, err)
+
+			expected := types.MessageEnvelope{
+				CorrelationID: "123",
+				Payload:       []byte("test payload"),
+				ContentType:   types.ContentTypeJSON,
+			}
+			require.NoError(t, client.Publish(expected, tt.publishTopic))
+
+			select {
+			case actual := <-messages:
+				require.True(t, tt.expectMessage, "unexpected message received")
+				assert.Equal(t, tt.publishTopic, actual.ReceivedTopic)
+				assert.Equal(t, expected.CorrelationID, actual.CorrelationID)
+				assert.Equal(t, expected.Payload, actual.Payload)
+			case err := <-errs:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				require.False(t, tt.expectMessage, "timed out waiting for message")
+			}
+		})
+	}
+}
+
+func TestClient_PublishEmptyTopic(t *testing.T) {
+	client, err := NewMQTTClient(types.MessageBusConfig{Broker: TestHostInfo})
+	require.NoError(t, err)
+
+	err = client.Publish(types.MessageEnvelope{}, "")
+	require.Error(t, err)
+	assert.IsType(t, internal.InvalidTopicErr{}, err)
+}
+
+func TestClient_PublishMarshalError(t *testing.T) {
+	client, err := NewMQTTClientWithCreator(types.MessageBusConfig{Broker: TestHostInfo},
+		func(interface{}) ([]byte, error) { return nil, errors.New("test error") }, nil, DefaultClientCreator())
+	require.NoError(t, err)
+
+	err = client.Publish(types.MessageEnvelope{}, "test")
+	require.Error(t, err)
+	assert.IsType(t, OperationErr{}, err)
+}
+
+func TestClient_SubscribeDuplicateTopic(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	topics := []types.TopicChannel{{Topic: "test/duplicate", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestClient_SubscribeUnmarshalError(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/raw", Messages: messages}}, errs))
+
+	token := client.mqttClient.Publish("test/raw", 0, false, []byte("not an envelope"))
+	require.True(t, token.WaitTimeout(time.Second))
+
+	select {
+	case err := <-errs:
+		require.Error(t, err)
+	case <-messages:
+		require.Fail(t, "unexpected message received")
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestClient_Unsubscribe(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	topic := "test/unsubscribe"
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+	require.NoError(t, client.Unsubscribe(topic))
+	assert.Empty(t, client.activeSubscriptions)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, topic))
+	select {
+	case <-messages:
+		require.Fail(t, "unexpected message received after unsubscribe")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// The same topic can be subscribed again once unsubscribed
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+}
+
+func TestClient_UnsubscribeWhileDelivering(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	// Nobody receives, so the handler is blocked delivering the message
+	topic := "test/blocked"
+	messages := make(chan types.MessageEnvelope)
+	errs := make(chan error)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, errs))
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, topic))
+	time.Sleep(100 * time.Millisecond)
+
+	// The channels can be closed once unsubscribed, as the Request API does, without the handler panicking
+	require.NoError(t, client.Unsubscribe(topic))
+	close(messages)
+	close(errs)
+
+	// The other subscriptions aren't held up by the blocked handler
+	other := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/other", Messages: other}}, make(chan error, 1)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "other"}, "test/other"))
+	select {
+	case message := <-other:
+		assert.Equal(t, "other", message.CorrelationID)
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for message")
+	}
+}
+
+func TestClient_Request(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	requester := newConnectedClient(t, hostInfo, nil)
+	responder := newConnectedClient(t, hostInfo, nil)
+
+	requestTopic := "test/request"
+	responseTopicPrefix := "test/response"
+
+	requests := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: requestTopic, Messages: requests}}, make(chan error, 1)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("pong"), request.RequestID, request.CorrelationID, types.ContentTypeText)
+		_ = responder.Publish(response, responseTopicPrefix+"/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("ping"), nil)
+	response, err := requester.Request(request, requestTopic, responseTopicPrefix, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, "pong", string(response.Payload))
+
+	// Response subscription must have been removed
+	assert.Empty(t, requester.activeSubscriptions)
+
+	_, err = requester.Request(types.NewMessageEnvelopeForRequest(nil, nil), "test/nobody", responseTopicPrefix, 100*time.Millisecond)
+	require.Error(t, err)
+}
+
+func TestClient_ResubscribeOnReconnect(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	topic := "test/reconnect"
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+
+	// Simulate a reconnect with a fresh connection which has no subscriptions on the broker
+	client.mqttClient.Disconnect(disconnectQuiesce)
+	require.NoError(t, client.Connect())
+
+	require.Eventually(t, func() bool {
+		_ = client.Publish(types.MessageEnvelope{CorrelationID: "after-reconnect"}, topic)
+		select {
+		case message := <-messages:
+			return message.CorrelationID == "after-reconnect"
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// failingClient is a pahoMqtt.Client whose subscriptions fail.
+type failingClient struct {
+	pahoMqtt.Client
+}
+
+func (failingClient) Subscribe(string, byte, pahoMqtt.MessageHandler) pahoMqtt.Token {
+	return failedToken{}
+}
+
+// failedToken is a completed pahoMqtt.Token with an error.
+type failedToken struct{}
+
+func (failedToken) Wait() bool                     { return true }
+func (failedToken) WaitTimeout(time.Duration) bool { return true }
+func (failedToken) Error() error                   { return errors.New("not authorized") }
+
+func (failedToken) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+func TestClient_ResubscribeFailureDoesNotBlock(t *testing.T) {
+	hostInfo := startTestBroker(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	// Nobody receives the errors of the subscription
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/reconnect", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+
+	restored := make(chan struct{})
+	go func() {
+		client.onConnectHandler(failingClient{})
+		close(restored)
+	}()
+
+	select {
+	case <-restored:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "restoring the subscriptions is blocked on reporting the failure")
+	}
+	require.NoError(t, client.Unsubscribe("test/reconnect"))
+}
@@ -0,0 +1,358 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	StandardTopicSeparator  = "/"
+	NatsSubjectSeparator    = "."
+	StandardWildcard        = "#"
+	SingleLevelWildcard     = "+"
+	NatsWildcard            = ">"
+	NatsSingleLevelWildcard = "*"
+)
+
+// ConnectNats defines the function signature for creating the underlying NATS connection.
+//
+// This is mostly used for testing purposes so that the connection can be replaced.
+type ConnectNats func(config ClientConfig) (Connection, error)
+
+// Connection provides the functionality needed from the underlying NATS connection.
+type Connection interface {
+	QueueSubscribe(subject string, queue string, handler nats.MsgHandler) (*nats.Subscription, error)
+	PublishMsg(msg *nats.Msg) error
+	FlushTimeout(timeout time.Duration) error
+	Drain() error
+}
+
+// ResponseSubscriber is implemented by the Connections which subscribe differently to the response topics of requests,
+// see internal.IsResponseSubscription. The other Connections subscribe to them with QueueSubscribe.
+type ResponseSubscriber interface {
+	SubscribeResponse(subject string, handler nats.MsgHandler) (*nats.Subscription, error)
+}
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages using
+// NATS Core subjects.
+type Client struct {
+	config     ClientConfig
+	connector  ConnectNats
+	marshaller MarshallerUnmarshaller
+
+	connection      Connection
+	connectionMutex sync.RWMutex
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+}
+
+// subscription tracks the NATS subscriptions of a topic.
+type subscription struct {
+	*internal.Delivery
+
+	subscriptions []*nats.Subscription
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	return NewClientWithConnectionFactory(messageBusConfig, defaultConnector)
+}
+
+// NewClientWithConnectionFactory creates a new Client based on the provided configuration while allowing more control
+// on the creation of the underlying NATS connection.
+func NewClientWithConnectionFactory(messageBusConfig types.MessageBusConfig, connector ConnectNats) (*Client, error) {
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	marshaller, err := newMarshaller(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:                config,
+		connector:             connector,
+		marshaller:            marshaller,
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+	}, nil
+}
+
+func defaultConnector(config ClientConfig) (Connection, error) {
+	opts, err := config.ConnectOpt()
+	if err != nil {
+		return nil, err
+	}
+
+	return nats.Connect(config.BrokerURL, opts...)
+}
+
+// Connect establishes the connection to the NATS server.
+func (c *Client) Connect() error {
+	c.connectionMutex.Lock()
+	defer c.connectionMutex.Unlock()
+
+	if c.connection != nil {
+		return nil
+	}
+
+	connection, err := c.connector(c.config)
+	if err != nil {
+		return err
+	}
+
+	c.connection = connection
+
+	return nil
+}
+
+// Publish sends the provided message to the NATS subject mapped from the topic.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which gives up once the context is done, such as while waiting for JetStream to store the
+// message.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	connection := c.connected()
+	if connection == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to publish with a disconnected client")
+	}
+
+	if topic == "" {
+		// Empty topics are not allowed for NATS
+		return internal.NewInvalidTopicErr("", "Unable to publish to the invalid topic")
+	}
+
+	msg, err := c.marshaller.Marshal(message, TopicToSubject(topic))
+	if err != nil {
+		return err
+	}
+
+	return internal.RunWithContext(ctx, func() error {
+		return connection.PublishMsg(msg)
+	})
+}
+
+// Subscribe creates subscriptions for the NATS subjects mapped from the topics. When a QueueGroup is configured
+// messages are load balanced between all the subscribers of the group.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, false)
+}
+
+// subscribe creates the subscriptions, with the connection's ResponseSubscriber when they are for the response topics
+// of requests.
+func (c *Client) subscribe(topics []types.TopicChannel, messageErrors chan error, response bool) error {
+	connection := c.connected()
+	if connection == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to subscribe with a disconnected client")
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		s := &subscription{Delivery: internal.NewDelivery(topic.Messages, messageErrors)}
+		handler := newMessageHandler(c.marshaller, s.Delivery)
+
+		for _, subject := range subjectsForTopic(topic.Topic) {
+			var natsSubscription *nats.Subscription
+			var err error
+			if responseSubscriber, ok := connection.(ResponseSubscriber); ok && response {
+				natsSubscription, err = responseSubscriber.SubscribeResponse(subject, handler)
+			} else {
+				natsSubscription, err = connection.QueueSubscribe(subject, c.config.QueueGroup, handler)
+			}
+			if err != nil {
+				_ = s.stop()
+				return fmt.Errorf("unable to subscribe to '%s' topic: %w", topic.Topic, err)
+			}
+			s.subscriptions = append(s.subscriptions, natsSubscription)
+		}
+
+		c.existingSubscriptions[topic.Topic] = s
+	}
+
+	// Wait for the server to have processed the subscriptions so that messages published right after, possibly from
+	// another connection, are not missed, which is needed for the Request API.
+	if err := connection.FlushTimeout(time.Duration(c.config.ConnectTimeout) * time.Second); err != nil {
+		for _, topic := range topics {
+			_ = c.existingSubscriptions[topic.Topic].stop()
+			delete(c.existingSubscriptions, topic.Topic)
+		}
+
+		return fmt.Errorf("unable to confirm the subscriptions with the server: %w", err)
+	}
+
+	return nil
+}
+
+// SubscribeContext is Subscribe which gives up once the context is done, such as while provisioning the JetStream
+// streams. The subscriptions are then removed.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	response := internal.IsResponseSubscription(ctx)
+	subscribe := func(topics []types.TopicChannel, messageErrors chan error) error {
+		return c.subscribe(topics, messageErrors, response)
+	}
+
+	return internal.SubscribeWithContext(ctx, subscribe, c.Unsubscribe, topics, messageErrors)
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe removes the subscriptions for the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	var errs []string
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		if err := s.stop(); err != nil {
+			errs = append(errs, fmt.Sprintf("unable to unsubscribe from '%s' topic: %v", topic, err))
+		}
+
+		delete(c.existingSubscriptions, topic)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// Disconnect stops all subscriptions, flushes the pending publishes and closes the connection to the NATS server.
+func (c *Client) Disconnect() error {
+	c.connectionMutex.Lock()
+	connection := c.connection
+	c.connection = nil
+	c.connectionMutex.Unlock()
+
+	if connection == nil {
+		return nil
+	}
+
+	// Stopped first since draining doesn't wait for the messages being handled, which are then no longer delivered
+	c.subscriptionMutex.Lock()
+	for topic, s := range c.existingSubscriptions {
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	return connection.Drain()
+}
+
+// connected returns the connection, nil when the client is disconnected.
+func (c *Client) connected() Connection {
+	c.connectionMutex.RLock()
+	defer c.connectionMutex.RUnlock()
+
+	return c.connection
+}
+
+// TopicToSubject converts the standard MQTT style topic scheme of "/", "#" & "+" to the NATS subject scheme of
+// ".", ">" & "*".
+func TopicToSubject(topic string) string {
+	subject := strings.Replace(topic, StandardTopicSeparator, NatsSubjectSeparator, -1)
+	subject = strings.Replace(subject, SingleLevelWildcard, NatsSingleLevelWildcard, -1)
+	subject = strings.Replace(subject, StandardWildcard, NatsWildcard, -1)
+
+	return subject
+}
+
+// SubjectToTopic converts the NATS subject scheme of ".", ">" & "*" to the standard MQTT style topic scheme of
+// "/", "#" & "+".
+func SubjectToTopic(subject string) string {
+	topic := strings.Replace(subject, NatsSubjectSeparator, StandardTopicSeparator, -1)
+	topic = strings.Replace(topic, NatsSingleLevelWildcard, SingleLevelWildcard, -1)
+	topic = strings.Replace(topic, NatsWildcard, StandardWildcard, -1)
+
+	return topic
+}
+
+// subjectsForTopic returns the NATS subjects which need to be subscribed to match the topic.
+//
+// The NATS ">" wildcard requires at least one more token, to match the MQTT multi-level wildcard the parent subject
+// is subscribed as well, for example subscribing subjects a.b and a.b.> is equal to MQTT topic a/b/#
+func subjectsForTopic(topic string) []string {
+	subject := TopicToSubject(topic)
+	if strings.HasSuffix(subject, NatsSubjectSeparator+NatsWildcard) {
+		return []string{subject, strings.TrimSuffix(subject, NatsSubjectSeparator+NatsWildcard)}
+	}
+
+	return []string{subject}
+}
+
+// stop stops the delivery, since unsubscribing doesn't wait for the handlers already running, and then removes the
+// NATS subscriptions.
+func (s *subscription) stop() error {
+	s.Stop()
+
+	var err error
+	for _, natsSubscription := range s.subscriptions {
+		if unsubscribeErr := natsSubscription.Unsubscribe(); unsubscribeErr != nil {
+			err = unsubscribeErr
+		}
+	}
+
+	return err
+}
+
+// newMessageHandler creates a function which propagates the received messages with the subscription's delivery.
+func newMessageHandler(unmarshaller MarshallerUnmarshaller, delivery *internal.Delivery) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		var messageEnvelope types.MessageEnvelope
+		if err := unmarshaller.Unmarshal(msg, &messageEnvelope); err != nil {
+			delivery.SendError(err)
+			return
+		}
+
+		messageEnvelope.ReceivedTopic = SubjectToTopic(msg.Subject)
+
+		if !delivery.Send(messageEnvelope) {
+			// JetStream redelivers the message rather than it being acknowledged, a no-op for NATS Core messages
+			_ = msg.Nak()
+		}
+	}
+}
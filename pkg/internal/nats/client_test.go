@@ -0,0 +1,271 @@
+package nats
+
+import (
+	"errors"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer starts an embedded NATS server listening on a random local port and returns its HostInfo.
+func startTestServer(t *testing.T) types.HostInfo {
+	natsServer, err := server.NewServer(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   server.RANDOM_PORT,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	require.NoError(t, err)
+
+	go natsServer.Start()
+	require.True(t, natsServer.ReadyForConnections(5*time.Second), "NATS server not ready")
+	t.Cleanup(natsServer.Shutdown)
+
+	return types.HostInfo{
+		Host:     "127.0.0.1",
+		Port:     natsServer.Addr().(*net.TCPAddr).Port,
+		Protocol: "nats",
+	}
+}
+
+func newConnectedClient(t *testing.T, hostInfo types.HostInfo, optional map[string]string) *Client {
+	client, err := NewClient(types.MessageBusConfig{Broker: hostInfo, Optional: optional})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() {
+		_ = client.Disconnect()
+	})
+
+	return client
+}
+
+func TestTopicToSubject(t *testing.T) {
+	tests := []struct {
+		topic   string
+		subject string
+	}{
+		{"edgex/events/device", "edgex.events.device"},
+		{"edgex/events/#", "edgex.events.>"},
+		{"edgex/+/device", "edgex.*.device"},
+		{"#", ">"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			assert.Equal(t, tt.subject, TopicToSubject(tt.topic))
+			assert.Equal(t, tt.topic, SubjectToTopic(tt.subject))
+		})
+	}
+}
+
+func TestSubjectsForTopic(t *testing.T) {
+	assert.Equal(t, []string{"a.b"}, subjectsForTopic("a/b"))
+	assert.Equal(t, []string{"a.*"}, subjectsForTopic("a/+"))
+	assert.Equal(t, []string{"a.b.>", "a.b"}, subjectsForTopic("a/b/#"))
+	assert.Equal(t, []string{">"}, subjectsForTopic("#"))
+}
+
+func TestNewClient(t *testing.T) {
+	hostInfo := types.HostInfo{Host: "localhost", Port: 4222, Protocol: "nats"}
+
+	_, err := NewClient(types.MessageBusConfig{Broker: hostInfo})
+	require.NoError(t, err)
+
+	_, err = NewClient(types.MessageBusConfig{Broker: hostInfo, Optional: map[string]string{internal.Format: "xml"}})
+	require.Error(t, err)
+
+	client, err := NewClientWithConnectionFactory(types.MessageBusConfig{Broker: hostInfo},
+		func(ClientConfig) (Connection, error) { return nil, errors.New("test error") })
+	require.NoError(t, err)
+	require.Error(t, client.Connect())
+}
+
+func TestClient_NotConnected(t *testing.T) {
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 4222}})
+	require.NoError(t, err)
+
+	err = client.Publish(types.MessageEnvelope{}, "test")
+	assert.IsType(t, internal.MissingConfigurationErr{}, err)
+
+	err = client.Subscribe([]types.TopicChannel{{Topic: "test"}}, make(chan error))
+	assert.IsType(t, internal.MissingConfigurationErr{}, err)
+
+	require.NoError(t, client.Disconnect())
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	hostInfo := startTestServer(t)
+
+	tests := []struct {
+		name           string
+		format         string
+		subscribeTopic string
+		publishTopic   string
+		expectMessage  bool
+	}{
+		{"Exact topic", FormatJSON, "test/exact", "test/exact", true},
+		{"Exact topic nats format", FormatNATS, "test/exact", "test/exact", true},
+		{"Single level wildcard", FormatJSON, "test/+/single", "test/device/single", true},
+		{"Single level wildcard no match", FormatJSON, "test/+/nomatch", "test/a/b/nomatch", false},
+		{"Multi level wildcard", FormatJSON, "test/multi/#", "test/multi/a/b/c", true},
+		{"Multi level wildcard parent level", FormatNATS, "test/parent/#", "test/parent", true},
+		{"No match", FormatJSON, "test/other", "test/something", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newConnectedClient(t, hostInfo, map[string]string{internal.Format: tt.format})
+
+			messages := make(chan types.MessageEnvelope, 1)
+			errs := make(chan error, 1)
+			err := client.Subscribe([]types.TopicChannel{{Topic: tt.subscribeTopic, Messages: messages}}, errs)
+			require.NoError(t, err)
+
+			expected := types.NewMessageEnvelopeForRequest([]byte("test payload"), map[string]string{"key": "value"})
+			expected.SetHeader(types.HeaderTenantID, "tenant")
+			require.NoError(t, client.Publish(expected, tt.publishTopic))
+
+			select {
+			case actual := <-messages:
+				require.True(t, tt.expectMessage, "unexpected message received")
+				expected.ReceivedTopic = tt.publishTopic
+				assert.Equal(t, expected, actual)
+			case err := <-errs:
+				require.NoError(t, err)
+			case <-time.After(500 * time.Millisecond):
+				require.False(t, tt.expectMessage, "timed out waiting for message")
+			}
+		})
+	}
+}
+
+func TestClient_QueueGroup(t *testing.T) {
+	hostInfo := startTestServer(t)
+	publisher := newConnectedClient(t, hostInfo, nil)
+
+	messages := make(chan types.MessageEnvelope, 10)
+	for i := 0; i < 2; i++ {
+		subscriber := newConnectedClient(t, hostInfo, map[string]string{internal.QueueGroup: "workers"})
+		require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "test/work", Messages: messages}}, make(chan error, 1)))
+	}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "test/work"))
+	}
+
+	// Each message must only be delivered to one member of the queue group
+	received := 0
+	timeout := time.After(500 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case <-messages:
+			received++
+		case <-timeout:
+			done = true
+		}
+	}
+	assert.Equal(t, 5, received)
+}
+
+func TestClient_SubscribeDuplicateTopic(t *testing.T) {
+	hostInfo := startTestServer(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	topics := []types.TopicChannel{{Topic: "test/duplicate", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestClient_SubscribeUnmarshalError(t *testing.T) {
+	hostInfo := startTestServer(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/raw", Messages: messages}}, errs))
+
+	require.NoError(t, client.connection.PublishMsg(&nats.Msg{Subject: "test.raw", Data: []byte("not an envelope")}))
+
+	select {
+	case err := <-errs:
+		require.Error(t, err)
+	case <-messages:
+		require.Fail(t, "unexpected message received")
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for error")
+	}
+}
+
+func TestClient_Unsubscribe(t *testing.T) {
+	hostInfo := startTestServer(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	topic := "test/unsubscribe/#"
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+	require.NoError(t, client.Unsubscribe(topic, "test/not-subscribed"))
+	assert.Empty(t, client.existingSubscriptions)
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test/unsubscribe"))
+	select {
+	case <-messages:
+		require.Fail(t, "unexpected message received after unsubscribe")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The same topic can be subscribed again once unsubscribed
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+}
+
+func TestClient_UnsubscribeWhileDelivering(t *testing.T) {
+	hostInfo := startTestServer(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	// Nobody receives, so the handler is blocked delivering the message
+	topic := "test/blocked"
+	messages := make(chan types.MessageEnvelope)
+	errs := make(chan error)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, errs))
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, topic))
+	time.Sleep(100 * time.Millisecond)
+
+	// The channels can be closed once unsubscribed, as the Request API does, without the handler panicking
+	require.NoError(t, client.Unsubscribe(topic))
+	close(messages)
+	close(errs)
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestClient_Request(t *testing.T) {
+	hostInfo := startTestServer(t)
+	requester := newConnectedClient(t, hostInfo, nil)
+	responder := newConnectedClient(t, hostInfo, nil)
+
+	requestTopic := "test/request"
+	responseTopicPrefix := "test/response"
+
+	requests := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: requestTopic, Messages: requests}}, make(chan error, 1)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("pong"), request.RequestID, request.CorrelationID, types.ContentTypeText)
+		_ = responder.Publish(response, responseTopicPrefix+"/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("ping"), nil)
+	response, err := requester.Request(request, requestTopic, responseTopicPrefix, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, "pong", string(response.Payload))
+	assert.Empty(t, requester.existingSubscriptions)
+
+	_, err = requester.Request(types.NewMessageEnvelopeForRequest(nil, nil), "test/nobody", responseTopicPrefix, 100*time.Millisecond)
+	require.Error(t, err)
+}
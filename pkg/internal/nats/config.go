@@ -0,0 +1,158 @@
+package nats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// FormatJSON encodes the whole MessageEnvelope as JSON in the body of the NATS message. The formats other than
+	// FormatNATS name the codec encoding the body, any codec registered with codec.Register can be used.
+	FormatJSON = "json"
+	// FormatNATS carries the MessageEnvelope fields as NATS headers and the payload as the raw message body.
+	FormatNATS = "nats"
+	// FormatCBOR encodes the whole MessageEnvelope as CBOR in the body of the NATS message.
+	FormatCBOR = "cbor"
+
+	// DeliverAll delivers all the messages available in the JetStream stream to a new consumer.
+	DeliverAll = "all"
+	// DeliverNew only delivers the messages published after a new consumer has been created.
+	DeliverNew = "new"
+	// DeliverLast delivers the last message available in the JetStream stream and all the following ones.
+	DeliverLast = "last"
+)
+
+// ClientConfig contains all the configurations for the NATS client.
+type ClientConfig struct {
+	BrokerURL string
+	ClientOptions
+}
+
+// ClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type ClientOptions struct {
+	// Client Identifiers
+	Username string
+	Password string
+	ClientId string
+
+	// Connection information
+	Format               string
+	ConnectTimeout       int // Seconds
+	RetryOnFailedConnect bool
+	QueueGroup           string
+
+	// JetStream specifics
+	Durable                 string
+	Subject                 string
+	AutoProvision           bool
+	Deliver                 string
+	DefaultPubRetryAttempts int
+	AckWait                 int // Seconds before an unacknowledged message is redelivered, the server's default when 0
+
+	internal.TlsConfigurationOptions
+}
+
+// NewClientConfiguration creates a ClientConfig based on the configuration properties provided.
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	brokerURL := messageBusConfig.Broker.GetHostURL()
+	_, err := url.Parse(brokerURL)
+	if err != nil {
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("Failed to parse broker: %v", err))
+	}
+
+	clientOptions := ClientOptions{
+		Format:                  FormatJSON,
+		ConnectTimeout:          5,
+		Deliver:                 DeliverNew,
+		DefaultPubRetryAttempts: 2,
+		TlsConfigurationOptions: internal.CreateDefaultTlsConfigurationOptions(),
+	}
+
+	err = internal.Load(messageBusConfig.Optional, &clientOptions)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	err = internal.Load(messageBusConfig.Optional, &clientOptions.TlsConfigurationOptions)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	clientOptions.Format = strings.ToLower(clientOptions.Format)
+	if _, err = newMarshaller(clientOptions.Format); err != nil {
+		return ClientConfig{}, err
+	}
+
+	clientOptions.Deliver = strings.ToLower(clientOptions.Deliver)
+	switch clientOptions.Deliver {
+	case DeliverAll, DeliverNew, DeliverLast:
+	default:
+		return ClientConfig{}, fmt.Errorf("unsupported %s value '%s', must be one of '%s', '%s' or '%s'",
+			internal.Deliver, clientOptions.Deliver, DeliverAll, DeliverNew, DeliverLast)
+	}
+
+	if clientOptions.AckWait < 0 {
+		return ClientConfig{}, fmt.Errorf("%s must not be negative", internal.AckWait)
+	}
+
+	return ClientConfig{
+		BrokerURL:     brokerURL,
+		ClientOptions: clientOptions,
+	}, nil
+}
+
+// ConnectOpt creates the NATS connection options based on the configuration.
+func (cc ClientConfig) ConnectOpt() ([]nats.Option, error) {
+	return cc.connectOpt(tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate, os.ReadFile, pem.Decode)
+}
+
+func (cc ClientConfig) connectOpt(
+	certCreator internal.X509KeyPairCreator,
+	certLoader internal.X509KeyLoader,
+	caCertCreator internal.X509CaCertCreator,
+	caCertLoader internal.X509CaCertLoader,
+	pemDecoder internal.PEMDecoder) ([]nats.Option, error) {
+
+	opts := []nats.Option{
+		nats.Timeout(time.Duration(cc.ConnectTimeout) * time.Second),
+		nats.RetryOnFailedConnect(cc.RetryOnFailedConnect),
+		// Keep retrying forever so the existing subscriptions are restored once the server is back
+		nats.MaxReconnects(-1),
+	}
+
+	if cc.ClientId != "" {
+		opts = append(opts, nats.Name(cc.ClientId))
+	}
+
+	if cc.Username != "" || cc.Password != "" {
+		opts = append(opts, nats.UserInfo(cc.Username, cc.Password))
+	}
+
+	tlsConfig, err := internal.GenerateTLSForClientClientOptions(
+		cc.BrokerURL,
+		cc.TlsConfigurationOptions,
+		certCreator,
+		certLoader,
+		caCertCreator,
+		caCertLoader,
+		pemDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,130 @@
+package nats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var TestHostInfo = types.HostInfo{
+	Host:     "localhost",
+	Port:     4222,
+	Protocol: "nats",
+}
+
+func TestNewClientConfiguration(t *testing.T) {
+	tests := []struct {
+		name     string
+		optional map[string]string
+		want     ClientOptions
+		wantErr  bool
+	}{
+		{
+			name: "Defaults",
+			want: ClientOptions{Format: FormatJSON, ConnectTimeout: 5, Deliver: DeliverNew, DefaultPubRetryAttempts: 2},
+		},
+		{
+			name: "All options",
+			optional: map[string]string{
+				internal.Username:                "user",
+				internal.Password:                "pass",
+				internal.ClientId:                "client",
+				internal.Format:                  "NATS",
+				internal.ConnectTimeout:          "10",
+				internal.RetryOnFailedConnect:    "true",
+				internal.QueueGroup:              "group",
+				internal.Durable:                 "durable",
+				internal.Subject:                 "edgex/#",
+				internal.AutoProvision:           "true",
+				internal.Deliver:                 "LAST",
+				internal.DefaultPubRetryAttempts: "5",
+				internal.AckWait:                 "1",
+			},
+			want: ClientOptions{
+				Username:                "user",
+				Password:                "pass",
+				ClientId:                "client",
+				Format:                  FormatNATS,
+				ConnectTimeout:          10,
+				RetryOnFailedConnect:    true,
+				QueueGroup:              "group",
+				Durable:                 "durable",
+				Subject:                 "edgex/#",
+				AutoProvision:           true,
+				Deliver:                 DeliverLast,
+				DefaultPubRetryAttempts: 5,
+				AckWait:                 1,
+			},
+		},
+		{
+			name:     "Invalid format",
+			optional: map[string]string{internal.Format: "xml"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid deliver policy",
+			optional: map[string]string{internal.Deliver: "first"},
+			wantErr:  true,
+		},
+		{
+			name:     "Negative ack wait",
+			optional: map[string]string{internal.AckWait: "-1"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid bool",
+			optional: map[string]string{internal.RetryOnFailedConnect: "NotABool"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid TLS option",
+			optional: map[string]string{internal.SkipCertVerify: "NotABool"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewClientConfiguration(types.MessageBusConfig{Broker: TestHostInfo, Optional: tt.optional})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "nats://localhost:4222", config.BrokerURL)
+			assert.Equal(t, tt.want, config.ClientOptions)
+		})
+	}
+}
+
+func TestClientConfig_ConnectOpt(t *testing.T) {
+	config, err := NewClientConfiguration(types.MessageBusConfig{
+		Broker: TestHostInfo,
+		Optional: map[string]string{
+			internal.Username:     "user",
+			internal.ClientId:     "client",
+			internal.CertPEMBlock: "cert",
+			internal.KeyPEMBlock:  "key",
+		},
+	})
+	require.NoError(t, err)
+
+	certCreator := func([]byte, []byte) (tls.Certificate, error) { return tls.Certificate{}, nil }
+	opts, err := config.connectOpt(certCreator, tls.LoadX509KeyPair, x509.ParseCertificate, nil, pem.Decode)
+	require.NoError(t, err)
+
+	// timeout, retry, reconnects, name, user info and TLS
+	assert.Len(t, opts, 6)
+
+	config.CertPEMBlock = ""
+	config.KeyPEMBlock = ""
+	opts, err = config.ConnectOpt()
+	require.NoError(t, err)
+	assert.Len(t, opts, 5)
+}
@@ -0,0 +1,235 @@
+package jetstream
+
+import (
+	"errors"
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/internal/nats"
+	"messaging/pkg/types"
+	"strings"
+	"time"
+
+	natsio "github.com/nats-io/nats.go"
+)
+
+// NewClient creates a new NATS JetStream client based on the provided configuration.
+//
+// The JetStream client reuses the NATS Core client functionality, only the underlying connection differs so that
+// messages are published to and consumed from JetStream streams with explicit acknowledgements.
+func NewClient(messageBusConfig types.MessageBusConfig) (*nats.Client, error) {
+	return nats.NewClientWithConnectionFactory(messageBusConfig, newConnection)
+}
+
+// connection implements nats.Connection on top of a JetStream context.
+type connection struct {
+	config nats.ClientConfig
+	conn   *natsio.Conn
+	js     natsio.JetStreamContext
+}
+
+func newConnection(config nats.ClientConfig) (nats.Connection, error) {
+	opts, err := config.ConnectOpt()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := natsio.Connect(config.BrokerURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if config.AutoProvision {
+		if err = provisionStream(js, config.Subject); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &connection{
+		config: config,
+		conn:   conn,
+		js:     js,
+	}, nil
+}
+
+// QueueSubscribe creates a JetStream push consumer for the subject. When Durable is configured the consumer state is
+// kept by the server so that a restarted client resumes where it left off. Messages are acknowledged once they have
+// been handed over to the subscriber, those which couldn't be since unsubscribed are redelivered after AckWait.
+func (c *connection) QueueSubscribe(subject string, queue string, handler natsio.MsgHandler) (*natsio.Subscription, error) {
+	opts := []natsio.SubOpt{natsio.ManualAck()}
+	if c.config.Durable != "" {
+		// The library deletes the consumers it creates once unsubscribed, so the durable consumer is created
+		// beforehand and bound to, which keeps it, and its state, when unsubscribing
+		stream, durable, err := c.addDurableConsumer(subject, queue)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, natsio.Bind(stream, durable))
+	} else {
+		opts = append(opts, deliverOption(c.config.Deliver))
+		if c.config.AckWait > 0 {
+			opts = append(opts, natsio.AckWait(c.ackWait()))
+		}
+	}
+
+	if queue != "" {
+		return c.js.QueueSubscribe(subject, queue, ackHandler(handler), opts...)
+	}
+
+	return c.js.Subscribe(subject, ackHandler(handler), opts...)
+}
+
+// SubscribeResponse creates an ephemeral JetStream push consumer for the response subject of requests, whatever the
+// Durable and QueueGroup, so that the library deletes it once unsubscribed rather than a durable consumer being left
+// behind for every request. Only the responses published from then on are delivered.
+func (c *connection) SubscribeResponse(subject string, handler natsio.MsgHandler) (*natsio.Subscription, error) {
+	return c.js.Subscribe(subject, ackHandler(handler), natsio.ManualAck(), natsio.DeliverNew())
+}
+
+// addDurableConsumer creates the durable consumer of the subject, unless it exists already, and returns the names of
+// its stream and of the consumer.
+func (c *connection) addDurableConsumer(subject string, queue string) (string, string, error) {
+	durable := durableName(c.config.Durable, subject)
+
+	stream, err := c.js.StreamNameBySubject(subject)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to lookup the stream of '%s' subject: %w", subject, err)
+	}
+
+	_, err = c.js.ConsumerInfo(stream, durable)
+	if err == nil {
+		return stream, durable, nil
+	}
+	if !errors.Is(err, natsio.ErrConsumerNotFound) {
+		return "", "", fmt.Errorf("unable to lookup consumer '%s': %w", durable, err)
+	}
+
+	_, err = c.js.AddConsumer(stream, &natsio.ConsumerConfig{
+		Durable:        durable,
+		DeliverSubject: c.conn.NewInbox(),
+		DeliverGroup:   queue,
+		DeliverPolicy:  deliverPolicy(c.config.Deliver),
+		AckPolicy:      natsio.AckExplicitPolicy,
+		AckWait:        c.ackWait(),
+		FilterSubject:  subject,
+	})
+	// Another client of the queue group may have created it in the meantime
+	if err != nil && !errors.Is(err, natsio.ErrConsumerNameAlreadyInUse) {
+		return "", "", fmt.Errorf("unable to create consumer '%s': %w", durable, err)
+	}
+
+	return stream, durable, nil
+}
+
+// ackWait returns the time after which the consumers redeliver an unacknowledged message, e.g. one sent to the
+// subscription of a client which stopped meanwhile, 0 for the server's default.
+func (c *connection) ackWait() time.Duration {
+	return time.Duration(c.config.AckWait) * time.Second
+}
+
+// PublishMsg publishes the message to the JetStream stream which captures its subject and waits for the stream to
+// acknowledge it, retrying when no stream is available yet.
+func (c *connection) PublishMsg(msg *natsio.Msg) error {
+	_, err := c.js.PublishMsg(msg, natsio.RetryAttempts(c.config.DefaultPubRetryAttempts))
+	return err
+}
+
+// FlushTimeout waits for the server to have processed everything sent so far.
+func (c *connection) FlushTimeout(timeout time.Duration) error {
+	return c.conn.FlushTimeout(timeout)
+}
+
+// Drain flushes the pending publishes and closes the connection. Draining the subscriptions is avoided on purpose
+// since it would delete the durable consumers and lose their state.
+func (c *connection) Drain() error {
+	err := c.conn.Flush()
+	c.conn.Close()
+
+	return err
+}
+
+// provisionStream creates the stream capturing the subject, which is in the standard topic scheme, if it doesn't exist.
+func provisionStream(js natsio.JetStreamContext, subject string) error {
+	if subject == "" {
+		return internal.NewMissingConfigurationErr(internal.Subject, "Subject is required to auto provision the stream")
+	}
+
+	streamSubject := nats.TopicToSubject(subject)
+	name := streamName(streamSubject)
+
+	_, err := js.StreamInfo(name)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, natsio.ErrStreamNotFound) {
+		return fmt.Errorf("unable to lookup stream '%s': %w", name, err)
+	}
+
+	_, err = js.AddStream(&natsio.StreamConfig{
+		Name:     name,
+		Subjects: []string{streamSubject},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to provision stream '%s': %w", name, err)
+	}
+
+	return nil
+}
+
+// ackHandler acknowledges the messages once handled.
+func ackHandler(handler natsio.MsgHandler) natsio.MsgHandler {
+	return func(msg *natsio.Msg) {
+		handler(msg)
+		_ = msg.Ack()
+	}
+}
+
+func deliverPolicy(deliver string) natsio.DeliverPolicy {
+	switch deliver {
+	case nats.DeliverAll:
+		return natsio.DeliverAllPolicy
+	case nats.DeliverLast:
+		return natsio.DeliverLastPolicy
+	default:
+		return natsio.DeliverNewPolicy
+	}
+}
+
+func deliverOption(deliver string) natsio.SubOpt {
+	switch deliver {
+	case nats.DeliverAll:
+		return natsio.DeliverAll()
+	case nats.DeliverLast:
+		return natsio.DeliverLast()
+	default:
+		return natsio.DeliverNew()
+	}
+}
+
+// streamName creates a valid stream name from a NATS subject, for example "edgex.events.>" becomes "edgex_events_all".
+func streamName(subject string) string {
+	return sanitizeName(subject)
+}
+
+// durableName creates a valid durable consumer name which is unique per subject, since a durable consumer can only
+// filter on a single subject.
+func durableName(durable string, subject string) string {
+	return sanitizeName(durable + "_" + subject)
+}
+
+func sanitizeName(name string) string {
+	name = strings.NewReplacer(
+		nats.NatsSubjectSeparator, "_",
+		nats.NatsWildcard, "all",
+		nats.NatsSingleLevelWildcard, "any",
+		" ", "_").Replace(name)
+
+	return strings.Trim(name, "_")
+}
@@ -0,0 +1,263 @@
+package jetstream
+
+import (
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/internal/nats"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsio "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStreamTopic = "test/#"
+
+// startTestServer starts an embedded NATS server with JetStream enabled and returns its HostInfo.
+func startTestServer(t *testing.T) types.HostInfo {
+	natsServer, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	go natsServer.Start()
+	require.True(t, natsServer.ReadyForConnections(5*time.Second), "NATS server not ready")
+	t.Cleanup(natsServer.Shutdown)
+
+	return types.HostInfo{
+		Host:     "127.0.0.1",
+		Port:     natsServer.Addr().(*net.TCPAddr).Port,
+		Protocol: "nats",
+	}
+}
+
+func newConnectedClient(t *testing.T, hostInfo types.HostInfo, optional map[string]string) *nats.Client {
+	config := map[string]string{
+		internal.AutoProvision: "true",
+		internal.Subject:       testStreamTopic,
+	}
+	for key, value := range optional {
+		config[key] = value
+	}
+
+	client, err := NewClient(types.MessageBusConfig{Broker: hostInfo, Optional: config})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() {
+		_ = client.Disconnect()
+	})
+
+	return client
+}
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, "edgex_events_all", streamName("edgex.events.>"))
+	assert.Equal(t, "all", streamName(">"))
+	assert.Equal(t, "app_edgex_any_device", durableName("app", "edgex.*.device"))
+}
+
+func TestAutoProvision(t *testing.T) {
+	hostInfo := startTestServer(t)
+
+	client, err := NewClient(types.MessageBusConfig{Broker: hostInfo, Optional: map[string]string{internal.AutoProvision: "true"}})
+	require.NoError(t, err)
+	err = client.Connect()
+	require.Error(t, err)
+	assert.IsType(t, internal.MissingConfigurationErr{}, err)
+
+	// Provisioning is idempotent
+	newConnectedClient(t, hostInfo, nil)
+	newConnectedClient(t, hostInfo, nil)
+}
+
+func TestPublishWithoutStream(t *testing.T) {
+	hostInfo := startTestServer(t)
+
+	client, err := NewClient(types.MessageBusConfig{Broker: hostInfo, Optional: map[string]string{internal.DefaultPubRetryAttempts: "1"}})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	defer func() { _ = client.Disconnect() }()
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "nostream/topic"))
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	hostInfo := startTestServer(t)
+	client := newConnectedClient(t, hostInfo, nil)
+
+	messages := make(chan types.MessageEnvelope, 10)
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/events/#", Messages: messages}}, errs))
+
+	expected := types.NewMessageEnvelopeForRequest([]byte("payload"), nil)
+	require.NoError(t, client.Publish(expected, "test/events/device"))
+	require.NoError(t, client.Publish(expected, "test/events"))
+	require.NoError(t, client.Publish(expected, "test/other"))
+
+	received := testutil.ReceiveMessages(messages, 500*time.Millisecond)
+	require.Len(t, received, 2)
+	assert.Equal(t, expected.RequestID, received[0].RequestID)
+	assert.ElementsMatch(t, []string{"test/events/device", "test/events"},
+		[]string{received[0].ReceivedTopic, received[1].ReceivedTopic})
+}
+
+func TestDeliverPolicies(t *testing.T) {
+	hostInfo := startTestServer(t)
+	publisher := newConnectedClient(t, hostInfo, nil)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: string(rune('a' + i))}, "test/history"))
+	}
+
+	tests := []struct {
+		deliver  string
+		expected []string
+	}{
+		{nats.DeliverAll, []string{"a", "b", "c"}},
+		{nats.DeliverLast, []string{"c"}},
+		{nats.DeliverNew, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.deliver, func(t *testing.T) {
+			client := newConnectedClient(t, hostInfo, map[string]string{internal.Deliver: tt.deliver})
+
+			messages := make(chan types.MessageEnvelope, 10)
+			require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test/history", Messages: messages}}, make(chan error, 1)))
+
+			var actual []string
+			for _, message := range testutil.ReceiveMessages(messages, 300*time.Millisecond) {
+				actual = append(actual, message.CorrelationID)
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDurableConsumerSurvivesRestart(t *testing.T) {
+	hostInfo := startTestServer(t)
+	publisher := newConnectedClient(t, hostInfo, nil)
+	// A message published right after disconnecting may still be sent to the stopped client, it is then only
+	// redelivered once AckWait elapsed
+	optional := map[string]string{internal.Durable: "service", internal.AckWait: "1"}
+	topic := "test/durable"
+
+	subscriber := newConnectedClient(t, hostInfo, optional)
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "before"}, topic))
+	received := testutil.ReceiveMessages(messages, 300*time.Millisecond)
+	require.Len(t, received, 1)
+	assert.Equal(t, "before", received[0].CorrelationID)
+
+	// Messages published while the subscriber is down must be delivered once it is back
+	require.NoError(t, subscriber.Disconnect())
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "while-down"}, topic))
+
+	restarted := newConnectedClient(t, hostInfo, optional)
+	messages = make(chan types.MessageEnvelope, 10)
+	require.NoError(t, restarted.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+
+	assert.Equal(t, "while-down", testutil.ReceiveMessage(t, messages, 5*time.Second).CorrelationID)
+}
+
+func TestDurableConsumerSurvivesUnsubscribe(t *testing.T) {
+	hostInfo := startTestServer(t)
+	publisher := newConnectedClient(t, hostInfo, nil)
+	subscriber := newConnectedClient(t, hostInfo, map[string]string{internal.Durable: "service"})
+	topic := "test/durable"
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+	require.NoError(t, subscriber.Unsubscribe(topic))
+
+	// The messages published while unsubscribed are delivered once subscribed again
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "while-unsubscribed"}, topic))
+
+	messages = make(chan types.MessageEnvelope, 10)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, make(chan error, 1)))
+
+	received := testutil.ReceiveMessages(messages, 500*time.Millisecond)
+	require.Len(t, received, 1)
+	assert.Equal(t, "while-unsubscribed", received[0].CorrelationID)
+}
+
+func TestQueueGroup(t *testing.T) {
+	hostInfo := startTestServer(t)
+	publisher := newConnectedClient(t, hostInfo, nil)
+	optional := map[string]string{internal.Durable: "workers", internal.QueueGroup: "workers"}
+
+	messages := make(chan types.MessageEnvelope, 10)
+	for i := 0; i < 2; i++ {
+		subscriber := newConnectedClient(t, hostInfo, optional)
+		require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "test/work", Messages: messages}}, make(chan error, 1)))
+	}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "test/work"))
+	}
+
+	assert.Len(t, testutil.ReceiveMessages(messages, 500*time.Millisecond), 5)
+}
+
+func TestRequest(t *testing.T) {
+	hostInfo := startTestServer(t)
+	requester := newConnectedClient(t, hostInfo, nil)
+	responder := newConnectedClient(t, hostInfo, nil)
+
+	requests := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "test/request", Messages: requests}}, make(chan error, 1)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("pong"), request.RequestID, request.CorrelationID, types.ContentTypeText)
+		_ = responder.Publish(response, "test/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("ping"), nil)
+	response, err := requester.Request(request, "test/request", "test/response", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, "pong", string(response.Payload))
+}
+
+func TestRequestWithDurableLeavesNoConsumer(t *testing.T) {
+	hostInfo := startTestServer(t)
+	requester := newConnectedClient(t, hostInfo, map[string]string{internal.Durable: "service", internal.QueueGroup: "service"})
+	responder := newConnectedClient(t, hostInfo, nil)
+
+	requests := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "test/request", Messages: requests}}, make(chan error, 1)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("pong"), request.RequestID, request.CorrelationID, types.ContentTypeText)
+		_ = responder.Publish(response, "test/response/"+request.RequestID)
+	}()
+
+	response, err := requester.Request(types.NewMessageEnvelopeForRequest([]byte("ping"), nil), "test/request", "test/response", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(response.Payload))
+
+	conn, err := natsio.Connect(fmt.Sprintf("nats://%s:%d", hostInfo.Host, hostInfo.Port))
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+
+	// Only the ephemeral consumer of the responder remains, the one of the response topic is deleted once unsubscribed
+	require.Eventually(t, func() bool {
+		info, err := js.StreamInfo(streamName(nats.TopicToSubject(testStreamTopic)))
+		return err == nil && info.State.Consumers == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,147 @@
+package nats
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/types"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// MarshallerUnmarshaller converts between a MessageEnvelope and a NATS message.
+type MarshallerUnmarshaller interface {
+	// Marshal creates the NATS message to publish to the subject for the provided envelope.
+	Marshal(v types.MessageEnvelope, subject string) (*nats.Msg, error)
+	// Unmarshal populates the provided envelope from a received NATS message.
+	Unmarshal(msg *nats.Msg, v *types.MessageEnvelope) error
+}
+
+const (
+	correlationIDHeader = "X-Correlation-ID"
+	apiVersionHeader    = "X-Api-Version"
+	requestIDHeader     = "X-Request-ID"
+	errorCodeHeader     = "X-Error-Code"
+	contentTypeHeader   = "Content-Type"
+	sequenceHeader      = "X-Sequence"
+	endOfStreamHeader   = "X-End-Of-Stream"
+	deadlineHeader      = "X-Deadline"
+	queryParamPrefix    = "X-Query-"
+	headerPrefix        = "X-Header-"
+)
+
+func newMarshaller(format string) (MarshallerUnmarshaller, error) {
+	if format == FormatNATS {
+		return &natsMarshaller{}, nil
+	}
+
+	envelopeCodec, err := codec.Lookup(format)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported message format '%s': %w", format, err)
+	}
+
+	return &codecMarshaller{codec: envelopeCodec}, nil
+}
+
+// codecMarshaller encodes the whole envelope with the codec in the body of the message. The bodies are decoded with
+// the codec detected from them, so that the publishers of a subject using different codecs can coexist.
+type codecMarshaller struct {
+	codec codec.Codec
+}
+
+func (cm *codecMarshaller) Marshal(v types.MessageEnvelope, subject string) (*nats.Msg, error) {
+	data, err := cm.codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nats.Msg{Subject: subject, Data: data}, nil
+}
+
+func (cm *codecMarshaller) Unmarshal(msg *nats.Msg, v *types.MessageEnvelope) error {
+	if err := codec.Decode(msg.Data, v, cm.codec); err != nil {
+		return fmt.Errorf("unable to unmarshal payload: %w", err)
+	}
+
+	return nil
+}
+
+// natsMarshaller carries the envelope fields as NATS headers so that non-EdgeX consumers receive the raw payload.
+type natsMarshaller struct{}
+
+func (nm *natsMarshaller) Marshal(v types.MessageEnvelope, subject string) (*nats.Msg, error) {
+	msg := nats.NewMsg(subject)
+	msg.Data = v.Payload
+
+	msg.Header.Set(correlationIDHeader, v.CorrelationID)
+	msg.Header.Set(apiVersionHeader, v.ApiVersion)
+	msg.Header.Set(requestIDHeader, v.RequestID)
+	msg.Header.Set(errorCodeHeader, strconv.Itoa(v.ErrorCode))
+	msg.Header.Set(contentTypeHeader, v.ContentType)
+	if v.Sequence != 0 {
+		msg.Header.Set(sequenceHeader, strconv.Itoa(v.Sequence))
+	}
+	if v.EndOfStream {
+		msg.Header.Set(endOfStreamHeader, "true")
+	}
+	if v.Deadline != 0 {
+		msg.Header.Set(deadlineHeader, strconv.FormatInt(v.Deadline, 10))
+	}
+	for key, value := range v.QueryParams {
+		msg.Header.Set(queryParamPrefix+key, value)
+	}
+	for key, value := range v.Headers {
+		msg.Header.Set(headerPrefix+key, value)
+	}
+
+	return msg, nil
+}
+
+func (nm *natsMarshaller) Unmarshal(msg *nats.Msg, v *types.MessageEnvelope) error {
+	v.Payload = msg.Data
+	v.CorrelationID = msg.Header.Get(correlationIDHeader)
+	v.ApiVersion = msg.Header.Get(apiVersionHeader)
+	v.RequestID = msg.Header.Get(requestIDHeader)
+	v.ContentType = msg.Header.Get(contentTypeHeader)
+
+	if errorCode := msg.Header.Get(errorCodeHeader); errorCode != "" {
+		code, err := strconv.Atoi(errorCode)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s header: %w", errorCodeHeader, err)
+		}
+		v.ErrorCode = code
+	}
+
+	if sequence := msg.Header.Get(sequenceHeader); sequence != "" {
+		value, err := strconv.Atoi(sequence)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s header: %w", sequenceHeader, err)
+		}
+		v.Sequence = value
+	}
+	v.EndOfStream = msg.Header.Get(endOfStreamHeader) == "true"
+
+	if deadline := msg.Header.Get(deadlineHeader); deadline != "" {
+		value, err := strconv.ParseInt(deadline, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s header: %w", deadlineHeader, err)
+		}
+		v.Deadline = value
+	}
+
+	v.QueryParams = make(map[string]string)
+	for key, values := range msg.Header {
+		if strings.HasPrefix(key, queryParamPrefix) && len(values) > 0 {
+			v.QueryParams[strings.TrimPrefix(key, queryParamPrefix)] = values[0]
+		}
+		if strings.HasPrefix(key, headerPrefix) && len(values) > 0 {
+			if v.Headers == nil {
+				v.Headers = make(map[string]string)
+			}
+			v.Headers[strings.TrimPrefix(key, headerPrefix)] = values[0]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package nats
+
+import (
+	"messaging/pkg/codec"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshallers(t *testing.T) {
+	expected := types.MessageEnvelope{
+		CorrelationID: "fa1def22-96de-4d44-8811-00333438c8e3",
+		ApiVersion:    types.ApiVersion,
+		RequestID:     "3ab0e022-464b-4bfe-bf7f-b0154093ddad",
+		ErrorCode:     1,
+		Payload:       []byte("test payload"),
+		ContentType:   types.ContentTypeText,
+		QueryParams:   map[string]string{"key": "value", "lowercase": "kept"},
+		Sequence:      3,
+		EndOfStream:   true,
+		Deadline:      1700000000000,
+		Headers:       map[string]string{types.HeaderTenantID: "tenant", "Mixed-Case": "kept"},
+	}
+
+	for _, format := range []string{FormatJSON, FormatNATS, FormatCBOR, codec.NameMessagePack, codec.NameProtobuf} {
+		t.Run(format, func(t *testing.T) {
+			marshaller, err := newMarshaller(format)
+			require.NoError(t, err)
+
+			msg, err := marshaller.Marshal(expected, "test.subject")
+			require.NoError(t, err)
+			assert.Equal(t, "test.subject", msg.Subject)
+
+			var actual types.MessageEnvelope
+			require.NoError(t, marshaller.Unmarshal(msg, &actual))
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestNatsMarshallerRawPayload(t *testing.T) {
+	marshaller := &natsMarshaller{}
+
+	msg, err := marshaller.Marshal(types.MessageEnvelope{Payload: []byte("raw")}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("raw"), msg.Data)
+
+	invalid := nats.NewMsg("test")
+	invalid.Header.Set(errorCodeHeader, "NaN")
+	require.Error(t, marshaller.Unmarshal(invalid, &types.MessageEnvelope{}))
+
+	invalid = nats.NewMsg("test")
+	invalid.Header.Set(sequenceHeader, "NaN")
+	require.Error(t, marshaller.Unmarshal(invalid, &types.MessageEnvelope{}))
+}
+
+func TestCodecMarshallersDecodeDetectedCodecs(t *testing.T) {
+	expected := types.MessageEnvelope{CorrelationID: "123", Payload: []byte{0x00, 0xff}, QueryParams: map[string]string{}}
+
+	var messages []*nats.Msg
+	for _, c := range []codec.Codec{codec.JSON, codec.CBOR, codec.MessagePack} {
+		msg, err := (&codecMarshaller{codec: c}).Marshal(expected, "test")
+		require.NoError(t, err)
+		messages = append(messages, msg)
+	}
+
+	for _, c := range []codec.Codec{codec.JSON, codec.CBOR, codec.MessagePack, codec.Protobuf} {
+		marshaller := &codecMarshaller{codec: c}
+		for _, msg := range messages {
+			var actual types.MessageEnvelope
+			require.NoError(t, marshaller.Unmarshal(msg, &actual))
+			assert.Equal(t, expected, actual)
+		}
+	}
+}
+
+func TestJsonMarshallerInvalidData(t *testing.T) {
+	marshaller := &codecMarshaller{codec: codec.JSON}
+	require.Error(t, marshaller.Unmarshal(&nats.Msg{Data: []byte("not json")}, &types.MessageEnvelope{}))
+}
+
+func TestNewMarshallerUnknownFormat(t *testing.T) {
+	_, err := newMarshaller("xml")
+	require.Error(t, err)
+}
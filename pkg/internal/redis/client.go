@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
@@ -28,8 +29,15 @@ type Client struct {
 	redisClient RedisClient
 
 	// Used to avoid multiple subscriptions to the same topic
-	existingTopics map[string]bool
-	mapMutex       *sync.Mutex
+	subscriptions map[string]*subscription
+	mapMutex      *sync.Mutex
+}
+
+// subscription is the go func receiving the messages of a topic, which exits once its context is cancelled.
+type subscription struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 // NewClient creates a new Client based on the provided configuration.
@@ -83,9 +91,9 @@ func NewClientWithCreator(
 	}
 
 	return Client{
-		redisClient:    client,
-		existingTopics: make(map[string]bool),
-		mapMutex:       new(sync.Mutex),
+		redisClient:   client,
+		subscriptions: make(map[string]*subscription),
+		mapMutex:      new(sync.Mutex),
 	}, nil
 }
 
@@ -97,6 +105,11 @@ func (c Client) Connect() error {
 
 // Publish sends the provided message to appropriate Redis Pub/Sub.
 func (c Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which gives up once the context is done, even when blocked on a dead connection.
+func (c Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
 	if c.redisClient == nil {
 		return internal.NewMissingConfigurationErr("Broker", "Unable to create a connection for publishing")
 	}
@@ -108,9 +121,9 @@ func (c Client) Publish(message types.MessageEnvelope, topic string) error {
 
 	topic = convertToRedisTopicScheme(topic)
 	var err error
-	if err = c.redisClient.Send(topic, message); err != nil && strings.Contains(err.Error(), "EOF") {
+	if err = c.redisClient.Send(ctx, topic, message); err != nil && strings.Contains(err.Error(), "EOF") {
 		// Redis may have been restarted and the first attempt will fail with EOF, so need to try again
-		err = c.redisClient.Send(topic, message)
+		err = c.redisClient.Send(ctx, topic, message)
 	}
 
 	return err
@@ -119,11 +132,21 @@ func (c Client) Publish(message types.MessageEnvelope, topic string) error {
 // Subscribe creates background processes which reads messages from the appropriate Redis Pub/Sub and sends to the
 // provided channels
 func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which gives up waiting for the subscriptions to be created once the context is done,
+// the subscriptions are then removed.
+func (c Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
 	if c.redisClient == nil {
 		return internal.NewMissingConfigurationErr("Broker", "Unable to create a connection for subscribing")
 	}
 
-	err := c.validateTopics(topics)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	subscriptions, err := c.addSubscriptions(topics)
 	if err != nil {
 		return err
 	}
@@ -132,7 +155,9 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 
 	for i := range topics {
 		wg.Add(1)
-		go func(topic types.TopicChannel) {
+		go func(topic types.TopicChannel, s *subscription) {
+			defer close(s.done)
+
 			topicName := convertToRedisTopicScheme(topic.Topic)
 			messageChannel := topic.Messages
 			var previousErr error
@@ -141,22 +166,16 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 
 			wg.Done()
 
-			for {
+			// Do the actual unsubscribe from Redis with the Redis style topic once this go func exits.
+			defer c.redisClient.Unsubscribe(topicName)
 
-				message, err := c.redisClient.Receive(topicName)
+			for {
+				message, err := c.redisClient.Receive(s.ctx, topicName)
 
 				// Make sure the topic is still subscribed before processing the message.
-				// If not cleanup and exit from the go func
-				c.mapMutex.Lock()
-				subscribed := c.existingTopics[topic.Topic]
-				if !subscribed {
-					delete(c.existingTopics, topic.Topic)
-					// Do the actual unsubscribe from Redis with the Redis style topic now that this go func can exit.
-					c.redisClient.Unsubscribe(topicName)
-					c.mapMutex.Unlock()
+				if s.ctx.Err() != nil {
 					return
 				}
-				c.mapMutex.Unlock()
 
 				if err != nil {
 					// This handles case when getting same repeated error due to Redis connectivity issue
@@ -165,7 +184,12 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 						time.Sleep(1 * time.Millisecond) // Sleep allows other threads to get time
 						continue
 					}
-					messageErrors <- err
+
+					select {
+					case messageErrors <- err:
+					case <-s.ctx.Done():
+						return
+					}
 
 					previousErr = err
 					continue
@@ -174,42 +198,75 @@ func (c Client) Subscribe(topics []types.TopicChannel, messageErrors chan error)
 				previousErr = nil
 				message.ReceivedTopic = convertFromRedisTopicScheme(message.ReceivedTopic)
 
-				messageChannel <- *message
+				select {
+				case messageChannel <- *message:
+				case <-s.ctx.Done():
+					return
+				}
 			}
-		}(topics[i])
+		}(topics[i], subscriptions[i])
 	}
 
 	// Wait for all the subscribe go funcs to be spun up
 	// This is needed for the Request API since the subscribe must be spun up prior to the response being published.
-	wg.Wait()
-
-	return nil
+	subscribed := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(subscribed)
+	}()
+
+	select {
+	case <-subscribed:
+		return nil
+	case <-ctx.Done():
+		names := make([]string, 0, len(topics))
+		for _, topic := range topics {
+			names = append(names, topic.Topic)
+		}
+		c.removeSubscriptions(names)
 
+		return ctx.Err()
+	}
 }
 
 func (c Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
-	return internal.DoRequest(c.Subscribe, c.Unsubscribe, c.Publish, message, requestTopic, responseTopicPrefix, timeout)
-}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-func (c Client) Unsubscribe(topics ...string) error {
-	c.mapMutex.Lock()
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
 
-	for _, topic := range topics {
-		c.existingTopics[topic] = false
-	}
+// RequestContext is Request which waits for the response until the context is done.
+func (c Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
 
-	c.mapMutex.Unlock()
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
 
-	for _, topic := range topics {
-		// Must publish a dummy message to kick the subscription go func out of the Receive() call
-		_ = c.Publish(types.MessageEnvelope{}, topic)
+// Unsubscribe stops receiving the messages of the topics. Once returned no more messages are sent to the channels of
+// these subscriptions.
+func (c Client) Unsubscribe(topics ...string) error {
+	for _, s := range c.removeSubscriptions(topics) {
+		<-s.done
 	}
 
 	return nil
 }
 
-// Disconnect closes connections to the Redis server.
+// Disconnect removes the subscriptions and closes connections to the Redis server.
 func (c Client) Disconnect() error {
+	c.mapMutex.Lock()
+	topics := make([]string, 0, len(c.subscriptions))
+	for topic := range c.subscriptions {
+		topics = append(topics, topic)
+	}
+	c.mapMutex.Unlock()
+
+	_ = c.Unsubscribe(topics...)
+
 	var disconnectErrors []string
 	if c.redisClient != nil {
 		err := c.redisClient.Close()
@@ -225,21 +282,53 @@ func (c Client) Disconnect() error {
 	return nil
 }
 
-func (c Client) validateTopics(topics []types.TopicChannel) error {
+// addSubscriptions validates the topics are unique, i.e. not existing subscription, and then adds their subscriptions.
+func (c Client) addSubscriptions(topics []types.TopicChannel) ([]*subscription, error) {
 	c.mapMutex.Lock()
 	defer c.mapMutex.Unlock()
 
-	// First validate all the topics are unique, i.e. not existing subscription
+	for i, topic := range topics {
+		if _, exists := c.subscriptions[topic.Topic]; exists {
+			return nil, fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+
+		for _, other := range topics[:i] {
+			if other.Topic == topic.Topic {
+				return nil, fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+			}
+		}
+	}
+
+	subscriptions := make([]*subscription, 0, len(topics))
+	for _, topic := range topics {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &subscription{ctx: ctx, cancel: cancel, done: make(chan struct{})}
+		c.subscriptions[topic.Topic] = s
+		subscriptions = append(subscriptions, s)
+	}
+
+	return subscriptions, nil
+}
+
+// removeSubscriptions removes the subscriptions of the topics and cancels their go funcs, which are returned so that
+// the caller can wait for them to exit.
+func (c Client) removeSubscriptions(topics []string) []*subscription {
+	c.mapMutex.Lock()
+	defer c.mapMutex.Unlock()
+
+	var removed []*subscription
 	for _, topic := range topics {
-		_, exists := c.existingTopics[topic.Topic]
-		if exists {
-			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		s, exists := c.subscriptions[topic]
+		if !exists {
+			continue
 		}
 
-		c.existingTopics[topic.Topic] = true
+		s.cancel()
+		delete(c.subscriptions, topic)
+		removed = append(removed, s)
 	}
 
-	return nil
+	return removed
 }
 
 // createRedisClient helper function for creating RedisClient implementations.
@@ -267,7 +356,7 @@ func createRedisClient(
 		return nil, err
 	}
 
-	return creator(redisServerURL, optionalClientConfiguration.Password, tlsConfig)
+	return creator(redisServerURL, optionalClientConfiguration, tlsConfig)
 }
 
 func convertToRedisTopicScheme(topic string) string {
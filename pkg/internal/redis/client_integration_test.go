@@ -70,7 +70,7 @@ func TestRedisUnsubscribeIntegration(t *testing.T) {
 	println("Subscribing to topic: " + eventTopic)
 	err = client.Subscribe(topics, errs)
 	require.NoError(t, err)
-	require.Equal(t, 1, len(client.existingTopics))
+	require.Equal(t, 1, len(client.subscriptions))
 
 	messageCount := 0
 
@@ -96,7 +96,7 @@ func TestRedisUnsubscribeIntegration(t *testing.T) {
 					require.NoError(t, err)
 
 					time.Sleep(time.Second)
-					_, exists := client.existingTopics[eventTopic]
+					_, exists := client.subscriptions[eventTopic]
 					assert.False(t, exists)
 				}
 			}
@@ -106,7 +106,7 @@ func TestRedisUnsubscribeIntegration(t *testing.T) {
 
 	wg.Wait()
 	assert.Greater(t, messageCount, 3)
-	assert.Equal(t, 0, len(client.existingTopics))
+	assert.Equal(t, 0, len(client.subscriptions))
 }
 
 // TestRedisRequestIntegration depends on Redis and Device Virtual to be running
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
@@ -261,6 +262,7 @@ func TestClient_Publish(t *testing.T) {
 				{
 					methodName: "Send",
 					arg: []interface{}{
+						mock.Anything,
 						Topic,
 						ValidMessage,
 					},
@@ -278,6 +280,7 @@ func TestClient_Publish(t *testing.T) {
 				{
 					methodName: "Send",
 					arg: []interface{}{
+						mock.Anything,
 						Topic,
 						emptyMessage,
 					},
@@ -405,14 +408,6 @@ func TestClient_Unsubscribe(t *testing.T) {
 	testTopic2 := "test2"
 	testTopic3 := "test3"
 
-	receiveWaitMap := map[string]*sync.WaitGroup{}
-	receiveWaitMap[testTopic1] = &sync.WaitGroup{}
-	receiveWaitMap[testTopic1].Add(1)
-	receiveWaitMap[testTopic2] = &sync.WaitGroup{}
-	receiveWaitMap[testTopic2].Add(1)
-	receiveWaitMap[testTopic3] = &sync.WaitGroup{}
-	receiveWaitMap[testTopic3].Add(1)
-
 	unsubscribeWaitMap := map[string]*sync.WaitGroup{}
 	unsubscribeWaitMap[testTopic1] = &sync.WaitGroup{}
 	unsubscribeWaitMap[testTopic1].Add(1)
@@ -428,21 +423,17 @@ func TestClient_Unsubscribe(t *testing.T) {
 			Protocol: "redis",
 		},
 	}
-	creator := func(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
+	creator := func(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error) {
 		redisMock := &redisMocks.RedisClient{}
 		redisMock.On("Subscribe", mock.Anything)
 		redisMock.On("Unsubscribe", mock.Anything).Run(func(args mock.Arguments) {
 			topic := args.Get(0).(string)
 			unsubscribeWaitMap[topic].Done()
 		})
-		redisMock.On("Receive", mock.Anything).After(time.Millisecond*100).Run(func(args mock.Arguments) {
-			topic := args.Get(0).(string)
-			receiveWaitMap[topic].Wait()
-		}).Return(&types.MessageEnvelope{}, nil)
-		redisMock.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
-			topic := args.Get(0).(string)
-			receiveWaitMap[topic].Done()
-		}).Return(nil)
+		// Receive blocks until the subscription's context is cancelled, which Unsubscribe must do
+		redisMock.On("Receive", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).Return(nil, context.Canceled)
 		return redisMock, nil
 	}
 
@@ -471,45 +462,92 @@ func TestClient_Unsubscribe(t *testing.T) {
 	require.NoError(t, err)
 
 	target.mapMutex.Lock()
-	set, exists := target.existingTopics[testTopic1]
-	require.True(t, set)
+	_, exists := target.subscriptions[testTopic1]
 	require.True(t, exists)
-	set, exists = target.existingTopics[testTopic2]
-	require.True(t, set)
+	_, exists = target.subscriptions[testTopic2]
 	require.True(t, exists)
-	set, exists = target.existingTopics[testTopic3]
-	require.True(t, set)
+	_, exists = target.subscriptions[testTopic3]
 	require.True(t, exists)
 	target.mapMutex.Unlock()
 
 	err = target.Unsubscribe(testTopic1)
 	require.NoError(t, err)
 
-	// Need to wait for unsubscribe to have occurred
+	// Unsubscribe waits for the subscription go func to have unsubscribed from Redis
 	unsubscribeWaitMap[testTopic1].Wait()
 
 	target.mapMutex.Lock()
-	set, exists = target.existingTopics[testTopic1]
-	require.False(t, set)
+	_, exists = target.subscriptions[testTopic1]
 	require.False(t, exists)
 	target.mapMutex.Unlock()
 
 	err = target.Unsubscribe(testTopic2, testTopic3)
 	require.NoError(t, err)
 
-	// Need to wait for unsubscribes to have occurred
 	unsubscribeWaitMap[testTopic2].Wait()
 	unsubscribeWaitMap[testTopic3].Wait()
 
 	target.mapMutex.Lock()
-	set, exists = target.existingTopics[testTopic2]
-	require.False(t, set)
+	_, exists = target.subscriptions[testTopic2]
 	require.False(t, exists)
-	set, exists = target.existingTopics[testTopic3]
-	require.False(t, set)
+	_, exists = target.subscriptions[testTopic3]
 	require.False(t, exists)
 	target.mapMutex.Unlock()
 
+	// The topic can be subscribed again once unsubscribed
+	err = target.Subscribe(topics[:1], errs)
+	require.NoError(t, err)
+}
+
+func TestClient_PublishContext(t *testing.T) {
+	creator := func(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error) {
+		redisMock := &redisMocks.RedisClient{}
+		// Simulates a dead connection where sending blocks until given up
+		redisMock.On("Send", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).Return(context.DeadlineExceeded)
+		return redisMock, nil
+	}
+
+	target, err := NewClientWithCreator(types.MessageBusConfig{Broker: HostInfo}, creator, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = target.PublishContext(ctx, types.MessageEnvelope{}, "UnitTestTopic")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_RequestContext(t *testing.T) {
+	target, err := NewClientWithCreator(types.MessageBusConfig{Broker: HostInfo},
+		mockSubscriptionClientCreator(0, 0), nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	target.redisClient = &requestRedisClientMock{SubscriptionRedisClientMock: target.redisClient.(*SubscriptionRedisClientMock)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err = target.RequestContext(ctx, types.MessageEnvelope{}, "request", "response")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The response subscription is removed
+	target.mapMutex.Lock()
+	assert.Empty(t, target.subscriptions)
+	target.mapMutex.Unlock()
+}
+
+// requestRedisClientMock accepts the requests which are never answered.
+type requestRedisClientMock struct {
+	*SubscriptionRedisClientMock
+}
+
+func (r *requestRedisClientMock) Send(context.Context, string, types.MessageEnvelope) error {
+	return nil
 }
 
 func mockCertCreator(returnError error) internal.X509KeyPairCreator {
@@ -554,19 +592,19 @@ func mockRedisClientCreator(outlines []mockOutline, returnedError error) RedisCl
 		mockRedisClient.On(outline.methodName, outline.arg...).Return(outline.ret...)
 	}
 
-	return func(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
+	return func(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error) {
 		return mockRedisClient, returnedError
 	}
 }
 
 func mockNilRedisClientCreator() RedisClientCreator {
-	return func(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
+	return func(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error) {
 		return nil, nil
 	}
 }
 
 func mockSubscriptionClientCreator(numberOfMessages int, numberOfErrors int) RedisClientCreator {
-	return func(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
+	return func(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error) {
 		return &SubscriptionRedisClientMock{
 			NumberOfMessages: numberOfMessages,
 			NumberOfErrors:   numberOfErrors,
@@ -586,7 +624,7 @@ type SubscriptionRedisClientMock struct {
 	counterMutex *sync.Mutex
 }
 
-func (r *SubscriptionRedisClientMock) Send(string, types.MessageEnvelope) error {
+func (r *SubscriptionRedisClientMock) Send(context.Context, string, types.MessageEnvelope) error {
 	panic("implement me")
 
 }
@@ -599,7 +637,7 @@ func (r *SubscriptionRedisClientMock) Unsubscribe(_ string) {
 
 }
 
-func (r *SubscriptionRedisClientMock) Receive(topic string) (*types.MessageEnvelope, error) {
+func (r *SubscriptionRedisClientMock) Receive(ctx context.Context, topic string) (*types.MessageEnvelope, error) {
 	r.counterMutex.Lock()
 
 	if r.messagesReturned < r.NumberOfMessages {
@@ -618,11 +656,9 @@ func (r *SubscriptionRedisClientMock) Receive(topic string) (*types.MessageEnvel
 
 	r.counterMutex.Unlock()
 
-	for {
-		// Sleep to simulate no more messages which will block the caller.
-		// This does not affect the execution of the test as this is run in a different Go-routine
-		time.Sleep(time.Duration(10) * time.Second)
-	}
+	// Block until unsubscribed to simulate no more messages.
+	<-ctx.Done()
+	return nil, ctx.Err()
 }
 
 func (r *SubscriptionRedisClientMock) Close() error {
@@ -1,14 +1,35 @@
 package redis
 
 import (
+	"fmt"
+	"messaging/pkg/codec"
 	"messaging/pkg/internal"
 	"messaging/pkg/types"
+	"strings"
 )
 
+// AddressSeparator separates the addresses of the SentinelAddrs and ClusterAddrs lists.
+const AddressSeparator = ","
+
 // OptionalClientConfiguration contains additional configuration properties which can be provided via the
 // MessageBus.Optional's field.
 type OptionalClientConfiguration struct {
 	Password string
+	Format   string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+
+	// Redis Streams specifics, the consumer groups are only kept across restarts with a ClientId or QueueGroup
+	ClientId     string // Names the consumer and, without a QueueGroup, its consumer groups
+	QueueGroup   string // Names the consumer groups shared by the competing consumers
+	MaxLen       int
+	ClaimMinIdle int // Seconds
+
+	// Redis Sentinel specifics, the master is resolved through the sentinels instead of the Broker's host
+	SentinelAddrs    string // Comma separated list of host:port
+	MasterName       string
+	SentinelPassword string
+
+	// Redis Cluster specifics, the cluster topology is discovered from the seed nodes instead of the Broker's host
+	ClusterAddrs string // Comma separated list of host:port
 }
 
 // NewClientConfiguration creates a OptionalClientConfiguration based on the configuration properties provided.
@@ -18,5 +39,41 @@ func NewClientConfiguration(config types.MessageBusConfig) (OptionalClientConfig
 	if err != nil {
 		return OptionalClientConfiguration{}, err
 	}
+
+	if _, err = codec.Lookup(redisConfig.Format); err != nil {
+		return OptionalClientConfiguration{}, err
+	}
+
+	if redisConfig.SentinelAddrs != "" && redisConfig.ClusterAddrs != "" {
+		return OptionalClientConfiguration{}, fmt.Errorf("only one of %s and %s can be set", internal.SentinelAddrs,
+			internal.ClusterAddrs)
+	}
+
+	if redisConfig.SentinelAddrs != "" && redisConfig.MasterName == "" {
+		return OptionalClientConfiguration{}, internal.NewMissingConfigurationErr(internal.MasterName,
+			"MasterName is required when using Redis Sentinel")
+	}
+
 	return redisConfig, nil
 }
+
+// SentinelAddresses returns the list of the sentinel addresses, empty when Redis Sentinel is not used.
+func (c OptionalClientConfiguration) SentinelAddresses() []string {
+	return splitAddresses(c.SentinelAddrs)
+}
+
+// ClusterAddresses returns the list of the cluster seed node addresses, empty when Redis Cluster is not used.
+func (c OptionalClientConfiguration) ClusterAddresses() []string {
+	return splitAddresses(c.ClusterAddrs)
+}
+
+func splitAddresses(addresses string) []string {
+	var result []string
+	for _, address := range strings.Split(addresses, AddressSeparator) {
+		if address = strings.TrimSpace(address); address != "" {
+			result = append(result, address)
+		}
+	}
+
+	return result
+}
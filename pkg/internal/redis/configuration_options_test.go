@@ -58,6 +58,86 @@ func TestNewClientConfiguration(t *testing.T) {
 			want:    OptionalClientConfiguration{},
 			wantErr: false,
 		},
+		{
+			name: "Create Redis Streams OptionalClientConfiguration",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"ClientId":     "consumer-1",
+					"QueueGroup":   "group",
+					"MaxLen":       "1000",
+					"ClaimMinIdle": "60",
+				},
+			},
+			want:    OptionalClientConfiguration{ClientId: "consumer-1", QueueGroup: "group", MaxLen: 1000, ClaimMinIdle: 60},
+			wantErr: false,
+		},
+		{
+			name:    "Create CBOR OptionalClientConfiguration",
+			config:  types.MessageBusConfig{Optional: map[string]string{"Format": "cbor"}},
+			want:    OptionalClientConfiguration{Format: "cbor"},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid Format",
+			config:  types.MessageBusConfig{Optional: map[string]string{"Format": "xml"}},
+			want:    OptionalClientConfiguration{},
+			wantErr: true,
+		},
+		{
+			name: "Create Redis Sentinel OptionalClientConfiguration",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"SentinelAddrs":    "sentinel1:26379,sentinel2:26379",
+					"MasterName":       "mymaster",
+					"SentinelPassword": "secret",
+				},
+			},
+			want: OptionalClientConfiguration{
+				SentinelAddrs:    "sentinel1:26379,sentinel2:26379",
+				MasterName:       "mymaster",
+				SentinelPassword: "secret",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Create Redis Cluster OptionalClientConfiguration",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"ClusterAddrs": "node1:6379,node2:6379",
+				},
+			},
+			want:    OptionalClientConfiguration{ClusterAddrs: "node1:6379,node2:6379"},
+			wantErr: false,
+		},
+		{
+			name: "Sentinel without MasterName",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"SentinelAddrs": "sentinel1:26379",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Both Sentinel and Cluster",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"SentinelAddrs": "sentinel1:26379",
+					"MasterName":    "mymaster",
+					"ClusterAddrs":  "node1:6379",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid MaxLen",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"MaxLen": "many",
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -75,3 +155,14 @@ func TestNewClientConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestOptionalClientConfigurationAddresses(t *testing.T) {
+	config := OptionalClientConfiguration{
+		SentinelAddrs: "sentinel1:26379, sentinel2:26379,",
+		ClusterAddrs:  "node1:6379",
+	}
+
+	assert.Equal(t, []string{"sentinel1:26379", "sentinel2:26379"}, config.SentinelAddresses())
+	assert.Equal(t, []string{"node1:6379"}, config.ClusterAddresses())
+	assert.Empty(t, OptionalClientConfiguration{}.SentinelAddresses())
+}
@@ -1,56 +1,64 @@
 package redis
 
 import (
+	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	goRedis "github.com/go-redis/redis/v7"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
 	"messaging/pkg/types"
 	"strings"
 	"sync"
 )
 
-// goRedisWrapper implements RedisClient and uses a underlying 'go-redis' client to communicate with a Redis server.
+// goRedisWrapper implements RedisClient and uses a underlying 'go-redis' client to communicate with a Redis server,
+// a Redis Sentinel monitored master or a Redis Cluster.
 //
 // This functionality was abstracted out from Client so that unit testing can be done easily. The functionality provided
 // by this struct can be complex to test and has been tested in the integration test.
 type goRedisWrapper struct {
-	wrappedClient      *goRedis.Client
+	wrappedClient      goRedis.UniversalClient
 	subscriptions      map[string]*goRedis.PubSub
 	subscriptionsMutex *sync.Mutex
+	codec              codec.Codec
 }
 
 // NewGoRedisClientWrapper creates a RedisClient implementation which uses a 'go-redis' Client to achieve the necessary
 // functionality.
-func NewGoRedisClientWrapper(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error) {
-	options, err := goRedis.ParseURL(redisServerURL)
+//
+// Pub/Sub subscriptions are re-established by 'go-redis' when the connection is lost, so when using Redis Sentinel the
+// subscriptions move over to the new master after a failover.
+func NewGoRedisClientWrapper(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error) {
+	envelopeCodec, err := codec.Lookup(optionalConfiguration.Format)
 	if err != nil {
 		return nil, err
 	}
 
-	options.Password = password
-	options.TLSConfig = tlsConfig
+	client, err := newGoRedisClient(redisServerURL, optionalConfiguration, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	return &goRedisWrapper{
-		wrappedClient:      goRedis.NewClient(options),
+		wrappedClient:      client,
 		subscriptions:      make(map[string]*goRedis.PubSub),
 		subscriptionsMutex: &sync.Mutex{},
+		codec:              envelopeCodec,
 	}, nil
 }
 
-// Send sends the provided message to a topic
-func (g *goRedisWrapper) Send(topic string, message types.MessageEnvelope) error {
-	encoded, err := json.Marshal(message)
+// Send sends the provided message to a topic. Sending is abandoned once the context is done, even when blocked on an
+// unresponsive connection.
+func (g *goRedisWrapper) Send(ctx context.Context, topic string, message types.MessageEnvelope) error {
+	encoded, err := g.codec.Encode(message)
 	if err != nil {
 		return err
 	}
 
-	_, err = g.wrappedClient.Publish(topic, encoded).Result()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return internal.RunWithContext(ctx, func() error {
+		return g.wrappedClient.ProcessContext(ctx, goRedis.NewIntCmd("publish", topic, encoded))
+	})
 }
 
 // Subscribe creates the subscription in Redis
@@ -72,19 +80,41 @@ func (g *goRedisWrapper) Unsubscribe(topic string) {
 	delete(g.subscriptions, topic)
 }
 
-// Receive retrieves the next message from the specified topic. This operation blocks indefinitely until a
-// message is received for the topic.
-func (g *goRedisWrapper) Receive(topic string) (*types.MessageEnvelope, error) {
-	subscription := g.getSubscription(topic)
+// Receive retrieves the next message from the specified topic. This operation blocks until a message is received for
+// the topic or the context is done, the subscription is then closed to unblock it.
+func (g *goRedisWrapper) Receive(ctx context.Context, topic string) (*types.MessageEnvelope, error) {
+	// Checked under the lock so that a subscription already closed for the context isn't created again
+	g.subscriptionsMutex.Lock()
+	if err := ctx.Err(); err != nil {
+		g.subscriptionsMutex.Unlock()
+		return nil, err
+	}
+	subscription := g.subscription(topic)
+	g.subscriptionsMutex.Unlock()
+
+	// Only this subscription is closed since another one may have been created for the topic by then
+	stop := context.AfterFunc(ctx, func() {
+		g.subscriptionsMutex.Lock()
+		defer g.subscriptionsMutex.Unlock()
+
+		_ = subscription.Close()
+		if g.subscriptions[topic] == subscription {
+			delete(g.subscriptions, topic)
+		}
+	})
+	defer stop()
 
 	data, err := subscription.ReceiveMessage()
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
 
 	message := &types.MessageEnvelope{}
 	payload := []byte(data.Payload)
-	err = json.Unmarshal(payload, message)
+	err = codec.Decode(payload, message, g.codec)
 	if err != nil {
 		return nil, fmt.Errorf("unable to unmarshal payload: %w", err)
 	}
@@ -109,6 +139,12 @@ func (g *goRedisWrapper) Close() error {
 func (g *goRedisWrapper) getSubscription(topic string) *goRedis.PubSub {
 	g.subscriptionsMutex.Lock()
 	defer g.subscriptionsMutex.Unlock()
+	return g.subscription(topic)
+}
+
+// subscription returns the subscription to the topic, creating it when missing. Must be called with the
+// subscriptionsMutex held.
+func (g *goRedisWrapper) subscription(topic string) *goRedis.PubSub {
 	subscription, exists := g.subscriptions[topic]
 	if !exists {
 		// Redis Pub/Sub wildcard doesn't cover empty sub channel level, to match MQTT multi-level wildcard,
@@ -123,3 +159,37 @@ func (g *goRedisWrapper) getSubscription(topic string) *goRedis.PubSub {
 	}
 	return subscription
 }
+
+// newGoRedisClient creates the underlying 'go-redis' client for the topology configured, i.e. a single Redis server,
+// a master monitored by Redis Sentinel or a Redis Cluster. The Redis server URL still provides the options common to
+// all topologies such as the database.
+func newGoRedisClient(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (goRedis.UniversalClient, error) {
+	options, err := goRedis.ParseURL(redisServerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Password = optionalConfiguration.Password
+	options.TLSConfig = tlsConfig
+
+	if sentinelAddrs := optionalConfiguration.SentinelAddresses(); len(sentinelAddrs) > 0 {
+		return goRedis.NewFailoverClient(&goRedis.FailoverOptions{
+			MasterName:       optionalConfiguration.MasterName,
+			SentinelAddrs:    sentinelAddrs,
+			SentinelPassword: optionalConfiguration.SentinelPassword,
+			Password:         options.Password,
+			DB:               options.DB,
+			TLSConfig:        tlsConfig,
+		}), nil
+	}
+
+	if clusterAddrs := optionalConfiguration.ClusterAddresses(); len(clusterAddrs) > 0 {
+		return goRedis.NewClusterClient(&goRedis.ClusterOptions{
+			Addrs:     clusterAddrs,
+			Password:  options.Password,
+			TLSConfig: tlsConfig,
+		}), nil
+	}
+
+	return goRedis.NewClient(options), nil
+}
@@ -0,0 +1,263 @@
+package redis
+
+import (
+	"context"
+	"messaging/pkg/types"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMasterName = "mymaster"
+
+// fakeSentinel provides the subset of the Redis Sentinel commands used by 'go-redis' to resolve the master.
+type fakeSentinel struct {
+	server     *server.Server
+	masterAddr string
+	mutex      sync.Mutex
+}
+
+func startFakeSentinel(t *testing.T, masterAddr string) *fakeSentinel {
+	srv, err := server.NewServer("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	sentinel := &fakeSentinel{server: srv, masterAddr: masterAddr}
+	require.NoError(t, srv.Register("SENTINEL", sentinel.sentinelCommand))
+	require.NoError(t, srv.Register("SUBSCRIBE", func(c *server.Peer, _ string, args []string) {
+		for i, channel := range args {
+			c.WriteLen(3)
+			c.WriteBulk("subscribe")
+			c.WriteBulk(channel)
+			c.WriteInt(i + 1)
+		}
+	}))
+	require.NoError(t, srv.Register("PING", func(c *server.Peer, _ string, _ []string) {
+		c.WriteInline("PONG")
+	}))
+
+	return sentinel
+}
+
+func (s *fakeSentinel) sentinelCommand(c *server.Peer, _ string, args []string) {
+	switch {
+	case len(args) == 2 && strings.EqualFold(args[0], "get-master-addr-by-name") && args[1] == testMasterName:
+		s.mutex.Lock()
+		host, port, _ := net.SplitHostPort(s.masterAddr)
+		s.mutex.Unlock()
+		c.WriteStrings([]string{host, port})
+	case len(args) == 2 && strings.EqualFold(args[0], "sentinels"):
+		c.WriteLen(0)
+	default:
+		c.WriteNull()
+	}
+}
+
+func (s *fakeSentinel) failover(masterAddr string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.masterAddr = masterAddr
+}
+
+func (s *fakeSentinel) addr() string {
+	return s.server.Addr().String()
+}
+
+func newTopologyTestClient(t *testing.T, optional map[string]string) Client {
+	client, err := NewClient(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: "localhost", Port: 6379, Protocol: "redis"},
+		Type:     "redis",
+		Optional: optional,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func subscribeTopologyTest(t *testing.T, client Client, topic string) chan types.MessageEnvelope {
+	messages := make(chan types.MessageEnvelope, 10)
+	messageErrors := make(chan error)
+	go func() {
+		// Connection errors are expected while failing over
+		for range messageErrors {
+		}
+	}()
+
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: topic, Messages: messages}}, messageErrors))
+
+	return messages
+}
+
+// publishUntilReceived publishes until the subscriber receives the message, since the Pub/Sub subscription is
+// re-established asynchronously after a failover and messages published in between are lost.
+func publishUntilReceived(t *testing.T, client Client, topic string, messages chan types.MessageEnvelope, correlationID string) {
+	require.Eventually(t, func() bool {
+		_ = client.Publish(types.MessageEnvelope{CorrelationID: correlationID}, topic)
+
+		select {
+		case message := <-messages:
+			return message.CorrelationID == correlationID
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestGoRedisSentinelFailover(t *testing.T) {
+	master := miniredis.RunT(t)
+	replacement := miniredis.RunT(t)
+	sentinel := startFakeSentinel(t, master.Addr())
+
+	client := newTopologyTestClient(t, map[string]string{
+		"SentinelAddrs": sentinel.addr(),
+		"MasterName":    testMasterName,
+	})
+	messages := subscribeTopologyTest(t, client, "edgex/events")
+
+	publishUntilReceived(t, client, "edgex/events", messages, "before")
+
+	sentinel.failover(replacement.Addr())
+	master.Close()
+
+	publishUntilReceived(t, client, "edgex/events", messages, "after")
+}
+
+func TestGoRedisCluster(t *testing.T) {
+	node := miniredis.RunT(t)
+
+	client := newTopologyTestClient(t, map[string]string{"ClusterAddrs": node.Addr()})
+	messages := subscribeTopologyTest(t, client, "edgex/#")
+
+	publishUntilReceived(t, client, "edgex/events", messages, "cluster")
+}
+
+func TestNewGoRedisClientWrapperInvalidURL(t *testing.T) {
+	_, err := NewGoRedisClientWrapper("invalid://localhost", OptionalClientConfiguration{}, nil)
+	assert.Error(t, err)
+}
+
+func TestGoRedisReceiveCancelled(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	wrapper, err := NewGoRedisClientWrapper("redis://"+redisServer.Addr(), OptionalClientConfiguration{}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = wrapper.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Blocks as nothing is published until the context is cancelled
+	_, err = wrapper.Receive(ctx, "edgex.events")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Receiving with a new context subscribes again
+	received := make(chan *types.MessageEnvelope, 1)
+	go func() {
+		message, _ := wrapper.Receive(context.Background(), "edgex.events")
+		received <- message
+	}()
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, wrapper.Send(context.Background(), "edgex.events", types.MessageEnvelope{CorrelationID: "123"}))
+
+		select {
+		case message := <-received:
+			return assert.Equal(t, "123", message.CorrelationID)
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGoRedisSendCancelled(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	wrapper, err := NewGoRedisClientWrapper("redis://"+redisServer.Addr(), OptionalClientConfiguration{}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = wrapper.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = wrapper.Send(ctx, "edgex.events", types.MessageEnvelope{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGoRedisMixedFormats(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	receiver, err := NewGoRedisClientWrapper("redis://"+redisServer.Addr(), OptionalClientConfiguration{}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = receiver.Close() })
+
+	cborSender, err := NewGoRedisClientWrapper("redis://"+redisServer.Addr(), OptionalClientConfiguration{Format: "cbor"}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cborSender.Close() })
+
+	received := make(chan *types.MessageEnvelope, 10)
+	go func() {
+		for {
+			message, err := receiver.Receive(context.Background(), "edgex.events")
+			if err != nil {
+				return
+			}
+			received <- message
+		}
+	}()
+
+	// The JSON and CBOR publishers coexist, the receiver detects the encoding of each message
+	payload := []byte{0x00, 0xff}
+	for _, sender := range map[string]RedisClient{"cbor": cborSender, "json": receiver} {
+		correlationID := uuid.NewString()
+		require.Eventually(t, func() bool {
+			message := types.MessageEnvelope{CorrelationID: correlationID, Payload: payload}
+			require.NoError(t, sender.Send(context.Background(), "edgex.events", message))
+
+			select {
+			case message := <-received:
+				return message.CorrelationID == correlationID && assert.Equal(t, payload, message.Payload)
+			case <-time.After(100 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestGoRedisHeaders(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	wrapper, err := NewGoRedisClientWrapper("redis://"+redisServer.Addr(), OptionalClientConfiguration{}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = wrapper.Close() })
+
+	wrapper.Subscribe("edgex.events")
+	received := make(chan *types.MessageEnvelope, 1)
+	go func() {
+		message, _ := wrapper.Receive(context.Background(), "edgex.events")
+		received <- message
+	}()
+
+	expected := types.MessageEnvelope{CorrelationID: "123", QueryParams: map[string]string{"key": "value"}}
+	expected.SetHeader(types.HeaderTenantID, "tenant")
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, wrapper.Send(context.Background(), "edgex.events", expected))
+
+		select {
+		case message := <-received:
+			return assert.Equal(t, expected.Headers, message.Headers) && assert.Equal(t, expected.QueryParams, message.QueryParams)
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
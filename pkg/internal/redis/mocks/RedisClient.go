@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 	"messaging/pkg/types"
 )
@@ -26,25 +28,25 @@ func (_m *RedisClient) Close() error {
 	return r0
 }
 
-// Receive provides a mock function with given fields: topic
-func (_m *RedisClient) Receive(topic string) (*types.MessageEnvelope, error) {
-	ret := _m.Called(topic)
+// Receive provides a mock function with given fields: ctx, topic
+func (_m *RedisClient) Receive(ctx context.Context, topic string) (*types.MessageEnvelope, error) {
+	ret := _m.Called(ctx, topic)
 
 	var r0 *types.MessageEnvelope
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (*types.MessageEnvelope, error)); ok {
-		return rf(topic)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*types.MessageEnvelope, error)); ok {
+		return rf(ctx, topic)
 	}
-	if rf, ok := ret.Get(0).(func(string) *types.MessageEnvelope); ok {
-		r0 = rf(topic)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.MessageEnvelope); ok {
+		r0 = rf(ctx, topic)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*types.MessageEnvelope)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(topic)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, topic)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -52,13 +54,13 @@ func (_m *RedisClient) Receive(topic string) (*types.MessageEnvelope, error) {
 	return r0, r1
 }
 
-// Send provides a mock function with given fields: topic, message
-func (_m *RedisClient) Send(topic string, message types.MessageEnvelope) error {
-	ret := _m.Called(topic, message)
+// Send provides a mock function with given fields: ctx, topic, message
+func (_m *RedisClient) Send(ctx context.Context, topic string, message types.MessageEnvelope) error {
+	ret := _m.Called(ctx, topic, message)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, types.MessageEnvelope) error); ok {
-		r0 = rf(topic, message)
+	if rf, ok := ret.Get(0).(func(context.Context, string, types.MessageEnvelope) error); ok {
+		r0 = rf(ctx, topic, message)
 	} else {
 		r0 = ret.Error(0)
 	}
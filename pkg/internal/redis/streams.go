@@ -0,0 +1,688 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	goRedis "github.com/go-redis/redis/v7"
+	"github.com/google/uuid"
+)
+
+const (
+	// envelopeField is the name of the stream entry field which holds the encoded MessageEnvelope.
+	envelopeField = "envelope"
+
+	// defaultClaimMinIdle is the default number of seconds an entry must have been pending before it is reclaimed
+	// from the consumer it was delivered to.
+	defaultClaimMinIdle = 30
+
+	// blockTimeout is the maximum time a XREADGROUP call blocks waiting for new entries.
+	blockTimeout = time.Second
+	// readCount is the maximum number of entries read or reclaimed at once.
+	readCount = 100
+	// streamsRefreshInterval is how often the streams matching a wildcard subscription are discovered.
+	streamsRefreshInterval = 5 * time.Second
+	// clusterPollInterval is how long to wait before reading again when no entries were found in a Redis Cluster.
+	clusterPollInterval = 100 * time.Millisecond
+)
+
+// StreamsClient MessageClient implementation which provides functionality for sending and receiving messages using
+// Redis Streams and consumer groups. Unlike Redis Pub/Sub, messages published while a subscriber is down are kept in
+// the stream and delivered once the subscriber's consumer group reads again.
+//
+// Durability requires a ClientId or QueueGroup, which names the consumer groups across restarts. Without either the
+// groups are named after a random ID and destroyed on unsubscribe, so that every client still receives all the
+// messages without leaving groups behind, but the messages published while the client is down are missed.
+type StreamsClient struct {
+	client        goRedis.UniversalClient
+	configuration OptionalClientConfiguration
+	codec         codec.Codec
+
+	// group is the base name of the consumer groups, consumers of the same group compete for messages.
+	group string
+	// ephemeralGroup indicates the group was generated for this client only and must be destroyed on unsubscribe.
+	ephemeralGroup bool
+	consumer       string
+
+	refreshInterval time.Duration
+
+	// Used to avoid multiple subscriptions to the same topic
+	subscriptions map[string]*streamSubscription
+	mapMutex      *sync.Mutex
+
+	// consumers tracks the consuming go routines, including those of unsubscribed topics still finishing up
+	consumers *sync.WaitGroup
+}
+
+// streamSubscription tracks a subscription and the streams it consumes from.
+type streamSubscription struct {
+	*internal.Delivery
+
+	topic string
+	group string
+	// response indicates the subscription is to the response topic of a request, whose stream is deleted once
+	// unsubscribed since nothing is published to it anymore
+	response bool
+
+	// streams is only accessed by the consuming go routine
+	streams map[string]bool
+}
+
+// NewStreamsClient creates a new StreamsClient based on the provided configuration.
+func NewStreamsClient(messageBusConfig types.MessageBusConfig) (*StreamsClient, error) {
+	return NewStreamsClientWithCreator(messageBusConfig, tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate,
+		os.ReadFile, pem.Decode)
+}
+
+// NewStreamsClientWithCreator creates a new StreamsClient based on the provided configuration while allowing more
+// control on the creation of the certs and keys.
+func NewStreamsClientWithCreator(
+	messageBusConfig types.MessageBusConfig,
+	pairCreator internal.X509KeyPairCreator,
+	keyLoader internal.X509KeyLoader,
+	caCertCreator internal.X509CaCertCreator,
+	caCertLoader internal.X509CaCertLoader,
+	pemDecoder internal.PEMDecoder) (*StreamsClient, error) {
+
+	optionalClientConfiguration, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(optionalClientConfiguration.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfigurationOptions := internal.TlsConfigurationOptions{}
+	err = internal.Load(messageBusConfig.Optional, &tlsConfigurationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	redisServerURL := messageBusConfig.Broker.GetHostURL()
+	tlsConfig, err := internal.GenerateTLSForClientClientOptions(
+		redisServerURL,
+		tlsConfigurationOptions,
+		pairCreator,
+		keyLoader,
+		caCertCreator,
+		caCertLoader,
+		pemDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newGoRedisClient(redisServerURL, optionalClientConfiguration, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if optionalClientConfiguration.ClaimMinIdle <= 0 {
+		optionalClientConfiguration.ClaimMinIdle = defaultClaimMinIdle
+	}
+
+	consumer := optionalClientConfiguration.ClientId
+	if consumer == "" {
+		consumer = uuid.NewString()
+	}
+
+	// Without a QueueGroup each client gets its own group so that every client receives all the messages. Without a
+	// ClientId either the group can't be found again after a restart, so it is ephemeral. A default derived from the
+	// topic alone would instead make the unrelated clients subscribing to the same topic compete for its messages.
+	group := optionalClientConfiguration.QueueGroup
+	ephemeralGroup := false
+	if group == "" {
+		group = optionalClientConfiguration.ClientId
+	}
+	if group == "" {
+		group = consumer
+		ephemeralGroup = true
+	}
+
+	return &StreamsClient{
+		client:          client,
+		configuration:   optionalClientConfiguration,
+		codec:           envelopeCodec,
+		group:           group,
+		ephemeralGroup:  ephemeralGroup,
+		consumer:        consumer,
+		refreshInterval: streamsRefreshInterval,
+		subscriptions:   make(map[string]*streamSubscription),
+		mapMutex:        new(sync.Mutex),
+		consumers:       new(sync.WaitGroup),
+	}, nil
+}
+
+// Connect noop as preemptive connections are not needed.
+func (c *StreamsClient) Connect() error {
+	// No need to connect, connection pooling is handled by the underlying client.
+	return nil
+}
+
+// Publish appends the provided message to the Redis stream mapped from the topic, trimming the stream to MaxLen
+// entries when configured.
+func (c *StreamsClient) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which gives up once the context is done, even when blocked on a dead connection.
+func (c *StreamsClient) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if topic == "" {
+		// Empty topics are not allowed for Redis
+		return internal.NewInvalidTopicErr("", "Unable to publish to the invalid topic")
+	}
+
+	encoded, err := c.codec.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	return internal.RunWithContext(ctx, func() error {
+		return c.client.XAdd(&goRedis.XAddArgs{
+			Stream:       convertToRedisTopicScheme(topic),
+			MaxLenApprox: int64(c.configuration.MaxLen),
+			Values:       map[string]interface{}{envelopeField: encoded},
+		}).Err()
+	})
+}
+
+// Subscribe creates background processes which read the messages from the Redis streams matching the topics with the
+// client's consumer group and sends them to the provided channels. Messages are acknowledged once they have been
+// handed over to the channel.
+func (c *StreamsClient) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(topics, messageErrors, false)
+}
+
+// subscribe creates the subscriptions, to the response topics of requests when response is set.
+func (c *StreamsClient) subscribe(topics []types.TopicChannel, messageErrors chan error, response bool) error {
+	c.mapMutex.Lock()
+	defer c.mapMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if _, exists := c.subscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		subscription := &streamSubscription{
+			Delivery: internal.NewDelivery(topic.Messages, messageErrors),
+			topic:    topic.Topic,
+			group:    c.groupName(topic.Topic),
+			response: response,
+			streams:  make(map[string]bool),
+		}
+
+		// The consumer groups must be in place before returning so that messages published right after are not
+		// missed, which is needed for the Request API.
+		if err := c.addStreams(subscription, "$"); err != nil {
+			return err
+		}
+
+		c.subscriptions[topic.Topic] = subscription
+		c.consumers.Add(1)
+		go c.consume(subscription)
+	}
+
+	return nil
+}
+
+// SubscribeContext is Subscribe which gives up creating the consumer groups once the context is done, the
+// subscriptions are then removed.
+func (c *StreamsClient) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return internal.SubscribeWithContext(ctx, c.Subscribe, c.Unsubscribe, topics, messageErrors)
+}
+
+// subscribeResponse is SubscribeContext for the response topic of a request, whose stream is deleted once unsubscribed.
+func (c *StreamsClient) subscribeResponse(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	subscribe := func(topics []types.TopicChannel, messageErrors chan error) error {
+		return c.subscribe(topics, messageErrors, true)
+	}
+
+	return internal.SubscribeWithContext(ctx, subscribe, c.Unsubscribe, topics, messageErrors)
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (c *StreamsClient) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *StreamsClient) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *StreamsClient) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe stops consuming the streams of the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions. Entries read but not yet delivered stay pending and are reclaimed later.
+func (c *StreamsClient) Unsubscribe(topics ...string) error {
+	c.mapMutex.Lock()
+	defer c.mapMutex.Unlock()
+
+	for _, topic := range topics {
+		subscription, exists := c.subscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		subscription.Stop()
+		delete(c.subscriptions, topic)
+	}
+
+	return nil
+}
+
+// Disconnect stops all subscriptions and closes the connections to the Redis server once the background processes
+// have exited.
+func (c *StreamsClient) Disconnect() error {
+	c.mapMutex.Lock()
+	for topic, subscription := range c.subscriptions {
+		subscription.Stop()
+		delete(c.subscriptions, topic)
+	}
+	c.mapMutex.Unlock()
+
+	c.consumers.Wait()
+
+	if err := c.client.Close(); err != nil {
+		return NewDisconnectErr([]string{fmt.Sprintf("Unable to disconnect streams client: %v", err)})
+	}
+
+	return nil
+}
+
+// consume reads the streams of the subscription until it is stopped.
+func (c *StreamsClient) consume(subscription *streamSubscription) {
+	defer c.consumers.Done()
+	defer c.cleanup(subscription)
+
+	var previousErr error
+	lastRefresh := time.Now()
+	lastReclaim := time.Time{}
+
+	// Entries delivered to this consumer before a restart which were never acknowledged come first.
+	startID := "0"
+
+	for !subscription.Stopped() {
+		if isWildcardTopic(subscription.topic) && time.Since(lastRefresh) >= c.refreshInterval {
+			// Streams created after the subscription are read from their beginning.
+			if err := c.addStreams(subscription, "0"); err != nil {
+				previousErr = subscription.ReportError(err, previousErr)
+			}
+			lastRefresh = time.Now()
+		}
+
+		if time.Since(lastReclaim) >= c.claimMinIdle() {
+			c.reclaim(subscription)
+			lastReclaim = time.Now()
+		}
+
+		if len(subscription.streams) == 0 {
+			subscription.Wait(blockTimeout)
+			continue
+		}
+
+		result, err := c.read(subscription, startID)
+		if err != nil && err != goRedis.Nil {
+			if subscription.Stopped() {
+				return
+			}
+
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				// The stream or group has been removed, so recreate them.
+				for stream := range subscription.streams {
+					_ = c.createGroup(subscription.group, stream, "0")
+				}
+			}
+
+			previousErr = subscription.ReportError(err, previousErr)
+			subscription.Wait(blockTimeout)
+			continue
+		}
+
+		previousErr = nil
+		pendingDrained := true
+		for _, stream := range result {
+			for _, entry := range stream.Messages {
+				pendingDrained = false
+				if !c.deliver(subscription, stream.Stream, entry) {
+					return
+				}
+			}
+		}
+
+		if startID == "0" && pendingDrained {
+			startID = ">"
+		}
+	}
+}
+
+// read reads the next entries of the subscription's streams. A single XREADGROUP blocks on all the streams, except with
+// Redis Cluster where the streams may be served by different nodes and are therefore read one by one without blocking.
+func (c *StreamsClient) read(subscription *streamSubscription, startID string) ([]goRedis.XStream, error) {
+	if _, isCluster := c.client.(*goRedis.ClusterClient); !isCluster || len(subscription.streams) == 1 {
+		streams := make([]string, 0, len(subscription.streams)*2)
+		for stream := range subscription.streams {
+			streams = append(streams, stream)
+		}
+		for range subscription.streams {
+			streams = append(streams, startID)
+		}
+
+		return c.readGroup(subscription.group, streams, blockTimeout)
+	}
+
+	var result []goRedis.XStream
+	for stream := range subscription.streams {
+		streams, err := c.readGroup(subscription.group, []string{stream, startID}, -1)
+		if err == goRedis.Nil {
+			continue
+		}
+		if err != nil {
+			if len(result) > 0 {
+				// Deliver what has been read, the error repeats on the next read if persistent.
+				break
+			}
+			return nil, err
+		}
+		result = append(result, streams...)
+	}
+
+	if len(result) == 0 {
+		subscription.Wait(clusterPollInterval)
+		return nil, goRedis.Nil
+	}
+
+	return result, nil
+}
+
+// readGroup reads the entries of the streams, followed by their start IDs, with the consumer group. A negative block
+// duration doesn't block.
+func (c *StreamsClient) readGroup(group string, streams []string, block time.Duration) ([]goRedis.XStream, error) {
+	return c.client.XReadGroup(&goRedis.XReadGroupArgs{
+		Group:    group,
+		Consumer: c.consumer,
+		Streams:  streams,
+		Count:    readCount,
+		Block:    block,
+	}).Result()
+}
+
+// deliver sends the entry to the subscription's channel and acknowledges it. Returns false if the subscription has
+// been stopped before the entry could be delivered.
+func (c *StreamsClient) deliver(subscription *streamSubscription, stream string, entry goRedis.XMessage) bool {
+	message, err := decodeStreamEntry(entry, c.codec)
+	if err != nil {
+		// The entry can never be processed, so acknowledge it to avoid it being redelivered forever.
+		_ = c.client.XAck(stream, subscription.group, entry.ID).Err()
+		subscription.SendError(fmt.Errorf("unable to decode entry %s of stream %s: %w", entry.ID, stream, err))
+		return true
+	}
+
+	message.ReceivedTopic = convertFromRedisTopicScheme(stream)
+	if !subscription.Send(*message) {
+		return false
+	}
+
+	if err = c.client.XAck(stream, subscription.group, entry.ID).Err(); err != nil {
+		subscription.SendError(fmt.Errorf("unable to acknowledge entry %s of stream %s: %w", entry.ID, stream, err))
+	}
+
+	return true
+}
+
+// reclaim takes over the entries of the subscription's streams which have been pending longer than ClaimMinIdle,
+// typically because the consumer they were delivered to died, and delivers them.
+func (c *StreamsClient) reclaim(subscription *streamSubscription) {
+	minIdle := c.claimMinIdle().Milliseconds()
+
+	for stream := range subscription.streams {
+		start := "0-0"
+		for {
+			reply, err := c.client.Do("XAUTOCLAIM", stream, subscription.group, c.consumer, minIdle, start, "COUNT", readCount).Result()
+			if err != nil {
+				if !strings.HasPrefix(err.Error(), "NOGROUP") {
+					subscription.SendError(fmt.Errorf("unable to reclaim pending entries of stream %s: %w", stream, err))
+				}
+				break
+			}
+
+			next, entries, err := parseAutoClaimReply(reply)
+			if err != nil {
+				subscription.SendError(err)
+				break
+			}
+
+			for _, entry := range entries {
+				if !c.deliver(subscription, stream, entry) {
+					return
+				}
+			}
+
+			if next == "0-0" || len(entries) == 0 {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+// addStreams adds the streams matching the subscription's topic which are not yet consumed, creating their consumer
+// group starting at the provided ID when missing. Streams existing when subscribing start at "$" so that only the new
+// messages are delivered, while streams discovered later start at "0".
+func (c *StreamsClient) addStreams(subscription *streamSubscription, startID string) error {
+	if !isWildcardTopic(subscription.topic) {
+		stream := convertToRedisTopicScheme(subscription.topic)
+		if subscription.streams[stream] {
+			return nil
+		}
+
+		// The stream is created along with the group so that messages published right after are captured.
+		if err := c.createGroup(subscription.group, stream, startID); err != nil {
+			return err
+		}
+
+		subscription.streams[stream] = true
+		return nil
+	}
+
+	streams, err := c.findStreams(subscription.topic)
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		if subscription.streams[stream] {
+			continue
+		}
+
+		if err = c.createGroup(subscription.group, stream, startID); err != nil {
+			return err
+		}
+		subscription.streams[stream] = true
+	}
+
+	return nil
+}
+
+// findStreams scans the keys for the streams matching the wildcard topic. With Redis Cluster the keys of every master
+// are scanned since each holds a part of the key space.
+func (c *StreamsClient) findStreams(topic string) ([]string, error) {
+	cluster, isCluster := c.client.(*goRedis.ClusterClient)
+	if !isCluster {
+		return scanStreams(c.client, topic)
+	}
+
+	var streams []string
+	var mutex sync.Mutex
+	err := cluster.ForEachMaster(func(node *goRedis.Client) error {
+		nodeStreams, err := scanStreams(node, topic)
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		streams = append(streams, nodeStreams...)
+		mutex.Unlock()
+
+		return nil
+	})
+
+	return streams, err
+}
+
+// scanStreams scans the keys of the Redis server for the streams matching the wildcard topic.
+func scanStreams(client goRedis.UniversalClient, topic string) ([]string, error) {
+	// Scan with the topic's prefix up to the first wildcard, the exact matching is done with the MQTT semantics.
+	prefix := topic
+	if index := strings.IndexAny(topic, StandardWildcard+SingleLevelWildcard); index >= 0 {
+		prefix = topic[:index]
+	}
+	pattern := convertToRedisTopicScheme(strings.TrimSuffix(prefix, StandardTopicSeparator)) + RedisWildcard
+
+	var streams []string
+	cursor := "0"
+	for {
+		reply, err := client.Do("SCAN", cursor, "MATCH", pattern, "COUNT", readCount, "TYPE", "stream").Result()
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan streams for topic '%s': %w", topic, err)
+		}
+
+		values, ok := reply.([]interface{})
+		if !ok || len(values) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+
+		cursor = fmt.Sprint(values[0])
+		keys, _ := values[1].([]interface{})
+		for _, key := range keys {
+			stream := fmt.Sprint(key)
+			if internal.TopicMatches(topic, convertFromRedisTopicScheme(stream)) {
+				streams = append(streams, stream)
+			}
+		}
+
+		if cursor == "0" {
+			return streams, nil
+		}
+	}
+}
+
+// createGroup creates the consumer group for the stream, creating the stream if needed.
+func (c *StreamsClient) createGroup(group string, stream string, startID string) error {
+	err := c.client.XGroupCreateMkStream(stream, group, startID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("unable to create consumer group '%s' for stream '%s': %w", group, stream, err)
+	}
+
+	return nil
+}
+
+// cleanup deletes the streams of the response topics, along with their consumer groups, and destroys the consumer
+// groups which were generated for this client only.
+func (c *StreamsClient) cleanup(subscription *streamSubscription) {
+	if subscription.response {
+		for stream := range subscription.streams {
+			_ = c.client.Del(stream).Err()
+		}
+		return
+	}
+
+	if !c.ephemeralGroup {
+		return
+	}
+
+	for stream := range subscription.streams {
+		_ = c.client.XGroupDestroy(stream, subscription.group).Err()
+	}
+}
+
+// groupName creates the consumer group name of the topic's subscription. The group is specific to the topic so that
+// overlapping subscriptions of the same client, e.g. "a/b" and "a/#", each receive the messages of the shared streams.
+func (c *StreamsClient) groupName(topic string) string {
+	return c.group + ":" + topic
+}
+
+func (c *StreamsClient) claimMinIdle() time.Duration {
+	return time.Duration(c.configuration.ClaimMinIdle) * time.Second
+}
+
+// decodeStreamEntry decodes the envelope of the entry, with the fallback codec when its encoding isn't detected.
+func decodeStreamEntry(entry goRedis.XMessage, fallback codec.Codec) (*types.MessageEnvelope, error) {
+	value, ok := entry.Values[envelopeField]
+	if !ok {
+		return nil, fmt.Errorf("missing '%s' field", envelopeField)
+	}
+
+	data, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected '%s' field type %T", envelopeField, value)
+	}
+
+	message := &types.MessageEnvelope{}
+	if err := codec.Decode([]byte(data), message, fallback); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal payload: %w", err)
+	}
+
+	return message, nil
+}
+
+// parseAutoClaimReply parses the XAUTOCLAIM reply which is the next start ID followed by the claimed entries.
+func parseAutoClaimReply(reply interface{}) (string, []goRedis.XMessage, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) < 2 {
+		return "", nil, fmt.Errorf("unexpected XAUTOCLAIM reply %v", reply)
+	}
+
+	next := fmt.Sprint(values[0])
+	rawEntries, ok := values[1].([]interface{})
+	if !ok {
+		return "", nil, errors.New("unexpected XAUTOCLAIM entries")
+	}
+
+	entries := make([]goRedis.XMessage, 0, len(rawEntries))
+	for _, rawEntry := range rawEntries {
+		entry, ok := rawEntry.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+
+		fields, _ := entry[1].([]interface{})
+		// Entries deleted from the stream have no fields
+		if fields == nil {
+			continue
+		}
+
+		message := goRedis.XMessage{ID: fmt.Sprint(entry[0]), Values: make(map[string]interface{}, len(fields)/2)}
+		for i := 0; i+1 < len(fields); i += 2 {
+			message.Values[fmt.Sprint(fields[i])] = fields[i+1]
+		}
+		entries = append(entries, message)
+	}
+
+	return next, entries, nil
+}
+
+func isWildcardTopic(topic string) bool {
+	return strings.ContainsAny(topic, StandardWildcard+SingleLevelWildcard)
+}
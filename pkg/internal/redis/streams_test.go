@@ -0,0 +1,273 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goRedis "github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamsTestTimeout = 5 * time.Second
+
+func startMiniRedis(t *testing.T) *miniredis.Miniredis {
+	return miniredis.RunT(t)
+}
+
+func newRawTestClient(t *testing.T, server *miniredis.Miniredis) *goRedis.Client {
+	client := goRedis.NewClient(&goRedis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func newStreamsTestClient(t *testing.T, server *miniredis.Miniredis, optional map[string]string) *StreamsClient {
+	port, err := strconv.Atoi(server.Port())
+	require.NoError(t, err)
+
+	client, err := NewStreamsClient(types.MessageBusConfig{
+		Broker:   types.HostInfo{Host: server.Host(), Port: port, Protocol: "redis"},
+		Type:     "redis-streams",
+		Optional: optional,
+	})
+	require.NoError(t, err)
+	client.refreshInterval = 50 * time.Millisecond
+
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func TestStreamsClientPublishSubscribe(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, map[string]string{"ClientId": "consumer"})
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	expected := types.NewMessageEnvelope([]byte("payload"), context.Background())
+	require.NoError(t, client.Publish(expected, "edgex/events"))
+
+	message := testutil.ReceiveMessage(t, messages, streamsTestTimeout)
+	assert.Equal(t, expected.CorrelationID, message.CorrelationID)
+	assert.Equal(t, expected.Payload, message.Payload)
+	assert.Equal(t, "edgex/events", message.ReceivedTopic)
+
+	// The message is acknowledged once delivered
+	raw := newRawTestClient(t, server)
+	assert.Eventually(t, func() bool {
+		pending, err := raw.XPending("edgex.events", "consumer:edgex/events").Result()
+		return err == nil && pending.Count == 0
+	}, streamsTestTimeout, 10*time.Millisecond)
+}
+
+func TestStreamsClientPublishEmptyTopic(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, nil)
+
+	err := client.Publish(types.MessageEnvelope{}, "")
+	require.Error(t, err)
+}
+
+func TestStreamsClientMaxLen(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, map[string]string{"MaxLen": "5"})
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	}
+
+	length, err := newRawTestClient(t, server).XLen("edgex.events").Result()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, length, int64(5))
+}
+
+func TestStreamsClientSubscribeDuplicateTopic(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, nil)
+
+	topics := []types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestStreamsClientWildcardSubscription(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, nil)
+
+	// Stream existing before subscribing
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "old"}, "edgex/events/device1"))
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events/#", Messages: messages}}, make(chan error)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "existing"}, "edgex/events/device1"))
+	message := testutil.ReceiveMessage(t, messages, streamsTestTimeout)
+	assert.Equal(t, "existing", message.CorrelationID)
+	assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+
+	// Stream created after subscribing is discovered and read from its beginning
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "new"}, "edgex/events/device2/reading"))
+	message = testutil.ReceiveMessage(t, messages, streamsTestTimeout)
+	assert.Equal(t, "new", message.CorrelationID)
+	assert.Equal(t, "edgex/events/device2/reading", message.ReceivedTopic)
+
+	// Non matching streams are ignored
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "other"}, "edgex/commands/device1"))
+	select {
+	case message = <-messages:
+		assert.Fail(t, "unexpected message", message.CorrelationID)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestStreamsClientResumesAfterRestart(t *testing.T) {
+	server := startMiniRedis(t)
+	optional := map[string]string{"ClientId": "service"}
+
+	client := newStreamsTestClient(t, server, optional)
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Disconnect())
+
+	publisher := newStreamsTestClient(t, server, nil)
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "while-down"}, "edgex/events"))
+
+	restarted := newStreamsTestClient(t, server, optional)
+	messages = make(chan types.MessageEnvelope)
+	require.NoError(t, restarted.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	message := testutil.ReceiveMessage(t, messages, streamsTestTimeout)
+	assert.Equal(t, "while-down", message.CorrelationID)
+}
+
+func TestStreamsClientReclaimsPendingEntries(t *testing.T) {
+	server := startMiniRedis(t)
+	optional := map[string]string{"QueueGroup": "group", "ClientId": "alive", "ClaimMinIdle": "1"}
+
+	client := newStreamsTestClient(t, server, optional)
+	group := client.groupName("edgex/events")
+	require.NoError(t, client.createGroup(group, "edgex.events", "$"))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "orphan"}, "edgex/events"))
+
+	// Another consumer of the group reads the entry and dies before acknowledging it
+	read, err := newRawTestClient(t, server).XReadGroup(&goRedis.XReadGroupArgs{
+		Group: group, Consumer: "dead", Streams: []string{"edgex.events", ">"}, Count: 1,
+	}).Result()
+	require.NoError(t, err)
+	require.Len(t, read[0].Messages, 1)
+
+	server.SetTime(time.Now().Add(time.Hour))
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	message := testutil.ReceiveMessage(t, messages, streamsTestTimeout)
+	assert.Equal(t, "orphan", message.CorrelationID)
+}
+
+func TestStreamsClientCompetingConsumers(t *testing.T) {
+	server := startMiniRedis(t)
+	messages := make(chan types.MessageEnvelope, 20)
+
+	for i := 0; i < 2; i++ {
+		client := newStreamsTestClient(t, server, map[string]string{"QueueGroup": "group", "ClientId": fmt.Sprintf("consumer%d", i)})
+		require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	}
+
+	publisher := newStreamsTestClient(t, server, nil)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: strconv.Itoa(i)}, "edgex/events"))
+	}
+
+	received := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		received[testutil.ReceiveMessage(t, messages, streamsTestTimeout).CorrelationID]++
+	}
+	assert.Len(t, received, 10)
+
+	select {
+	case message := <-messages:
+		assert.Fail(t, "message delivered more than once", message.CorrelationID)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestStreamsClientRequest(t *testing.T) {
+	server := startMiniRedis(t)
+	responder := newStreamsTestClient(t, server, nil)
+	requester := newStreamsTestClient(t, server, nil)
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", streamsTestTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+
+	// The stream of the response topic is deleted once the request completed
+	assert.Eventually(t, func() bool {
+		return !server.Exists("edgex.response." + request.RequestID)
+	}, streamsTestTimeout, 10*time.Millisecond)
+}
+
+func TestStreamsClientEphemeralGroupCleanup(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, nil)
+
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, client.Disconnect())
+
+	groups, err := newRawTestClient(t, server).Do("XINFO", "GROUPS", "edgex.events").Result()
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestStreamsClientUnsubscribe(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, nil)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Unsubscribe("edgex/events"))
+	close(messages)
+
+	// Publishing after the channel is closed must not panic the consuming go routine
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	time.Sleep(100 * time.Millisecond)
+
+	// The topic can be subscribed again
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+}
+
+func TestStreamsClientCluster(t *testing.T) {
+	server := startMiniRedis(t)
+	client := newStreamsTestClient(t, server, map[string]string{"ClusterAddrs": server.Addr()})
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events/#", Messages: messages}}, make(chan error)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "first"}, "edgex/events/device1"))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "second"}, "edgex/events/device2"))
+
+	received := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		received[testutil.ReceiveMessage(t, messages, streamsTestTimeout).CorrelationID] = true
+	}
+	assert.Equal(t, map[string]bool{"first": true, "second": true}, received)
+}
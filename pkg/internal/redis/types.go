@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"crypto/tls"
 	"messaging/pkg/types"
 )
@@ -8,7 +9,7 @@ import (
 // RedisClientCreator type alias for functions which create RedisClient implementation.
 //
 // This is mostly used for testing purposes so that we can easily inject mocks.
-type RedisClientCreator func(redisServerURL string, password string, tlsConfig *tls.Config) (RedisClient, error)
+type RedisClientCreator func(redisServerURL string, optionalConfiguration OptionalClientConfiguration, tlsConfig *tls.Config) (RedisClient, error)
 
 // RedisClient provides functionality needed to read and send messages to/from Redis' Redis Pub/Sub functionality.
 //
@@ -20,11 +21,12 @@ type RedisClient interface {
 	Subscribe(topic string)
 	// Unsubscribe closes the subscription in Redis and removes it.
 	Unsubscribe(topic string)
-	// Send sends a message to the specified topic, aka Publish.
-	Send(topic string, message types.MessageEnvelope) error
+	// Send sends a message to the specified topic, aka Publish, until the context is done.
+	Send(ctx context.Context, topic string, message types.MessageEnvelope) error
 	// Receive blocking operation which receives the next message for the specified subscribed topic
 	// This supports multi-level topic scheme with wild cards
-	Receive(topic string) (*types.MessageEnvelope, error)
+	// Once the context is done the subscription is closed and the context's error is returned.
+	Receive(ctx context.Context, topic string) (*types.MessageEnvelope, error)
 	// Close cleans up any entities which need to be deconstructed.
 	Close() error
 }
@@ -17,6 +17,8 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"messaging/pkg/types"
 	"strings"
@@ -25,6 +27,23 @@ import (
 	"github.com/google/uuid"
 )
 
+// responseSubscriptionKey marks the context of a subscription to response topics, see IsResponseSubscription.
+type responseSubscriptionKey struct{}
+
+// WithResponseSubscription returns the context marking the subscription made with it as a subscription to the response
+// topics of requests, see IsResponseSubscription.
+func WithResponseSubscription(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseSubscriptionKey{}, true)
+}
+
+// IsResponseSubscription reports whether the subscription with the context is to the response topics of requests,
+// which are only needed as long as the requests are waiting. The backends persisting state for each subscription,
+// e.g. a JetStream durable consumer, don't for these subscriptions rather than leaving that state behind.
+func IsResponseSubscription(ctx context.Context) bool {
+	response, _ := ctx.Value(responseSubscriptionKey{}).(bool)
+	return response
+}
+
 // DoRequest publishes a request containing a RequestID to the specified topic,
 // then subscribes to a response topic which contains the RequestID. Once the response is received, the
 // response topic is unsubscribed and the response data is returned. If no response is received within
@@ -37,9 +56,38 @@ func DoRequest(
 	requestTopic string,
 	responseTopicPrefix string,
 	requestTimeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	return DoRequestContext(
+		ctx,
+		func(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+			return SubscribeWithContext(ctx, subscribe, unsubscribe, topics, messageErrors)
+		},
+		unsubscribe,
+		func(ctx context.Context, message types.MessageEnvelope, topic string) error {
+			return RunWithContext(ctx, func() error { return publish(message, topic) })
+		},
+		requestMessage,
+		requestTopic,
+		responseTopicPrefix)
+}
+
+// DoRequestContext is DoRequest waiting for the response until the context is done, instead of a timeout. The error
+// returned when the context is done wraps the context's error, i.e. context.DeadlineExceeded or context.Canceled, and
+// the responders are then notified that the request is abandoned with PublishCancel.
+func DoRequestContext(
+	ctx context.Context,
+	subscribe func(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error,
+	unsubscribe func(topics ...string) error,
+	publish func(ctx context.Context, message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string) (*types.MessageEnvelope, error) {
 	if len(strings.TrimSpace(requestMessage.RequestID)) == 0 {
 		requestMessage.RequestID = uuid.NewString()
 	}
+	requestMessage = WithDeadline(ctx, requestMessage)
 
 	// Format of response topic is <prefix>/<request-id>
 	responseTopic := strings.Join([]string{responseTopicPrefix, requestMessage.RequestID}, "/")
@@ -52,9 +100,9 @@ func DoRequest(
 	}
 
 	// Must create the subscription first so that it is in place when the request is handled and response published back
-	err := subscribe([]types.TopicChannel{responseTopicChan}, errs)
+	err := subscribe(WithResponseSubscription(ctx), []types.TopicChannel{responseTopicChan}, errs)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create response subscription: %v", err)
+		return nil, fmt.Errorf("unable to create response subscription: %w", err)
 	}
 
 	defer func() {
@@ -63,17 +111,18 @@ func DoRequest(
 		close(messages)
 	}()
 
-	err = publish(requestMessage, requestTopic)
+	err = publish(ctx, requestMessage, requestTopic)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create publish request to %s: %v", requestTopic, err)
+		return nil, fmt.Errorf("unable to create publish request to %s: %w", requestTopic, err)
 	}
 
-	timer := time.NewTimer(requestTimeout)
-	defer timer.Stop()
-
 	select {
-	case <-timer.C:
-		return nil, fmt.Errorf("timed out waiting for response on %s topic", responseTopicChan.Topic)
+	case <-ctx.Done():
+		PublishCancel(publish, requestMessage, responseTopicPrefix)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out waiting for response on %s topic: %w", responseTopicChan.Topic, ctx.Err())
+		}
+		return nil, fmt.Errorf("request to %s cancelled while waiting for response: %w", requestTopic, ctx.Err())
 
 	case err = <-errs:
 		return nil, fmt.Errorf("encountered error waiting for response to %s: %v", requestTopic, err)
@@ -82,3 +131,97 @@ func DoRequest(
 		return &responseMessage, nil
 	}
 }
+
+// DoRequestAll publishes a request containing a RequestID to the specified topic and collects the responses of all
+// the responders published to the response topic which contains the RequestID. It completes once the options are
+// satisfied, returning the responses received. When the context's deadline is reached first, the responses received so
+// far are returned along with a *types.RequestTimeoutErr, and the responders still handling the request are notified
+// with PublishCancel.
+func DoRequestAll(
+	ctx context.Context,
+	subscribe func(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error,
+	unsubscribe func(topics ...string) error,
+	publish func(ctx context.Context, message types.MessageEnvelope, topic string) error,
+	requestMessage types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string,
+	options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	if len(strings.TrimSpace(requestMessage.RequestID)) == 0 {
+		requestMessage.RequestID = uuid.NewString()
+	}
+	requestMessage = WithDeadline(ctx, requestMessage)
+
+	// Format of response topic is <prefix>/<request-id>
+	responseTopic := strings.Join([]string{responseTopicPrefix, requestMessage.RequestID}, "/")
+
+	errs := make(chan error, 1)
+	messages := make(chan types.MessageEnvelope, max(options.MaxResponses, 1))
+	responseTopicChan := types.TopicChannel{
+		Topic:    responseTopic,
+		Messages: messages,
+	}
+
+	// Must create the subscription first so that it is in place when the request is handled and responses published back
+	err := subscribe(WithResponseSubscription(ctx), []types.TopicChannel{responseTopicChan}, errs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create response subscription: %w", err)
+	}
+
+	defer func() {
+		_ = unsubscribe(responseTopicChan.Topic)
+		close(errs)
+		close(messages)
+	}()
+
+	err = publish(ctx, requestMessage, requestTopic)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create publish request to %s: %w", requestTopic, err)
+	}
+
+	// A nil channel never receives, so the quiet period is disabled unless set
+	var quiet <-chan time.Time
+	var quietTimer *time.Timer
+	if options.QuietPeriod > 0 {
+		quietTimer = time.NewTimer(options.QuietPeriod)
+		defer quietTimer.Stop()
+		quiet = quietTimer.C
+	}
+
+	var responses []types.MessageEnvelope
+	for {
+		select {
+		case <-ctx.Done():
+			PublishCancel(publish, requestMessage, responseTopicPrefix)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return responses, &types.RequestTimeoutErr{ResponseTopic: responseTopic, Received: len(responses)}
+			}
+			return responses, fmt.Errorf("request to %s cancelled while waiting for responses: %w", requestTopic, ctx.Err())
+
+		case err = <-errs:
+			return responses, fmt.Errorf("encountered error waiting for responses to %s: %v", requestTopic, err)
+
+		case <-quiet:
+			return responses, nil
+
+		case responseMessage := <-messages:
+			if responseMessage.ReceivedTopic == "" {
+				responseMessage.ReceivedTopic = responseTopic
+			}
+			responses = append(responses, responseMessage)
+
+			if options.MaxResponses > 0 && len(responses) >= options.MaxResponses {
+				return responses, nil
+			}
+
+			if quietTimer != nil {
+				if !quietTimer.Stop() {
+					select {
+					case <-quietTimer.C:
+					default:
+					}
+				}
+				quietTimer.Reset(options.QuietPeriod)
+			}
+		}
+	}
+}
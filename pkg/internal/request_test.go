@@ -17,8 +17,11 @@
 package internal
 
 import (
+	"context"
 	"errors"
 	"messaging/pkg/types"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -151,3 +154,147 @@ func TestDoRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestDoRequestContext(t *testing.T) {
+	unsubscribed := make(chan string, 1)
+	unsubscribeFunc := func(topics ...string) error {
+		unsubscribed <- topics[0]
+		return nil
+	}
+
+	subscribeFunc := func(_ context.Context, _ []types.TopicChannel, _ chan error) error {
+		return nil
+	}
+
+	publishFunc := func(_ context.Context, _ types.MessageEnvelope, _ string) error {
+		return nil
+	}
+
+	// Simulates a publish blocked on a dead connection
+	publishBlockedFunc := func(ctx context.Context, _ types.MessageEnvelope, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		Name          string
+		Publish       func(ctx context.Context, message types.MessageEnvelope, topic string) error
+		Cancel        bool
+		ExpectedErr   error
+		ExpectedError string
+	}{
+		{"Deadline exceeded", publishFunc, false, context.DeadlineExceeded, "timed out waiting for response on edgex/response/my-service/"},
+		{"Cancelled", publishFunc, true, context.Canceled, "request to test-topic cancelled"},
+		{"Blocked publish cancelled", publishBlockedFunc, true, context.Canceled, "unable to create publish request"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			if test.Cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+
+			message := types.MessageEnvelope{RequestID: uuid.NewString()}
+			_, err := DoRequestContext(ctx, subscribeFunc, unsubscribeFunc, test.Publish, message, "test-topic", "edgex/response/my-service")
+			require.ErrorIs(t, err, test.ExpectedErr)
+			assert.Contains(t, err.Error(), test.ExpectedError)
+			assert.Equal(t, "edgex/response/my-service/"+message.RequestID, <-unsubscribed)
+		})
+	}
+}
+
+func TestDoRequestAll(t *testing.T) {
+	// Simulates responders each responding once on the response topic after their delay, until unsubscribed
+	responders := func(delays ...time.Duration) (
+		func(context.Context, []types.TopicChannel, chan error) error, func(...string) error) {
+		var mutex sync.Mutex
+		unsubscribed := make(chan struct{})
+
+		subscribe := func(_ context.Context, topics []types.TopicChannel, _ chan error) error {
+			for i, delay := range delays {
+				go func(i int, delay time.Duration) {
+					time.Sleep(delay)
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					select {
+					case <-unsubscribed:
+						return
+					default:
+					}
+
+					select {
+					case topics[0].Messages <- types.MessageEnvelope{CorrelationID: strconv.Itoa(i)}:
+					case <-unsubscribed:
+					}
+				}(i, delay)
+			}
+			return nil
+		}
+
+		unsubscribe := func(...string) error {
+			close(unsubscribed)
+			mutex.Lock()
+			defer mutex.Unlock()
+			return nil
+		}
+
+		return subscribe, unsubscribe
+	}
+
+	publishFunc := func(_ context.Context, _ types.MessageEnvelope, _ string) error {
+		return nil
+	}
+
+	tests := []struct {
+		Name              string
+		Delays            []time.Duration
+		Options           types.RequestAllOptions
+		ExpectedResponses int
+		ExpectTimeout     bool
+	}{
+		{"Max responses", []time.Duration{0, 0, 0}, types.RequestAllOptions{MaxResponses: 2}, 2, false},
+		{"Quiet period", []time.Duration{0, 10 * time.Millisecond, 500 * time.Millisecond}, types.RequestAllOptions{QuietPeriod: 50 * time.Millisecond}, 2, false},
+		{"Deadline with partial responses", []time.Duration{0, 0, 500 * time.Millisecond}, types.RequestAllOptions{}, 2, true},
+		{"Deadline without responses", nil, types.RequestAllOptions{MaxResponses: 1}, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			subscribe, unsubscribe := responders(test.Delays...)
+			message := types.MessageEnvelope{RequestID: uuid.NewString()}
+			responses, err := DoRequestAll(ctx, subscribe, unsubscribe, publishFunc, message, "test-topic", "edgex/response/my-service", test.Options)
+			require.Len(t, responses, test.ExpectedResponses)
+			for _, response := range responses {
+				assert.Equal(t, "edgex/response/my-service/"+message.RequestID, response.ReceivedTopic)
+			}
+
+			if !test.ExpectTimeout {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			var timeoutErr *types.RequestTimeoutErr
+			require.ErrorAs(t, err, &timeoutErr)
+			assert.Equal(t, test.ExpectedResponses, timeoutErr.Received)
+		})
+	}
+
+	t.Run("Subscription error", func(t *testing.T) {
+		subscribe := func(_ context.Context, _ []types.TopicChannel, messageErrors chan error) error {
+			messageErrors <- errors.New("subscription error")
+			return nil
+		}
+
+		_, err := DoRequestAll(context.Background(), subscribe, func(...string) error { return nil }, publishFunc,
+			types.MessageEnvelope{}, "test-topic", "edgex/response/my-service", types.RequestAllOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "subscription error")
+	})
+}
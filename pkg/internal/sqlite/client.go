@@ -0,0 +1,562 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	// Registers the pure Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// batchSize is the maximum number of messages claimed by a poll.
+	batchSize = 100
+	// busyTimeout is the number of milliseconds to wait for the lock held by another connection, or process.
+	busyTimeout = 10000
+)
+
+// schema creates the tables shared by all the clients using the database file. The AUTOINCREMENT guarantees the ids
+// are never reused once the messages are removed by the retention limits, which the cursors rely on.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS messages (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		topic    TEXT    NOT NULL,
+		envelope BLOB    NOT NULL,
+		created  INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS messages_topic ON messages (topic, id)`,
+	`CREATE INDEX IF NOT EXISTS messages_created ON messages (created)`,
+	`CREATE TABLE IF NOT EXISTS cursors (
+		consumer TEXT    NOT NULL,
+		filter   TEXT    NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (consumer, filter)
+	)`,
+}
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages through a
+// SQLite database file, without broker. The messages are persisted, so they survive restarts, and the processes
+// sharing the file exchange messages through it.
+type Client struct {
+	config       ClientConfig
+	codec        codec.Codec
+	pollInterval time.Duration
+
+	db      *sql.DB
+	dbMutex sync.RWMutex
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+
+	// consumers tracks the polling go routines of the subscriptions
+	consumers *sync.WaitGroup
+}
+
+// subscription tracks a subscription and its position in the messages.
+type subscription struct {
+	*internal.Delivery
+
+	filter string
+	// consumer is the name of the persisted cursor, empty when the cursor is only kept in memory
+	consumer string
+
+	// position is the id of the last message claimed when the cursor is only kept in memory, only accessed by the
+	// polling go routine
+	position int64
+
+	// wake is signaled when a message is published by this client, so it's delivered without waiting for the poll
+	wake chan struct{}
+	// stopped is closed once the polling go routine has exited
+	stopped chan struct{}
+}
+
+// storedMessage is a message read from the database, err is set when its envelope can't be decoded.
+type storedMessage struct {
+	id       int64
+	envelope types.MessageEnvelope
+	err      error
+}
+
+// queryer is implemented by both sql.DB and sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:                config,
+		codec:                 envelopeCodec,
+		pollInterval:          time.Duration(config.PollInterval) * time.Millisecond,
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+		consumers:             new(sync.WaitGroup),
+	}, nil
+}
+
+// Connect opens the database file, creating it and its tables when missing.
+func (c *Client) Connect() error {
+	c.dbMutex.Lock()
+	defer c.dbMutex.Unlock()
+
+	if c.db != nil {
+		return nil
+	}
+
+	// The write-ahead log lets readers proceed while another process writes, and the transactions take the write
+	// lock right away since claiming messages always ends with updating the cursor
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_txlock=immediate",
+		c.config.Path, busyTimeout)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return fmt.Errorf("unable to open the database '%s': %w", c.config.Path, err)
+	}
+
+	for _, statement := range schema {
+		if _, err = db.Exec(statement); err != nil {
+			_ = db.Close()
+			return fmt.Errorf("unable to create the tables of the database '%s': %w", c.config.Path, err)
+		}
+	}
+
+	c.db = db
+
+	return nil
+}
+
+// Publish stores the message for the subscriptions matching the topic, then enforces the retention limits.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which gives up waiting for the database, e.g. locked by another writer, once the context
+// is done.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	envelope, err := c.codec.Encode(message)
+	if err != nil {
+		return fmt.Errorf("unable to encode the message: %w", err)
+	}
+
+	c.dbMutex.RLock()
+	defer c.dbMutex.RUnlock()
+
+	if c.db == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to publish with a disconnected client")
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to publish to '%s': %w", topic, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	if _, err = tx.ExecContext(ctx, `INSERT INTO messages (topic, envelope, created) VALUES (?, ?, ?)`,
+		topic, envelope, now.UnixMilli()); err != nil {
+		return fmt.Errorf("unable to publish to '%s': %w", topic, err)
+	}
+
+	if c.config.MaxLen > 0 {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM messages WHERE topic = ? AND id <= `+
+			`(SELECT id FROM messages WHERE topic = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`,
+			topic, topic, c.config.MaxLen); err != nil {
+			return fmt.Errorf("unable to apply the %s retention: %w", internal.MaxLen, err)
+		}
+	}
+
+	if c.config.MaxAge > 0 {
+		expired := now.Add(-time.Duration(c.config.MaxAge) * time.Second).UnixMilli()
+		if _, err = tx.ExecContext(ctx, `DELETE FROM messages WHERE created < ?`, expired); err != nil {
+			return fmt.Errorf("unable to apply the %s retention: %w", internal.MaxAge, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("unable to publish to '%s': %w", topic, err)
+	}
+
+	c.wakeSubscriptions(topic)
+
+	return nil
+}
+
+// Subscribe creates background processes which poll the database for the messages matching the topics and send them
+// to the provided channels. The MQTT '+' and '#' wildcards are supported.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which gives up initializing the cursors once the context is done. The subscriptions
+// created by then are kept, use Unsubscribe to remove them.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(ctx, topics, messageErrors, c.config.consumer())
+}
+
+// subscribeResponse is SubscribeContext keeping the cursors in memory, since the response topic of a request is
+// never subscribed again and its persisted cursor would be left behind.
+func (c *Client) subscribeResponse(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	return c.subscribe(ctx, topics, messageErrors, "")
+}
+
+// subscribe creates the subscriptions with the cursors of the consumer, kept in memory when empty.
+func (c *Client) subscribe(ctx context.Context, topics []types.TopicChannel, messageErrors chan error, consumer string) error {
+	c.dbMutex.RLock()
+	defer c.dbMutex.RUnlock()
+
+	if c.db == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to subscribe with a disconnected client")
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		s := &subscription{
+			Delivery: internal.NewDelivery(topic.Messages, messageErrors),
+			filter:   topic.Topic,
+			consumer: consumer,
+			wake:     make(chan struct{}, 1),
+			stopped:  make(chan struct{}),
+		}
+
+		// The cursor must be in place before returning so that messages published right after are not missed,
+		// which is needed for the Request API
+		if err := c.initCursor(ctx, s); err != nil {
+			return fmt.Errorf("unable to subscribe to '%s': %w", topic.Topic, err)
+		}
+
+		c.existingSubscriptions[topic.Topic] = s
+		c.consumers.Add(1)
+		go c.poll(s)
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.subscribeResponse, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe stops polling for the specified topics. Once returned no more messages are sent to the channels of these
+// subscriptions and the claimed messages which weren't delivered are released. The persisted cursors are kept, so
+// subscribing again resumes where the subscription stopped.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		s.Stop()
+		<-s.stopped
+		delete(c.existingSubscriptions, topic)
+	}
+
+	return nil
+}
+
+// Disconnect stops all subscriptions and closes the database once the background processes have exited.
+func (c *Client) Disconnect() error {
+	c.subscriptionMutex.Lock()
+	for topic, s := range c.existingSubscriptions {
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	c.consumers.Wait()
+
+	c.dbMutex.Lock()
+	defer c.dbMutex.Unlock()
+
+	if c.db == nil {
+		return nil
+	}
+
+	err := c.db.Close()
+	c.db = nil
+
+	return err
+}
+
+// initCursor positions the subscription according to Deliver, unless its persisted cursor already exists.
+func (c *Client) initCursor(ctx context.Context, s *subscription) error {
+	var start int64
+	if c.config.Deliver == DeliverNew {
+		if err := c.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM messages`).Scan(&start); err != nil {
+			return err
+		}
+	}
+
+	if s.consumer == "" {
+		s.position = start
+		return nil
+	}
+
+	_, err := c.db.ExecContext(ctx, `INSERT OR IGNORE INTO cursors (consumer, filter, position) VALUES (?, ?, ?)`,
+		s.consumer, s.filter, start)
+	return err
+}
+
+// poll delivers the messages of the subscription until it is stopped.
+func (c *Client) poll(s *subscription) {
+	defer c.consumers.Done()
+	defer close(s.stopped)
+
+	var previousErr error
+	for !s.Stopped() {
+		messages, claimed, err := c.claim(s)
+		if err != nil {
+			previousErr = s.ReportError(err, previousErr)
+			s.wait(c.pollInterval)
+			continue
+		}
+		previousErr = nil
+
+		for i, message := range messages {
+			// The cursor has moved past the message, so it isn't blocking the ones after it
+			if message.err != nil {
+				s.SendError(message.err)
+				continue
+			}
+
+			if !s.Send(message.envelope) {
+				c.release(s, messages[i:], claimed)
+				return
+			}
+		}
+
+		if claimed == 0 {
+			s.wait(c.pollInterval)
+		}
+	}
+}
+
+// claim reads the next messages matching the subscription and moves its cursor past them. Returns the position the
+// cursor was moved to, 0 when there were no new messages at all. The persisted cursors are moved within a transaction
+// so that the consumers sharing a cursor never claim the same messages.
+func (c *Client) claim(s *subscription) (messages []storedMessage, claimed int64, err error) {
+	c.dbMutex.RLock()
+	defer c.dbMutex.RUnlock()
+
+	if c.db == nil {
+		return nil, 0, nil
+	}
+
+	consumer := s.consumer
+	if consumer == "" {
+		messages, last, err := c.scan(c.db, s.filter, s.position)
+		if err != nil || last == s.position {
+			return nil, 0, err
+		}
+
+		s.position = last
+
+		return messages, last, nil
+	}
+
+	// Checking for new messages first avoids taking the write lock when there is nothing to claim
+	var position, latest int64
+	if err = c.db.QueryRow(`SELECT position FROM cursors WHERE consumer = ? AND filter = ?`,
+		consumer, s.filter).Scan(&position); err != nil {
+		return nil, 0, fmt.Errorf("unable to read the cursor of '%s': %w", s.filter, err)
+	}
+
+	if isWildcardTopic(s.filter) {
+		err = c.db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM messages`).Scan(&latest)
+	} else {
+		err = c.db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM messages WHERE topic = ?`, s.filter).Scan(&latest)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read the messages of '%s': %w", s.filter, err)
+	}
+
+	if latest <= position {
+		return nil, 0, nil
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to claim the messages of '%s': %w", s.filter, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = tx.QueryRow(`SELECT position FROM cursors WHERE consumer = ? AND filter = ?`,
+		consumer, s.filter).Scan(&position); err != nil {
+		return nil, 0, fmt.Errorf("unable to read the cursor of '%s': %w", s.filter, err)
+	}
+
+	messages, last, err := c.scan(tx, s.filter, position)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if last > position {
+		if _, err = tx.Exec(`UPDATE cursors SET position = ? WHERE consumer = ? AND filter = ?`,
+			last, consumer, s.filter); err != nil {
+			return nil, 0, fmt.Errorf("unable to move the cursor of '%s': %w", s.filter, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("unable to claim the messages of '%s': %w", s.filter, err)
+	}
+
+	if last == position {
+		return nil, 0, nil
+	}
+
+	return messages, last, nil
+}
+
+// scan reads the messages matching the filter after the position and returns them along with the id of the last
+// message scanned, which the cursor moves to since the messages scanned which don't match the wildcards are skipped.
+// The messages which can't be decoded are returned with their error, so that the cursor also moves past them rather
+// than being stuck on them.
+func (c *Client) scan(q queryer, filter string, position int64) ([]storedMessage, int64, error) {
+	var rows *sql.Rows
+	var err error
+	if isWildcardTopic(filter) {
+		// The wildcards are matched here, with the same rules as the other implementations
+		rows, err = q.Query(`SELECT id, topic, envelope FROM messages WHERE id > ? ORDER BY id LIMIT ?`,
+			position, batchSize)
+	} else {
+		rows, err = q.Query(`SELECT id, topic, envelope FROM messages WHERE topic = ? AND id > ? ORDER BY id LIMIT ?`,
+			filter, position, batchSize)
+	}
+	if err != nil {
+		return nil, position, fmt.Errorf("unable to read the messages of '%s': %w", filter, err)
+	}
+	defer rows.Close()
+
+	var messages []storedMessage
+	last := position
+	for rows.Next() {
+		var id int64
+		var topic string
+		var envelope []byte
+		if err = rows.Scan(&id, &topic, &envelope); err != nil {
+			return nil, position, fmt.Errorf("unable to read the messages of '%s': %w", filter, err)
+		}
+		last = id
+
+		if !internal.TopicMatches(filter, topic) {
+			continue
+		}
+
+		message := storedMessage{id: id}
+		if err = codec.Decode(envelope, &message.envelope, c.codec); err != nil {
+			message.err = fmt.Errorf("unable to decode the message %d of '%s': %w", id, topic, err)
+		}
+		message.envelope.ReceivedTopic = topic
+
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, position, fmt.Errorf("unable to read the messages of '%s': %w", filter, err)
+	}
+
+	return messages, last, nil
+}
+
+// release moves the persisted cursor back before the claimed messages which haven't been delivered, unless another
+// consumer has already moved it further than the claimed position.
+func (c *Client) release(s *subscription, undelivered []storedMessage, claimed int64) {
+	if s.consumer == "" || len(undelivered) == 0 {
+		return
+	}
+
+	c.dbMutex.RLock()
+	defer c.dbMutex.RUnlock()
+
+	if c.db == nil {
+		return
+	}
+
+	_, _ = c.db.Exec(`UPDATE cursors SET position = ? WHERE consumer = ? AND filter = ? AND position = ?`,
+		undelivered[0].id-1, s.consumer, s.filter, claimed)
+}
+
+// wakeSubscriptions signals the subscriptions matching the topic that a message has been published.
+func (c *Client) wakeSubscriptions(topic string) {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, s := range c.existingSubscriptions {
+		if internal.TopicMatches(s.filter, topic) {
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// wait waits for the duration, a message published by this client or the subscription to be stopped.
+func (s *subscription) wait(duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-s.wake:
+	case <-s.Done():
+	}
+}
+
+func isWildcardTopic(topic string) bool {
+	return strings.ContainsAny(topic, "+#")
+}
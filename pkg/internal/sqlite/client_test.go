@@ -0,0 +1,311 @@
+package sqlite
+
+import (
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+func newTestClient(t *testing.T, path string, optional map[string]string) *Client {
+	client, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: path}, Optional: optional})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func expectNoMessage(t *testing.T, messages chan types.MessageEnvelope) {
+	select {
+	case message := <-messages:
+		require.Failf(t, "unexpected message received", "received %s on %s", message.CorrelationID, message.ReceivedTopic)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	client := newTestClient(t, filepath.Join(t.TempDir(), "bus.db"), nil)
+
+	exact := make(chan types.MessageEnvelope, 1)
+	wildcard := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{
+		{Topic: "edgex/events/device1", Messages: exact},
+		{Topic: "edgex/+/device1", Messages: wildcard},
+	}, make(chan error)))
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "other"}, "edgex/events/device2"))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "123", Payload: []byte("data")}, "edgex/events/device1"))
+
+	for _, messages := range []chan types.MessageEnvelope{exact, wildcard} {
+		message := testutil.ReceiveMessage(t, messages, testTimeout)
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, []byte("data"), message.Payload)
+		assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+	}
+}
+
+func TestClientSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	publisher := newTestClient(t, path, nil)
+	subscriber := newTestClient(t, path, map[string]string{"PollInterval": "10"})
+
+	// The write-ahead log lets the processes read while another one writes
+	var journalMode string
+	require.NoError(t, subscriber.db.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, make(chan error)))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: fmt.Sprint(i)}, "edgex/events"))
+	}
+
+	// The messages published by another client, e.g. in another process, are delivered in order
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, fmt.Sprint(i), testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	}
+}
+
+func TestClientDurableCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	publisher := newTestClient(t, path, nil)
+	options := map[string]string{"ClientId": "service", "PollInterval": "10"}
+
+	subscriber := newTestClient(t, path, options)
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "1"}, "edgex/events"))
+	assert.Equal(t, "1", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	require.NoError(t, subscriber.Disconnect())
+
+	// The messages published while the subscriber is down are delivered once it's back
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "2"}, "edgex/events"))
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "3"}, "edgex/events"))
+
+	restarted := newTestClient(t, path, options)
+	messages = make(chan types.MessageEnvelope, 10)
+	require.NoError(t, restarted.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	assert.Equal(t, "2", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	assert.Equal(t, "3", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	expectNoMessage(t, messages)
+}
+
+func TestClientDeliverAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	publisher := newTestClient(t, path, nil)
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "before"}, "edgex/events"))
+
+	latest := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, newTestClient(t, path, nil).Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: latest}}, make(chan error)))
+	all := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, newTestClient(t, path, map[string]string{"Deliver": "all"}).Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: all}}, make(chan error)))
+
+	assert.Equal(t, "before", testutil.ReceiveMessage(t, all, testTimeout).CorrelationID)
+	expectNoMessage(t, latest)
+}
+
+func TestClientQueueGroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	publisher := newTestClient(t, path, nil)
+
+	const count = 50
+	received := make(map[string]int)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(count)
+
+	for i := 0; i < 2; i++ {
+		consumer := newTestClient(t, path, map[string]string{"QueueGroup": "workers", "PollInterval": "10"})
+		messages := make(chan types.MessageEnvelope)
+		require.NoError(t, consumer.Subscribe([]types.TopicChannel{{Topic: "edgex/+", Messages: messages}}, make(chan error)))
+
+		go func() {
+			for message := range messages {
+				mutex.Lock()
+				received[message.CorrelationID]++
+				mutex.Unlock()
+				wg.Done()
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: fmt.Sprint(i)}, "edgex/events"))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for the messages")
+	}
+
+	// Each message is delivered to a single consumer of the group
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Len(t, received, count)
+	for id, times := range received {
+		assert.Equal(t, 1, times, "message %s delivered %d times", id, times)
+	}
+}
+
+func TestClientRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	publisher := newTestClient(t, path, map[string]string{"MaxLen": "2"})
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: fmt.Sprint(i)}, "edgex/events"))
+	}
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "other"}, "edgex/other"))
+
+	var count int
+	require.NoError(t, publisher.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE topic = 'edgex/events'`).Scan(&count))
+	assert.Equal(t, 2, count)
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, newTestClient(t, path, map[string]string{"Deliver": "all"}).Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	assert.Equal(t, "3", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+	assert.Equal(t, "4", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+
+	// Messages older than MaxAge are removed
+	_, err := publisher.db.Exec(`UPDATE messages SET created = created - 10000`)
+	require.NoError(t, err)
+	aging := newTestClient(t, path, map[string]string{"MaxAge": "5"})
+	require.NoError(t, aging.Publish(types.MessageEnvelope{CorrelationID: "new"}, "edgex/events"))
+	require.NoError(t, publisher.db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestClientRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	responder := newTestClient(t, path, map[string]string{"PollInterval": "10"})
+	requester := newTestClient(t, path, map[string]string{"ClientId": "requester", "PollInterval": "10"})
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+
+	// The cursor of the response topic, never subscribed again, isn't persisted
+	var count int
+	require.NoError(t, requester.db.QueryRow(`SELECT COUNT(*) FROM cursors WHERE filter LIKE 'edgex/response/%'`).Scan(&count))
+	assert.Zero(t, count)
+
+	// The cancel notices of the abandoned requests are delivered through the topic shared by the requests
+	notices := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/response/cancel", Messages: notices}}, make(chan error)))
+	internal.PublishCancel(requester.PublishContext, request, "edgex/response")
+	assert.Equal(t, request.RequestID, testutil.ReceiveMessage(t, notices, testTimeout).RequestID)
+}
+
+func TestClientSkipsUndecodableMessages(t *testing.T) {
+	client := newTestClient(t, filepath.Join(t.TempDir(), "bus.db"), map[string]string{"ClientId": "service", "PollInterval": "10"})
+
+	messages := make(chan types.MessageEnvelope, 1)
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, errs))
+
+	_, err := client.db.Exec(`INSERT INTO messages (topic, envelope, created) VALUES (?, ?, ?)`,
+		"edgex/events", []byte("not an envelope"), time.Now().UnixMilli())
+	require.NoError(t, err)
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "valid"}, "edgex/events"))
+
+	// The message which can't be decoded is reported and doesn't block the ones after it
+	select {
+	case err = <-errs:
+		assert.ErrorContains(t, err, "unable to decode the message")
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for error")
+	}
+	assert.Equal(t, "valid", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientReleasesUndeliveredMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	client := newTestClient(t, path, map[string]string{"ClientId": "service", "PollInterval": "10", "Deliver": "all"})
+
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "undelivered"}, "edgex/events"))
+	// The last message scanned doesn't match the wildcard, so the cursor is moved past the claimed message
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "other"))
+
+	// Nobody receives, so the claimed message is never delivered
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+
+	position := func() int64 {
+		var position int64
+		require.NoError(t, client.db.QueryRow(`SELECT position FROM cursors WHERE consumer = ? AND filter = ?`,
+			"service", "edgex/#").Scan(&position))
+		return position
+	}
+	require.Eventually(t, func() bool { return position() == 2 }, testTimeout, 10*time.Millisecond)
+	require.NoError(t, client.Unsubscribe("edgex/#"))
+	assert.Equal(t, int64(0), position())
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, make(chan error)))
+	assert.Equal(t, "undelivered", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	client := newTestClient(t, filepath.Join(t.TempDir(), "bus.db"), nil)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	require.NoError(t, client.Unsubscribe("test"))
+	close(messages)
+
+	// Publishing after the channel is closed must not panic
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+	time.Sleep(2 * DefaultPollInterval * time.Millisecond)
+
+	// The topic can be subscribed again
+	messages = make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "again"}, "test"))
+	assert.Equal(t, "again", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientInvalidOperations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+
+	disconnected, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: path}})
+	require.NoError(t, err)
+	require.Error(t, disconnected.Publish(types.MessageEnvelope{}, "test"))
+	require.Error(t, disconnected.Subscribe([]types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, disconnected.Disconnect())
+
+	client := newTestClient(t, path, nil)
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "test/#"))
+	require.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "test/#/invalid", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+
+	topics := []types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
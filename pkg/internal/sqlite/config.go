@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"strings"
+)
+
+const (
+	// ProtocolSQLite is the only protocol supported, the Broker's Host is the path of the database file.
+	ProtocolSQLite = "sqlite"
+
+	// DeliverNew starts new cursors at the messages published after subscribing.
+	DeliverNew = "new"
+	// DeliverAll starts new cursors at the oldest messages retained.
+	DeliverAll = "all"
+
+	// DefaultPollInterval is the number of milliseconds between the polls of the database when not configured.
+	DefaultPollInterval = 100
+)
+
+// ClientConfig contains all the configurations for the SQLite client.
+type ClientConfig struct {
+	// Path is the path of the database file, which is created when missing
+	Path string
+	ClientOptions
+}
+
+// ClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type ClientOptions struct {
+	// Cursors, the position of the subscriptions is persisted under the QueueGroup, or the ClientId without group, so
+	// that they resume after a restart. The consumers sharing a QueueGroup compete for the messages, each one being
+	// delivered to a single consumer. Without either the position is only kept in memory.
+	ClientId   string
+	QueueGroup string
+	Deliver    string // Where new cursors start, "new" or "all"
+
+	// Retention limits, enforced when publishing, 0 disables the limit
+	MaxLen int // Messages kept per topic
+	MaxAge int // Seconds a message is kept
+
+	PollInterval int // Milliseconds between the polls catching the messages published by other processes
+
+	Format string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+}
+
+// NewClientConfiguration creates a ClientConfig based on the provided MessageBusConfig. The Broker's Host is the path
+// of the database file.
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	options := ClientOptions{
+		Deliver:      DeliverNew,
+		PollInterval: DefaultPollInterval,
+	}
+	if err := internal.Load(messageBusConfig.Optional, &options); err != nil {
+		return ClientConfig{}, err
+	}
+
+	if options.Deliver != DeliverNew && options.Deliver != DeliverAll {
+		return ClientConfig{}, fmt.Errorf("invalid %s '%s', must be '%s' or '%s'", internal.Deliver, options.Deliver,
+			DeliverNew, DeliverAll)
+	}
+
+	if options.MaxLen < 0 || options.MaxAge < 0 {
+		return ClientConfig{}, fmt.Errorf("%s and %s must not be negative", internal.MaxLen, internal.MaxAge)
+	}
+
+	if options.PollInterval <= 0 {
+		return ClientConfig{}, fmt.Errorf("%s must be positive", internal.PollInterval)
+	}
+
+	if _, err := codec.Lookup(options.Format); err != nil {
+		return ClientConfig{}, err
+	}
+
+	broker := messageBusConfig.Broker
+	if broker.Host == "" {
+		return ClientConfig{}, internal.NewBrokerURLErr("Host, the path of the database file, is required")
+	}
+
+	if broker.Protocol != "" && !strings.EqualFold(broker.Protocol, ProtocolSQLite) {
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("unsupported protocol '%s', must be '%s'",
+			broker.Protocol, ProtocolSQLite))
+	}
+
+	return ClientConfig{Path: broker.Host, ClientOptions: options}, nil
+}
+
+// consumer returns the name the cursors are persisted under, empty when they are only kept in memory.
+func (options ClientOptions) consumer() string {
+	if options.QueueGroup != "" {
+		return options.QueueGroup
+	}
+
+	return options.ClientId
+}
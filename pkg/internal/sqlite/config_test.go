@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientConfiguration(t *testing.T) {
+	defaultOptions := ClientOptions{Deliver: DeliverNew, PollInterval: DefaultPollInterval}
+
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		want    ClientConfig
+		wantErr bool
+	}{
+		{
+			name:   "Defaults",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "/var/lib/edge/bus.db"}},
+			want:   ClientConfig{Path: "/var/lib/edge/bus.db", ClientOptions: defaultOptions},
+		},
+		{
+			name: "All options",
+			config: types.MessageBusConfig{
+				Broker: types.HostInfo{Host: "bus.db", Protocol: "SQLite"},
+				Optional: map[string]string{
+					"ClientId":     "service",
+					"QueueGroup":   "workers",
+					"Deliver":      "all",
+					"MaxLen":       "1000",
+					"MaxAge":       "3600",
+					"PollInterval": "50",
+				},
+			},
+			want: ClientConfig{
+				Path: "bus.db",
+				ClientOptions: ClientOptions{
+					ClientId:     "service",
+					QueueGroup:   "workers",
+					Deliver:      DeliverAll,
+					MaxLen:       1000,
+					MaxAge:       3600,
+					PollInterval: 50,
+				},
+			},
+		},
+		{
+			name:    "Missing path",
+			config:  types.MessageBusConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported protocol",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Host: "bus.db", Protocol: "tcp"}},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Deliver",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "bus.db"},
+				Optional: map[string]string{"Deliver": "last"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative MaxLen",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "bus.db"},
+				Optional: map[string]string{"MaxLen": "-1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Zero PollInterval",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "bus.db"},
+				Optional: map[string]string{"PollInterval": "0"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientConfiguration(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientOptionsConsumer(t *testing.T) {
+	assert.Equal(t, "", ClientOptions{}.consumer())
+	assert.Equal(t, "service", ClientOptions{ClientId: "service"}.consumer())
+	assert.Equal(t, "workers", ClientOptions{ClientId: "service", QueueGroup: "workers"}.consumer())
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"messaging/pkg/types"
+	"sync"
+)
+
+// DefaultMaxPending is the number of messages queued for a subscriber when MaxPending isn't set.
+const DefaultMaxPending = 1000
+
+// Subscription delivers the queued messages, in enqueue order, to the subscriber's channel from its own go routine so
+// that the producer of the messages never blocks on a slow subscriber. The queue is bounded by its capacity, unless
+// 0, so that a slow subscriber doesn't grow the memory without limit, the messages enqueued while it is full being
+// dropped.
+type Subscription struct {
+	filter   string
+	messages chan<- types.MessageEnvelope
+	capacity int
+
+	queue      []types.MessageEnvelope
+	queueMutex sync.Mutex
+	signal     chan struct{}
+	done       chan struct{}
+	stopped    chan struct{}
+}
+
+// NewSubscription creates a Subscription with an unbounded queue for the topic filter and starts delivering to the
+// messages channel.
+func NewSubscription(filter string, messages chan<- types.MessageEnvelope) *Subscription {
+	return NewBoundedSubscription(filter, messages, 0)
+}
+
+// NewBoundedSubscription creates a Subscription queuing up to capacity messages, unbounded when 0, for the topic
+// filter and starts delivering to the messages channel.
+func NewBoundedSubscription(filter string, messages chan<- types.MessageEnvelope, capacity int) *Subscription {
+	s := &Subscription{
+		filter:   filter,
+		messages: messages,
+		capacity: capacity,
+		signal:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	go s.deliver()
+
+	return s
+}
+
+// Filter returns the topic filter of the subscription.
+func (s *Subscription) Filter() string {
+	return s.filter
+}
+
+// Matches reports whether the topic matches the subscription's topic filter.
+func (s *Subscription) Matches(topic string) bool {
+	return TopicMatches(s.filter, topic)
+}
+
+// Enqueue queues the message for delivery. Returns false when the queue is full, the message is then dropped.
+func (s *Subscription) Enqueue(message types.MessageEnvelope) bool {
+	s.queueMutex.Lock()
+	if s.capacity > 0 && len(s.queue) >= s.capacity {
+		s.queueMutex.Unlock()
+		return false
+	}
+	s.queue = append(s.queue, message)
+	s.queueMutex.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// Stop discards the pending messages and waits for the delivery go routine to exit, after which the subscriber's
+// channel is no longer used and can safely be closed.
+func (s *Subscription) Stop() {
+	close(s.done)
+	<-s.stopped
+}
+
+func (s *Subscription) next() (types.MessageEnvelope, bool) {
+	for {
+		s.queueMutex.Lock()
+		if len(s.queue) > 0 {
+			message := s.queue[0]
+			s.queue[0] = types.MessageEnvelope{}
+			s.queue = s.queue[1:]
+			s.queueMutex.Unlock()
+			return message, true
+		}
+		s.queueMutex.Unlock()
+
+		select {
+		case <-s.signal:
+		case <-s.done:
+			return types.MessageEnvelope{}, false
+		}
+	}
+}
+
+func (s *Subscription) deliver() {
+	defer close(s.stopped)
+
+	for {
+		message, ok := s.next()
+		if !ok {
+			return
+		}
+
+		select {
+		case s.messages <- message:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// CopyEnvelope creates a deep copy of the message received on the topic, so that the subscribers receiving the same
+// message can't observe each other's modifications.
+func CopyEnvelope(message types.MessageEnvelope, topic string) types.MessageEnvelope {
+	message.ReceivedTopic = topic
+
+	if message.Payload != nil {
+		message.Payload = append([]byte(nil), message.Payload...)
+	}
+
+	if message.QueryParams != nil {
+		queryParams := make(map[string]string, len(message.QueryParams))
+		for key, value := range message.QueryParams {
+			queryParams[key] = value
+		}
+		message.QueryParams = queryParams
+	}
+
+	if message.Headers != nil {
+		headers := make(map[string]string, len(message.Headers))
+		for key, value := range message.Headers {
+			headers[key] = value
+		}
+		message.Headers = headers
+	}
+
+	return message
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"messaging/pkg/types"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionDeliversInOrder(t *testing.T) {
+	messages := make(chan types.MessageEnvelope)
+	s := NewSubscription("test/#", messages)
+	defer s.Stop()
+
+	assert.Equal(t, "test/#", s.Filter())
+	assert.True(t, s.Matches("test/topic"))
+	assert.False(t, s.Matches("other"))
+
+	// Enqueueing never blocks even though nobody is receiving yet
+	for i := 0; i < 100; i++ {
+		s.Enqueue(types.MessageEnvelope{CorrelationID: strconv.Itoa(i)})
+	}
+
+	for i := 0; i < 100; i++ {
+		select {
+		case message := <-messages:
+			require.Equal(t, strconv.Itoa(i), message.CorrelationID)
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for message")
+		}
+	}
+}
+
+func TestSubscriptionStop(t *testing.T) {
+	messages := make(chan types.MessageEnvelope)
+	s := NewSubscription("test", messages)
+
+	s.Enqueue(types.MessageEnvelope{})
+	s.Stop()
+
+	// The channel is no longer used once stopped, so closing it is safe
+	close(messages)
+	s.Enqueue(types.MessageEnvelope{})
+}
+
+func TestSubscriptionDeliversExpired(t *testing.T) {
+	messages := make(chan types.MessageEnvelope)
+	s := NewSubscription("test", messages)
+	defer s.Stop()
+
+	// The expired requests are dropped by the Server, like with any other backend, not by the subscription
+	s.Enqueue(types.MessageEnvelope{CorrelationID: "expired", Deadline: time.Now().Add(-time.Second).UnixMilli()})
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "expired", message.CorrelationID)
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for message")
+	}
+}
+
+func TestBoundedSubscription(t *testing.T) {
+	messages := make(chan types.MessageEnvelope)
+	s := NewBoundedSubscription("test", messages, 2)
+	defer s.Stop()
+
+	// The message being delivered is no longer queued, so the messages after it are queued up to the capacity
+	require.True(t, s.Enqueue(types.MessageEnvelope{CorrelationID: "0"}))
+	require.Eventually(t, func() bool {
+		s.queueMutex.Lock()
+		defer s.queueMutex.Unlock()
+		return len(s.queue) == 0
+	}, time.Second, time.Millisecond)
+	assert.True(t, s.Enqueue(types.MessageEnvelope{CorrelationID: "1"}))
+	assert.True(t, s.Enqueue(types.MessageEnvelope{CorrelationID: "2"}))
+	assert.False(t, s.Enqueue(types.MessageEnvelope{CorrelationID: "dropped"}))
+
+	for _, expected := range []string{"0", "1", "2"} {
+		select {
+		case message := <-messages:
+			require.Equal(t, expected, message.CorrelationID)
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for message")
+		}
+	}
+
+	// Room is made once delivered
+	assert.True(t, s.Enqueue(types.MessageEnvelope{}))
+}
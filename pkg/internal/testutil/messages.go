@@ -0,0 +1,37 @@
+// Package testutil holds the helpers shared by the tests of the MessageClient implementations.
+package testutil
+
+import (
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ReceiveMessage returns the next message of the channel, failing the test when none is received within the timeout.
+func ReceiveMessage(t *testing.T, messages <-chan types.MessageEnvelope, timeout time.Duration) types.MessageEnvelope {
+	t.Helper()
+
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(timeout):
+		require.Fail(t, "timed out waiting for message")
+	}
+
+	return types.MessageEnvelope{}
+}
+
+// ReceiveMessages returns the messages of the channel received until none is received within the quiet period.
+func ReceiveMessages(messages <-chan types.MessageEnvelope, quiet time.Duration) []types.MessageEnvelope {
+	var received []types.MessageEnvelope
+	for {
+		select {
+		case message := <-messages:
+			received = append(received, message)
+		case <-time.After(quiet):
+			return received
+		}
+	}
+}
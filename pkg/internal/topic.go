@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"strings"
+)
+
+const (
+	// TopicLevelSeparator separates the levels of the standard MQTT style topic scheme.
+	TopicLevelSeparator = "/"
+	// MultiLevelWildcard matches any number of levels, including the parent level. It must be the last level.
+	MultiLevelWildcard = "#"
+	// SingleLevelWildcard matches exactly one level.
+	SingleLevelWildcard = "+"
+	// systemTopicPrefix marks topics which are not matched by wildcards in the first level.
+	systemTopicPrefix = "$"
+)
+
+// ValidateTopicFilter verifies the topic filter, which may contain wildcards, follows the MQTT topic rules.
+func ValidateTopicFilter(filter string) error {
+	if filter == "" {
+		return NewInvalidTopicErr(filter, "topic filter must not be empty")
+	}
+
+	levels := strings.Split(filter, TopicLevelSeparator)
+	for i, level := range levels {
+		if strings.Contains(level, MultiLevelWildcard) && (level != MultiLevelWildcard || i != len(levels)-1) {
+			return NewInvalidTopicErr(filter, "multi-level wildcard must occupy the entire last level")
+		}
+
+		if strings.Contains(level, SingleLevelWildcard) && level != SingleLevelWildcard {
+			return NewInvalidTopicErr(filter, "single-level wildcard must occupy an entire level")
+		}
+	}
+
+	return nil
+}
+
+// ValidatePublishTopic verifies the topic can be published to, i.e. it is not empty and has no wildcards.
+func ValidatePublishTopic(topic string) error {
+	if topic == "" {
+		return NewInvalidTopicErr(topic, "Unable to publish to the invalid topic")
+	}
+
+	if strings.ContainsAny(topic, MultiLevelWildcard+SingleLevelWildcard) {
+		return NewInvalidTopicErr(topic, "Unable to publish to a topic containing wildcards")
+	}
+
+	return nil
+}
+
+// TopicMatches reports whether the topic matches the topic filter using the MQTT semantics. The single-level wildcard
+// "+" matches exactly one level and the multi-level wildcard "#" matches the parent level and any number of child
+// levels. Topics starting with "$" are not matched by a wildcard in the first level.
+func TopicMatches(filter string, topic string) bool {
+	if strings.HasPrefix(topic, systemTopicPrefix) && !strings.HasPrefix(filter, systemTopicPrefix) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, TopicLevelSeparator)
+	topicLevels := strings.Split(topic, TopicLevelSeparator)
+
+	for i, filterLevel := range filterLevels {
+		if filterLevel == MultiLevelWildcard {
+			return true
+		}
+
+		if i >= len(topicLevels) {
+			return false
+		}
+
+		if filterLevel != SingleLevelWildcard && filterLevel != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
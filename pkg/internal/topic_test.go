@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter   string
+		topic    string
+		expected bool
+	}{
+		{"a/b/c", "a/b/c", true},
+		{"a/b/c", "a/b", false},
+		{"a/b", "a/b/c", false},
+		{"a/+/c", "a/b/c", true},
+		{"a/+/c", "a/b/d", false},
+		{"a/+/c", "a/b/x/c", false},
+		{"a/+", "a/", true},
+		{"+/+", "a/b", true},
+		{"+", "a/b", false},
+		{"a/#", "a", true},
+		{"a/#", "a/b", true},
+		{"a/#", "a/b/c/d", true},
+		{"a/#", "b/c", false},
+		{"a/+/#", "a/b", true},
+		{"#", "a/b/c", true},
+		{"#", "$SYS/info", false},
+		{"+/info", "$SYS/info", false},
+		{"$SYS/#", "$SYS/info", true},
+		{"/a", "/a", true},
+		{"+/a", "/a", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter+" "+tt.topic, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TopicMatches(tt.filter, tt.topic))
+		})
+	}
+}
+
+func TestValidateTopicFilter(t *testing.T) {
+	valid := []string{"a", "a/b", "a/+/c", "+", "#", "a/#", "+/+/#", "/"}
+	for _, filter := range valid {
+		assert.NoError(t, ValidateTopicFilter(filter), filter)
+	}
+
+	invalid := []string{"", "a/#/c", "a#", "a/b#", "a+/b", "a/+b"}
+	for _, filter := range invalid {
+		assert.Error(t, ValidateTopicFilter(filter), filter)
+	}
+}
+
+func TestValidatePublishTopic(t *testing.T) {
+	assert.NoError(t, ValidatePublishTopic("a/b/c"))
+	assert.Error(t, ValidatePublishTopic(""))
+	assert.Error(t, ValidatePublishTopic("a/+"))
+	assert.Error(t, ValidatePublishTopic("a/#"))
+}
@@ -0,0 +1,449 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TopicHeader is the header of the POST requests carrying the topic of the message.
+	TopicHeader = "X-Message-Topic"
+	// SignatureHeader is the header of the POST requests carrying the HMAC-SHA256 signature of the topic, timestamp
+	// and body.
+	SignatureHeader = "X-Signature-256"
+	// SignaturePrefix prefixes the hex encoded signature.
+	SignaturePrefix = "sha256="
+	// TimestampHeader is the header of the signed POST requests carrying the Unix time in seconds they were signed at.
+	TimestampHeader = "X-Signature-Timestamp"
+	// SignatureTolerance is how far the timestamp of a signed POST may be from the time of the ingest endpoint, which
+	// bounds the time a captured request can be replayed.
+	SignatureTolerance = 5 * time.Minute
+
+	// MaxBodySize is the maximum size of the bodies accepted by the ingest endpoint.
+	MaxBodySize = 16 * 1024 * 1024
+
+	// maxRetryBackoff caps the wait between the retries.
+	maxRetryBackoff = 30 * time.Second
+	// shutdownTimeout is how long the ingest endpoint waits for the in progress requests when disconnecting.
+	shutdownTimeout = 5 * time.Second
+	// retryAfter is the Retry-After, in seconds, of the POSTs answered 503 as a subscription queue is full.
+	retryAfter = "1"
+)
+
+// Client MessageClient implementation which POSTs the published messages to the webhooks configured for the topic
+// and receives messages through an HTTP ingest endpoint. The client is an http.Handler serving the ingest endpoint,
+// so it can also be mounted on an existing HTTP server.
+type Client struct {
+	config     ClientConfig
+	codec      codec.Codec
+	httpClient *http.Client
+
+	server *http.Server
+	// ctx is cancelled on disconnect to abort the POSTs in progress, nil while disconnected
+	ctx         context.Context
+	cancel      context.CancelFunc
+	clientMutex sync.RWMutex
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*internal.Subscription
+	subscriptionMutex     *sync.RWMutex
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	return NewClientWithHTTPClient(messageBusConfig, &http.Client{})
+}
+
+// NewClientWithHTTPClient creates a new Client based on the provided configuration which POSTs with the provided
+// http.Client, e.g. one trusting the certificate of an httptest TLS server. The ConnectTimeout replaces the
+// http.Client's Timeout.
+func NewClientWithHTTPClient(messageBusConfig types.MessageBusConfig, httpClient *http.Client) (*Client, error) {
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	client := *httpClient
+	client.Timeout = time.Duration(config.ConnectTimeout) * time.Second
+
+	return &Client{
+		config:                config,
+		codec:                 envelopeCodec,
+		httpClient:            &client,
+		existingSubscriptions: make(map[string]*internal.Subscription),
+		subscriptionMutex:     new(sync.RWMutex),
+	}, nil
+}
+
+// Connect starts the ingest endpoint when a listen address is configured.
+func (c *Client) Connect() error {
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	if c.ctx != nil {
+		return nil
+	}
+
+	if c.config.ListenAddress != "" {
+		listener, err := net.Listen("tcp", c.config.ListenAddress)
+		if err != nil {
+			return fmt.Errorf("unable to listen on '%s': %w", c.config.ListenAddress, err)
+		}
+
+		c.server = &http.Server{Handler: c, ReadHeaderTimeout: time.Duration(c.config.ConnectTimeout) * time.Second}
+		go func(server *http.Server) {
+			_ = server.Serve(listener)
+		}(c.server)
+	}
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	return nil
+}
+
+// Publish POSTs the provided message to the webhooks whose topic filter matches the topic, retrying with an
+// exponential backoff. Returns an error if any of the webhooks could not be delivered.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which aborts the POSTs and their retries when the context is done.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	clientCtx := c.context()
+	if clientCtx == nil {
+		return internal.NewMissingConfigurationErr("Connection", "Unable to publish with a disconnected client")
+	}
+
+	// The POSTs are also aborted when the client is disconnected
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(clientCtx, cancel)
+	defer stop()
+
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	body, err := c.codec.Encode(message)
+	if err != nil {
+		return err
+	}
+
+	var webhooks []Webhook
+	for _, webhook := range c.config.Webhooks {
+		if internal.TopicMatches(webhook.Filter, topic) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	errs := make([]error, len(webhooks))
+	wg := new(sync.WaitGroup)
+	for i, webhook := range webhooks {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			errs[i] = c.post(ctx, url, topic, body)
+		}(i, webhook.URL)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Subscribe creates subscriptions for the specified topics, the messages POSTed to the ingest endpoint are sent to
+// the channels of the subscriptions whose topic matches.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which fails with the context's error when the context is already done, subscribing
+// never blocks.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, _ chan error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+	}
+
+	for _, topic := range topics {
+		c.existingSubscriptions[topic.Topic] = internal.NewBoundedSubscription(topic.Topic, topic.Messages, c.config.MaxPending)
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the response on the response topic which contains the RequestID. The
+// responder's webhooks must route the response topic to this client's ingest endpoint.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe removes the subscriptions for the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+	}
+
+	return nil
+}
+
+// Disconnect aborts the POSTs in progress, stops the ingest endpoint and removes all the subscriptions.
+func (c *Client) Disconnect() error {
+	c.clientMutex.Lock()
+	var err error
+	if c.ctx != nil {
+		c.cancel()
+		c.ctx = nil
+	}
+	if c.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err = c.server.Shutdown(ctx)
+		cancel()
+		c.server = nil
+	}
+	c.clientMutex.Unlock()
+
+	c.subscriptionMutex.Lock()
+	for topic, s := range c.existingSubscriptions {
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+	}
+	c.subscriptionMutex.Unlock()
+
+	return err
+}
+
+// ServeHTTP implements the ingest endpoint. The POSTed body is the encoded MessageEnvelope and the topic is taken from
+// the TopicHeader, or else from the request's path, e.g. a POST to "/edgex/events/device1". With a Secret the topic
+// is covered by the signature, along with the timestamp which must be within SignatureTolerance.
+func (c *Client) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, MaxBodySize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(writer, fmt.Sprintf("unable to read body: %v", err), status)
+		return
+	}
+
+	topic := request.Header.Get(TopicHeader)
+	if topic == "" {
+		topic = strings.Trim(request.URL.Path, "/")
+	}
+	if err = internal.ValidatePublishTopic(topic); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if c.config.Secret != "" {
+		timestamp := request.Header.Get(TimestampHeader)
+		if !VerifySignature(c.config.Secret, topic, timestamp, body, request.Header.Get(SignatureHeader)) {
+			http.Error(writer, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		if !freshTimestamp(timestamp, time.Now()) {
+			http.Error(writer, "stale signature timestamp", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	message := types.MessageEnvelope{}
+	if err = codec.Decode(body, &message, c.codec); err != nil {
+		http.Error(writer, fmt.Sprintf("unable to unmarshal message: %v", err), http.StatusBadRequest)
+		return
+	}
+	message.ReceivedTopic = topic
+
+	matched, dropped := c.dispatch(message)
+	if !matched {
+		http.Error(writer, fmt.Sprintf("no subscription for '%s' topic", topic), http.StatusNotFound)
+		return
+	}
+
+	// The sender retries, the subscriptions which did queue the message then receive it again
+	if dropped {
+		writer.Header().Set("Retry-After", retryAfter)
+		http.Error(writer, fmt.Sprintf("subscription queue full for '%s' topic", topic), http.StatusServiceUnavailable)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// dispatch queues a copy of the message for the subscriptions whose topic matches, returns whether there are any and
+// whether the queue of any of them is full, the message being dropped for those.
+func (c *Client) dispatch(message types.MessageEnvelope) (matched bool, dropped bool) {
+	c.subscriptionMutex.RLock()
+	defer c.subscriptionMutex.RUnlock()
+
+	for _, s := range c.existingSubscriptions {
+		if s.Matches(message.ReceivedTopic) {
+			matched = true
+			if !s.Enqueue(internal.CopyEnvelope(message, message.ReceivedTopic)) {
+				dropped = true
+			}
+		}
+	}
+
+	return matched, dropped
+}
+
+// post POSTs the body to the URL, retrying the network errors, the 429 and the 5xx statuses with an exponential
+// backoff until MaxRetries is reached or the client is disconnected.
+func (c *Client) post(ctx context.Context, url string, topic string, body []byte) error {
+	backoff := time.Duration(c.config.RetryBackoff) * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.postOnce(ctx, url, topic, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= c.config.MaxRetries {
+			return fmt.Errorf("unable to POST '%s' topic to '%s' after %d attempts: %w", topic, url, attempt+1, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("unable to POST '%s' topic to '%s': %w", topic, url, ctx.Err())
+		}
+
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// postOnce POSTs the body to the URL, returns whether a failure is worth retrying.
+func (c *Client) postOnce(ctx context.Context, url string, topic string, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	request.Header.Set("Content-Type", c.codec.ContentType())
+	request.Header.Set(TopicHeader, topic)
+	if c.config.Secret != "" {
+		// Signed on each attempt, so that the retries are not rejected as stale
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set(TimestampHeader, timestamp)
+		request.Header.Set(SignatureHeader, Sign(c.config.Secret, topic, timestamp, body))
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, MaxBodySize))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status '%s'", response.Status)
+}
+
+func (c *Client) context() context.Context {
+	c.clientMutex.RLock()
+	defer c.clientMutex.RUnlock()
+
+	return c.ctx
+}
+
+// Sign returns the value of the SignatureHeader of the body POSTed to the topic with the timestamp, the value of the
+// TimestampHeader. It is the hex encoded HMAC-SHA256 of "<topic>\n<timestamp>\n<body>" keyed with the secret, prefixed
+// with SignaturePrefix, so that a signed body can't be replayed to another topic nor once its timestamp is stale.
+func Sign(secret string, topic string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(topic + "\n" + timestamp + "\n"))
+	mac.Write(body)
+
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether the signature is the valid SignatureHeader value of the body POSTed to the topic
+// with the timestamp for the secret. Whether the timestamp is recent is checked separately.
+func VerifySignature(secret string, topic string, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, topic, timestamp, body)), []byte(signature))
+}
+
+// freshTimestamp reports whether the TimestampHeader value is within SignatureTolerance of now, in either direction
+// to allow for clock skew.
+func freshTimestamp(timestamp string, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	skew := now.Sub(time.Unix(seconds, 0))
+	return skew <= SignatureTolerance && skew >= -SignatureTolerance
+}
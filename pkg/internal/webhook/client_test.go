@@ -0,0 +1,376 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+func newTestClient(t *testing.T, optional map[string]string) *Client {
+	client, err := NewClient(types.MessageBusConfig{Type: "webhook", Optional: optional})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+// newIngestServer serves the ingest endpoint of a new client with the httptest server.
+func newIngestServer(t *testing.T, optional map[string]string) (*Client, *httptest.Server) {
+	client := newTestClient(t, optional)
+	server := httptest.NewServer(client)
+	t.Cleanup(server.Close)
+
+	return client, server
+}
+
+// signedHeaders returns the headers of a POST of the body to the topic signed with the secret at the time.
+func signedHeaders(secret string, topic string, body string, at time.Time) map[string]string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return map[string]string{
+		TimestampHeader: timestamp,
+		SignatureHeader: Sign(secret, topic, timestamp, []byte(body)),
+	}
+}
+
+func TestSignature(t *testing.T) {
+	signature := Sign("secret", "edgex/events", "1700000000", []byte("body"))
+
+	assert.True(t, strings.HasPrefix(signature, SignaturePrefix))
+	assert.True(t, VerifySignature("secret", "edgex/events", "1700000000", []byte("body"), signature))
+	assert.False(t, VerifySignature("other", "edgex/events", "1700000000", []byte("body"), signature))
+	assert.False(t, VerifySignature("secret", "edgex/other", "1700000000", []byte("body"), signature))
+	assert.False(t, VerifySignature("secret", "edgex/events", "1700000001", []byte("body"), signature))
+	assert.False(t, VerifySignature("secret", "edgex/events", "1700000000", []byte("tampered"), signature))
+	assert.False(t, VerifySignature("secret", "edgex/events", "1700000000", []byte("body"), ""))
+}
+
+func TestFreshTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	assert.True(t, freshTimestamp("1700000000", now))
+	assert.True(t, freshTimestamp(strconv.FormatInt(now.Add(-SignatureTolerance).Unix(), 10), now))
+	assert.True(t, freshTimestamp(strconv.FormatInt(now.Add(SignatureTolerance).Unix(), 10), now))
+	assert.False(t, freshTimestamp(strconv.FormatInt(now.Add(-SignatureTolerance-time.Second).Unix(), 10), now))
+	assert.False(t, freshTimestamp(strconv.FormatInt(now.Add(SignatureTolerance+time.Second).Unix(), 10), now))
+	assert.False(t, freshTimestamp("", now))
+	assert.False(t, freshTimestamp("NaN", now))
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	subscriber, server := newIngestServer(t, map[string]string{"Secret": "secret"})
+	publisher := newTestClient(t, map[string]string{
+		"Webhooks": "edgex/events/#=" + server.URL + " edgex/commands/#=" + server.URL,
+		"Secret":   "secret",
+	})
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events/+", Messages: messages}}, make(chan error)))
+
+	expected := types.NewMessageEnvelope([]byte("payload"), context.Background())
+	require.NoError(t, publisher.Publish(expected, "edgex/events/device1"))
+
+	message := testutil.ReceiveMessage(t, messages, testTimeout)
+	assert.Equal(t, expected.CorrelationID, message.CorrelationID)
+	assert.Equal(t, expected.Payload, message.Payload)
+	assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+
+	// The ingest endpoint has no subscription for the topic
+	require.Error(t, publisher.Publish(expected, "edgex/commands/device1"))
+
+	// No webhook is configured for the topic
+	require.NoError(t, publisher.Publish(expected, "edgex/other"))
+}
+
+func TestClientPublishHeaders(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		requests <- request
+		bodies <- body
+	}))
+	t.Cleanup(server.Close)
+
+	publisher := newTestClient(t, map[string]string{"Webhooks": "edgex/#=" + server.URL + "/hook", "Secret": "secret"})
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "123"}, "edgex/events"))
+
+	request := <-requests
+	body := <-bodies
+	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, "/hook", request.URL.Path)
+	assert.Equal(t, types.ContentTypeJSON, request.Header.Get("Content-Type"))
+	assert.Equal(t, "edgex/events", request.Header.Get(TopicHeader))
+	timestamp := request.Header.Get(TimestampHeader)
+	assert.True(t, freshTimestamp(timestamp, time.Now()))
+	assert.Equal(t, Sign("secret", "edgex/events", timestamp, body), request.Header.Get(SignatureHeader))
+	assert.Contains(t, string(body), `"CorrelationID":"123"`)
+}
+
+func TestClientPublishRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if attempts.Add(1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	publisher := newTestClient(t, map[string]string{"Webhooks": "edgex/#=" + server.URL, "RetryBackoff": "10"})
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{}, "edgex/events"))
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// Gives up once MaxRetries is reached
+	attempts.Store(-10)
+	publisher = newTestClient(t, map[string]string{"Webhooks": "edgex/#=" + server.URL, "RetryBackoff": "10", "MaxRetries": "2"})
+	require.Error(t, publisher.Publish(types.MessageEnvelope{}, "edgex/events"))
+	assert.Equal(t, int32(-7), attempts.Load())
+}
+
+func TestClientPublishClientErrorNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		attempts.Add(1)
+		writer.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(server.Close)
+
+	publisher := newTestClient(t, map[string]string{"Webhooks": "edgex/#=" + server.URL, "RetryBackoff": "10"})
+	require.Error(t, publisher.Publish(types.MessageEnvelope{}, "edgex/events"))
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClientPublishAbortedOnDisconnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	publisher := newTestClient(t, map[string]string{"Webhooks": "edgex/#=" + server.URL, "RetryBackoff": "60000"})
+
+	published := make(chan error)
+	go func() {
+		published <- publisher.Publish(types.MessageEnvelope{}, "edgex/events")
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, publisher.Disconnect())
+
+	select {
+	case err := <-published:
+		assert.True(t, errors.Is(err, context.Canceled))
+	case <-time.After(testTimeout):
+		require.Fail(t, "publish not aborted")
+	}
+}
+
+func TestClientPublishTLS(t *testing.T) {
+	subscriber := newTestClient(t, nil)
+	server := httptest.NewTLSServer(subscriber)
+	t.Cleanup(server.Close)
+
+	publisher, err := NewClientWithHTTPClient(types.MessageBusConfig{
+		Optional: map[string]string{"Webhooks": "edgex/#=" + server.URL},
+	}, server.Client())
+	require.NoError(t, err)
+	require.NoError(t, publisher.Connect())
+	t.Cleanup(func() { _ = publisher.Disconnect() })
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, make(chan error)))
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "tls"}, "edgex/events"))
+
+	assert.Equal(t, "tls", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientIngest(t *testing.T) {
+	subscriber, server := newIngestServer(t, map[string]string{"Secret": "secret"})
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/#", Messages: messages}}, make(chan error)))
+
+	body := `{"CorrelationID":"123","Payload":"cGF5bG9hZA=="}`
+	now := time.Now()
+	withTopic := func(headers map[string]string, topic string) map[string]string {
+		headers[TopicHeader] = topic
+		return headers
+	}
+	withTimestamp := func(headers map[string]string, at time.Time) map[string]string {
+		headers[TimestampHeader] = strconv.FormatInt(at.Unix(), 10)
+		return headers
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"Topic from path", http.MethodPost, "/edgex/events/device1", body, signedHeaders("secret", "edgex/events/device1", body, now), http.StatusAccepted},
+		{"Topic from header", http.MethodPost, "/", body, withTopic(signedHeaders("secret", "edgex/events", body, now), "edgex/events"), http.StatusAccepted},
+		{"Missing signature", http.MethodPost, "/edgex/events", body, nil, http.StatusUnauthorized},
+		{"Invalid signature", http.MethodPost, "/edgex/events", body, signedHeaders("other", "edgex/events", body, now), http.StatusUnauthorized},
+		{"Replayed to another topic", http.MethodPost, "/edgex/other", body, signedHeaders("secret", "edgex/events", body, now), http.StatusUnauthorized},
+		{"Replayed with another timestamp", http.MethodPost, "/edgex/events", body, withTimestamp(signedHeaders("secret", "edgex/events", body, now), now.Add(time.Second)), http.StatusUnauthorized},
+		{"Stale timestamp", http.MethodPost, "/edgex/events", body, signedHeaders("secret", "edgex/events", body, now.Add(-SignatureTolerance-time.Minute)), http.StatusUnauthorized},
+		{"Missing topic", http.MethodPost, "/", body, signedHeaders("secret", "", body, now), http.StatusBadRequest},
+		{"Invalid body", http.MethodPost, "/edgex/events", "not json", signedHeaders("secret", "edgex/events", "not json", now), http.StatusBadRequest},
+		{"Body too large", http.MethodPost, "/edgex/events", strings.Repeat(" ", MaxBodySize+1), nil, http.StatusRequestEntityTooLarge},
+		{"No subscription", http.MethodPost, "/other", body, signedHeaders("secret", "other", body, now), http.StatusNotFound},
+		{"Not a POST", http.MethodGet, "/edgex/events", "", nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			for key, value := range tt.headers {
+				request.Header.Set(key, value)
+			}
+
+			response, err := server.Client().Do(request)
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			require.Equal(t, tt.expectedStatus, response.StatusCode)
+
+			if tt.expectedStatus == http.StatusAccepted {
+				message := testutil.ReceiveMessage(t, messages, testTimeout)
+				assert.Equal(t, "123", message.CorrelationID)
+				assert.Equal(t, []byte("payload"), message.Payload)
+			}
+		})
+	}
+}
+
+func TestClientIngestQueueFull(t *testing.T) {
+	subscriber, server := newIngestServer(t, map[string]string{"MaxPending": "1"})
+
+	// Nobody receives, so the messages pile up in the subscription queue
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	status := http.StatusAccepted
+	var response *http.Response
+	for i := 0; i < 5 && status == http.StatusAccepted; i++ {
+		var err error
+		response, err = server.Client().Post(server.URL+"/edgex/events", types.ContentTypeJSON, strings.NewReader("{}"))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		status = response.StatusCode
+	}
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	assert.NotEmpty(t, response.Header.Get("Retry-After"))
+
+	// The POSTs are accepted again once the subscriber caught up
+	testutil.ReceiveMessage(t, messages, testTimeout)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-messages:
+			case <-done:
+				return
+			}
+		}
+	}()
+	require.Eventually(t, func() bool {
+		response, err := server.Client().Post(server.URL+"/edgex/events", types.ContentTypeJSON, strings.NewReader("{}"))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		return response.StatusCode == http.StatusAccepted
+	}, testTimeout, 10*time.Millisecond)
+}
+
+func TestClientListen(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	subscriber, err := NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: "127.0.0.1", Port: port, Protocol: "http"}})
+	require.NoError(t, err)
+	require.NoError(t, subscriber.Connect())
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	publisher := newTestClient(t, map[string]string{"Webhooks": "edgex/#=http://127.0.0.1:" + strconv.Itoa(port)})
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "listen"}, "edgex/events"))
+	assert.Equal(t, "listen", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+
+	// The ingest endpoint is stopped on disconnect
+	require.NoError(t, subscriber.Disconnect())
+	_, err = http.Post(fmt.Sprintf("http://127.0.0.1:%d/edgex/events", port), types.ContentTypeJSON, strings.NewReader("{}"))
+	require.Error(t, err)
+}
+
+func TestClientRequest(t *testing.T) {
+	requester, requesterServer := newIngestServer(t, nil)
+	responder, responderServer := newIngestServer(t, map[string]string{"Webhooks": "edgex/response/#=" + requesterServer.URL})
+	requester.config.Webhooks = []Webhook{{Filter: "edgex/request", URL: responderServer.URL}}
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+}
+
+func TestClientSubscribeDuplicateTopic(t *testing.T) {
+	client := newTestClient(t, nil)
+
+	topics := []types.TopicChannel{{Topic: "edgex/events", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	subscriber, server := newIngestServer(t, nil)
+	publisher := newTestClient(t, map[string]string{"Webhooks": "edgex/#=" + server.URL, "MaxRetries": "0"})
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.NoError(t, subscriber.Unsubscribe("edgex/events"))
+	close(messages)
+
+	// The ingest endpoint no longer has a subscription for the topic
+	require.Error(t, publisher.Publish(types.MessageEnvelope{}, "edgex/events"))
+}
+
+func TestClientDisconnected(t *testing.T) {
+	client := newTestClient(t, nil)
+	require.NoError(t, client.Disconnect())
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+	require.NoError(t, client.Disconnect())
+}
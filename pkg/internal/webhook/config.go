@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolHTTP is the only protocol supported by the ingest endpoint.
+	ProtocolHTTP = "http"
+
+	// WebhookSeparator separates the webhooks of the Webhooks list, any white space does. White space can't appear
+	// in a URL, unlike "," which a URL's query may contain.
+	WebhookSeparator = " "
+	// FilterSeparator separates the topic filter from the URL of a webhook, at its first occurrence so that the URL
+	// may contain it. The topic filters therefore can't contain it.
+	FilterSeparator = "="
+
+	// DefaultConnectTimeout is the number of seconds a POST may take when not configured.
+	DefaultConnectTimeout = 30
+	// DefaultMaxRetries is the number of times a failed POST is retried when not configured.
+	DefaultMaxRetries = 3
+	// DefaultRetryBackoff is the number of milliseconds to wait before the first retry when not configured.
+	DefaultRetryBackoff = 500
+)
+
+// ClientConfig contains all the configurations for the webhook client.
+type ClientConfig struct {
+	// ListenAddress is the address of the ingest endpoint, empty when the client doesn't listen itself
+	ListenAddress string
+	Webhooks      []Webhook
+	ClientOptions
+}
+
+// Webhook is a URL the messages published to the topics matching the filter are POSTed to.
+type Webhook struct {
+	Filter string
+	URL    string
+}
+
+// ClientOptions contains the client options which are loaded via reflection from the MessageBus.Optional field.
+type ClientOptions struct {
+	Webhooks string // White space separated list of <topic filter>=<URL>, e.g. "edgex/events/#=http://service/events"
+
+	// Secret is the key of the HMAC-SHA256 signature of the POSTed bodies, the ingest endpoint rejects the bodies
+	// without a valid signature when set
+	Secret string
+
+	MaxRetries     int // Retries of a POST failing with a network error, a 429 or 5xx status
+	RetryBackoff   int // Milliseconds before the first retry, doubled after each retry
+	ConnectTimeout int // Seconds a POST attempt may take
+
+	// MaxPending is the number of messages queued for each subscription, the ingest endpoint answers 503 to the POSTs
+	// finding the queue full, unbounded when 0
+	MaxPending int
+
+	Format string // Name of the codec of the published envelopes, JSON when empty, see codec.Lookup
+}
+
+// NewClientConfiguration creates a ClientConfig based on the provided MessageBusConfig. The Broker's Host and Port
+// are the address the ingest endpoint listens on, the client only publishes when no Host is set.
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	options := ClientOptions{
+		MaxRetries:     DefaultMaxRetries,
+		RetryBackoff:   DefaultRetryBackoff,
+		ConnectTimeout: DefaultConnectTimeout,
+		MaxPending:     internal.DefaultMaxPending,
+	}
+	if err := internal.Load(messageBusConfig.Optional, &options); err != nil {
+		return ClientConfig{}, err
+	}
+
+	if options.MaxRetries < 0 || options.RetryBackoff < 0 {
+		return ClientConfig{}, fmt.Errorf("%s and %s must not be negative", internal.MaxRetries, internal.RetryBackoff)
+	}
+
+	if options.MaxPending < 0 {
+		return ClientConfig{}, fmt.Errorf("%s must not be negative", internal.MaxPending)
+	}
+
+	if _, err := codec.Lookup(options.Format); err != nil {
+		return ClientConfig{}, err
+	}
+
+	webhooks, err := parseWebhooks(options.Webhooks)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	config := ClientConfig{
+		Webhooks:      webhooks,
+		ClientOptions: options,
+	}
+
+	broker := messageBusConfig.Broker
+	if broker.Host == "" {
+		return config, nil
+	}
+
+	if broker.Protocol != "" && !strings.EqualFold(broker.Protocol, ProtocolHTTP) {
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("unsupported protocol '%s', must be '%s'",
+			broker.Protocol, ProtocolHTTP))
+	}
+
+	config.ListenAddress = net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port))
+
+	return config, nil
+}
+
+// parseWebhooks parses the white space separated list of <topic filter>=<URL>.
+func parseWebhooks(webhooks string) ([]Webhook, error) {
+	var result []Webhook
+	for _, webhook := range strings.Fields(webhooks) {
+		filter, rawURL, found := strings.Cut(webhook, FilterSeparator)
+		if !found {
+			return nil, fmt.Errorf("invalid webhook '%s', must be <topic filter>%s<URL>", webhook, FilterSeparator)
+		}
+
+		if err := internal.ValidateTopicFilter(filter); err != nil {
+			return nil, err
+		}
+
+		parsedURL, err := url.Parse(rawURL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL '%s', must be an absolute http or https URL", rawURL)
+		}
+
+		result = append(result, Webhook{Filter: filter, URL: rawURL})
+	}
+
+	return result, nil
+}
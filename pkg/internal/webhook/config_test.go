@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientConfiguration(t *testing.T) {
+	defaults := ClientOptions{
+		MaxRetries:     DefaultMaxRetries,
+		RetryBackoff:   DefaultRetryBackoff,
+		ConnectTimeout: DefaultConnectTimeout,
+		MaxPending:     internal.DefaultMaxPending,
+	}
+
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		want    ClientConfig
+		wantErr bool
+	}{
+		{
+			name:   "Publish only",
+			config: types.MessageBusConfig{},
+			want:   ClientConfig{ClientOptions: defaults},
+		},
+		{
+			name:   "Ingest endpoint",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "0.0.0.0", Port: 8080, Protocol: "HTTP"}},
+			want:   ClientConfig{ListenAddress: "0.0.0.0:8080", ClientOptions: defaults},
+		},
+		{
+			name: "All options",
+			config: types.MessageBusConfig{
+				Optional: map[string]string{
+					"Webhooks":       "edgex/events/#=http://service/events\n edgex/+/device1=https://other:8443/hook?a=b,c&d=e=f ",
+					"Secret":         "secret",
+					"MaxRetries":     "5",
+					"RetryBackoff":   "100",
+					"ConnectTimeout": "10",
+					"MaxPending":     "0",
+				},
+			},
+			want: ClientConfig{
+				Webhooks: []Webhook{
+					{Filter: "edgex/events/#", URL: "http://service/events"},
+					{Filter: "edgex/+/device1", URL: "https://other:8443/hook?a=b,c&d=e=f"},
+				},
+				ClientOptions: ClientOptions{
+					Webhooks:       "edgex/events/#=http://service/events\n edgex/+/device1=https://other:8443/hook?a=b,c&d=e=f ",
+					Secret:         "secret",
+					MaxRetries:     5,
+					RetryBackoff:   100,
+					ConnectTimeout: 10,
+				},
+			},
+		},
+		{
+			name:    "Webhook without URL",
+			config:  types.MessageBusConfig{Optional: map[string]string{"Webhooks": "edgex/#"}},
+			wantErr: true,
+		},
+		{
+			name:    "Webhook with relative URL",
+			config:  types.MessageBusConfig{Optional: map[string]string{"Webhooks": "edgex/#=/hook"}},
+			wantErr: true,
+		},
+		{
+			name:    "Webhook with invalid filter",
+			config:  types.MessageBusConfig{Optional: map[string]string{"Webhooks": "edgex/#/events=http://service"}},
+			wantErr: true,
+		},
+		{
+			name:    "Negative MaxRetries",
+			config:  types.MessageBusConfig{Optional: map[string]string{"MaxRetries": "-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "Negative MaxPending",
+			config:  types.MessageBusConfig{Optional: map[string]string{"MaxPending": "-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported protocol",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 8443, Protocol: "https"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientConfiguration(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,504 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"messaging/pkg/websocket"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	gorilla "github.com/gorilla/websocket"
+)
+
+const (
+	// responseTimeout is the maximum time to wait for the handler to acknowledge a frame, requests wait this long on
+	// top of their own timeout.
+	responseTimeout = 30 * time.Second
+	// writeTimeout is the maximum time writing a frame may take.
+	writeTimeout = 10 * time.Second
+)
+
+// Client MessageClient implementation which provides functionality for sending and receiving messages through the
+// WebSocket handler provided by the websocket package.
+type Client struct {
+	config    ClientConfig
+	tlsConfig *tls.Config
+	codec     codec.Codec
+
+	conn       *gorilla.Conn
+	done       chan struct{}
+	connMutex  sync.Mutex
+	writeMutex sync.Mutex
+
+	nextID       uint64
+	pending      map[uint64]chan websocket.Frame
+	pendingMutex sync.Mutex
+
+	// Used to avoid multiple subscriptions to the same topic
+	existingSubscriptions map[string]*subscription
+	subscriptionMutex     *sync.Mutex
+}
+
+type subscription struct {
+	*internal.Subscription
+	errors chan error
+}
+
+// NewClient creates a new Client based on the provided configuration.
+func NewClient(messageBusConfig types.MessageBusConfig) (*Client, error) {
+	return NewClientWithCreator(messageBusConfig, tls.X509KeyPair, tls.LoadX509KeyPair, x509.ParseCertificate,
+		os.ReadFile, pem.Decode)
+}
+
+// NewClientWithCreator creates a new Client based on the provided configuration while allowing more control on the
+// creation of the certs and keys.
+func NewClientWithCreator(
+	messageBusConfig types.MessageBusConfig,
+	pairCreator internal.X509KeyPairCreator,
+	keyLoader internal.X509KeyLoader,
+	caCertCreator internal.X509CaCertCreator,
+	caCertLoader internal.X509CaCertLoader,
+	pemDecoder internal.PEMDecoder) (*Client, error) {
+
+	config, err := NewClientConfiguration(messageBusConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := internal.GenerateTLSForClientClientOptions(
+		config.URL,
+		config.TlsConfigurationOptions,
+		pairCreator,
+		keyLoader,
+		caCertCreator,
+		caCertLoader,
+		pemDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without certificates the TLS configuration is only needed to skip the verification
+	if tlsConfig == nil && config.SkipCertVerify {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true} // nolint: gosec
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:                config,
+		tlsConfig:             tlsConfig,
+		codec:                 envelopeCodec,
+		pending:               make(map[uint64]chan websocket.Frame),
+		existingSubscriptions: make(map[string]*subscription),
+		subscriptionMutex:     new(sync.Mutex),
+	}, nil
+}
+
+// Connect establishes the WebSocket connection to the handler.
+func (c *Client) Connect() error {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+
+	dialer := gorilla.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: time.Duration(c.config.ConnectTimeout) * time.Second,
+		TLSClientConfig:  c.tlsConfig,
+	}
+
+	header := http.Header{}
+	if c.config.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(c.config.Username + ":" + c.config.Password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+
+	conn, _, err := dialer.Dial(c.config.URL, header)
+	if err != nil {
+		return fmt.Errorf("unable to connect to the WebSocket handler at %s: %w", c.config.URL, err)
+	}
+
+	conn.SetReadLimit(websocket.MaxFrameSize)
+
+	c.conn = conn
+	c.done = make(chan struct{})
+	go c.read(conn, c.done)
+
+	return nil
+}
+
+// Publish sends the provided message to the handler which publishes it to the topic.
+func (c *Client) Publish(message types.MessageEnvelope, topic string) error {
+	return c.PublishContext(context.Background(), message, topic)
+}
+
+// PublishContext is Publish which stops waiting for the handler to acknowledge the message when the context is done.
+func (c *Client) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	if err := internal.ValidatePublishTopic(topic); err != nil {
+		return err
+	}
+
+	envelope, data, err := internal.EncodeFrameEnvelope(message, c.codec)
+	if err != nil {
+		return err
+	}
+
+	frame := websocket.Frame{Type: websocket.FramePublish, Topic: topic, Envelope: envelope, Data: data}
+	_, err = c.roundTrip(ctx, frame, responseTimeout)
+	return err
+}
+
+// Subscribe creates subscriptions for the specified topics. The MQTT '+' and '#' wildcards are supported.
+func (c *Client) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return c.SubscribeContext(context.Background(), topics, messageErrors)
+}
+
+// SubscribeContext is Subscribe which stops waiting for the handler to acknowledge the subscriptions when the context
+// is done. The subscriptions are then removed, messages the handler still delivers for them are dropped.
+func (c *Client) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	c.subscriptionMutex.Lock()
+
+	// First validate all the topics are unique, i.e. not existing subscription
+	filters := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if err := internal.ValidateTopicFilter(topic.Topic); err != nil {
+			c.subscriptionMutex.Unlock()
+			return err
+		}
+
+		if _, exists := c.existingSubscriptions[topic.Topic]; exists {
+			c.subscriptionMutex.Unlock()
+			return fmt.Errorf("subscription for '%s' topic already exists, must be unique", topic.Topic)
+		}
+
+		filters = append(filters, topic.Topic)
+	}
+
+	// The subscriptions must be in place before the handler starts delivering messages for them
+	for _, topic := range topics {
+		c.existingSubscriptions[topic.Topic] = &subscription{
+			Subscription: internal.NewSubscription(topic.Topic, topic.Messages),
+			errors:       messageErrors,
+		}
+	}
+
+	// The lock is released while waiting for the handler since dispatching the received messages needs it
+	c.subscriptionMutex.Unlock()
+
+	if _, err := c.roundTrip(ctx, websocket.Frame{Type: websocket.FrameSubscribe, Topics: filters}, responseTimeout); err != nil {
+		c.subscriptionMutex.Lock()
+		c.removeSubscriptions(filters)
+		c.subscriptionMutex.Unlock()
+
+		return fmt.Errorf("unable to subscribe: %w", err)
+	}
+
+	return nil
+}
+
+// Request sends the request to the handler which publishes it and waits for the response on the response topic
+// which contains the RequestID.
+func (c *Client) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done. The handler is given the time
+// left until the context's deadline to wait for the response, or its default timeout when the context has none. The
+// responders are notified with a cancel notice when the context is done first.
+func (c *Client) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	if err := internal.ValidatePublishTopic(requestTopic); err != nil {
+		return nil, err
+	}
+
+	// The RequestID is needed to cancel the request, so it isn't left to the handler
+	if strings.TrimSpace(message.RequestID) == "" {
+		message.RequestID = uuid.NewString()
+	}
+	message = internal.WithDeadline(ctx, message)
+
+	timeout := websocket.DefaultRequestTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout < time.Millisecond {
+			timeout = time.Millisecond
+		}
+	}
+
+	envelope, data, err := internal.EncodeFrameEnvelope(message, c.codec)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := websocket.Frame{
+		Type:                websocket.FrameRequest,
+		Topic:               requestTopic,
+		ResponseTopicPrefix: responseTopicPrefix,
+		Timeout:             timeout.Milliseconds(),
+		Envelope:            envelope,
+		Data:                data,
+	}
+
+	response, err := c.roundTrip(ctx, frame, timeout+responseTimeout)
+	if err != nil {
+		if ctx.Err() != nil {
+			internal.PublishCancel(c.PublishContext, message, responseTopicPrefix)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out waiting for response to %s: %w", requestTopic, err)
+		}
+		return nil, err
+	}
+
+	envelope, err = internal.DecodeFrameEnvelope(response.Envelope, response.Data, c.codec)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the response to %s: %w", requestTopic, err)
+	}
+
+	return envelope, nil
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *Client) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
+
+// Unsubscribe removes the subscriptions for the specified topics. Once returned no more messages are sent to the
+// channels of these subscriptions.
+func (c *Client) Unsubscribe(topics ...string) error {
+	c.subscriptionMutex.Lock()
+	filters := c.removeSubscriptions(topics)
+	c.subscriptionMutex.Unlock()
+
+	if len(filters) == 0 || !c.isConnected() {
+		return nil
+	}
+
+	_, err := c.roundTrip(context.Background(), websocket.Frame{Type: websocket.FrameUnsubscribe, Topics: filters}, responseTimeout)
+	return err
+}
+
+// Disconnect removes all the subscriptions and closes the connection to the handler.
+func (c *Client) Disconnect() error {
+	c.subscriptionMutex.Lock()
+	topics := make([]string, 0, len(c.existingSubscriptions))
+	for topic := range c.existingSubscriptions {
+		topics = append(topics, topic)
+	}
+	c.removeSubscriptions(topics)
+	c.subscriptionMutex.Unlock()
+
+	c.connMutex.Lock()
+	conn, done := c.conn, c.done
+	c.conn = nil
+	c.connMutex.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	c.writeMutex.Lock()
+	_ = conn.WriteControl(gorilla.CloseMessage, gorilla.FormatCloseMessage(gorilla.CloseNormalClosure, ""),
+		time.Now().Add(writeTimeout))
+	c.writeMutex.Unlock()
+
+	err := conn.Close()
+	<-done
+
+	return err
+}
+
+// removeSubscriptions stops the existing subscriptions of the topics and returns their topics. Must be called with the
+// subscriptionMutex held.
+func (c *Client) removeSubscriptions(topics []string) []string {
+	var removed []string
+	for _, topic := range topics {
+		s, exists := c.existingSubscriptions[topic]
+		if !exists {
+			continue
+		}
+
+		s.Stop()
+		delete(c.existingSubscriptions, topic)
+		removed = append(removed, topic)
+	}
+
+	return removed
+}
+
+// roundTrip sends the frame to the handler and waits for its response, an error frame is returned as an error. Waiting
+// stops when the context is done, returning the context's error.
+func (c *Client) roundTrip(ctx context.Context, frame websocket.Frame, timeout time.Duration) (websocket.Frame, error) {
+	if err := ctx.Err(); err != nil {
+		return websocket.Frame{}, err
+	}
+
+	c.connMutex.Lock()
+	conn, done := c.conn, c.done
+	c.connMutex.Unlock()
+
+	if conn == nil {
+		return websocket.Frame{}, internal.NewMissingConfigurationErr("Connection", "Unable to send to the WebSocket handler with a disconnected client")
+	}
+
+	frame.ID = atomic.AddUint64(&c.nextID, 1)
+	response := make(chan websocket.Frame, 1)
+
+	c.pendingMutex.Lock()
+	c.pending[frame.ID] = response
+	c.pendingMutex.Unlock()
+
+	defer func() {
+		c.pendingMutex.Lock()
+		delete(c.pending, frame.ID)
+		c.pendingMutex.Unlock()
+	}()
+
+	c.writeMutex.Lock()
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	err := conn.WriteJSON(frame)
+	c.writeMutex.Unlock()
+	if err != nil {
+		return websocket.Frame{}, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-response:
+		if result.Type == websocket.FrameError {
+			return websocket.Frame{}, errors.New(result.Error)
+		}
+		return result, nil
+	case <-done:
+		return websocket.Frame{}, errors.New("connection to the WebSocket handler closed")
+	case <-ctx.Done():
+		return websocket.Frame{}, ctx.Err()
+	case <-timer.C:
+		return websocket.Frame{}, fmt.Errorf("timed out waiting for the WebSocket handler to answer the %s", frame.Type)
+	}
+}
+
+// read processes the frames received from the handler until the connection is closed.
+func (c *Client) read(conn *gorilla.Conn, done chan struct{}) {
+	defer close(done)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			c.connectionLost(conn, err)
+			return
+		}
+
+		var frame websocket.Frame
+		if err = json.Unmarshal(data, &frame); err != nil {
+			continue
+		}
+
+		switch {
+		case frame.Type == websocket.FrameMessage:
+			c.dispatch(frame)
+		case frame.ID != 0:
+			c.pendingMutex.Lock()
+			response, exists := c.pending[frame.ID]
+			c.pendingMutex.Unlock()
+			if exists {
+				response <- frame
+			}
+		case frame.Type == websocket.FrameError:
+			c.subscriptionError(frame)
+		}
+	}
+}
+
+// dispatch queues the message for the subscription to the topic filter the handler delivered it for, the messages
+// which can't be decoded are reported to the subscription instead.
+func (c *Client) dispatch(frame websocket.Frame) {
+	message, err := internal.DecodeFrameEnvelope(frame.Envelope, frame.Data, c.codec)
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	s, exists := c.existingSubscriptions[frame.Topic]
+	if !exists {
+		return
+	}
+
+	if err != nil {
+		select {
+		case s.errors <- fmt.Errorf("unable to decode the message received for '%s': %w", frame.Topic, err):
+		default:
+		}
+		return
+	}
+
+	s.Enqueue(*message)
+}
+
+// subscriptionError reports the error of the handler's subscription to the subscription of the same topic filter.
+func (c *Client) subscriptionError(frame websocket.Frame) {
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	if s, exists := c.existingSubscriptions[frame.Topic]; exists {
+		select {
+		case s.errors <- errors.New(frame.Error):
+		default:
+		}
+	}
+}
+
+// connectionLost reports the loss of the connection to the subscriptions unless the client has been disconnected.
+func (c *Client) connectionLost(conn *gorilla.Conn, err error) {
+	c.connMutex.Lock()
+	lost := c.conn == conn
+	if lost {
+		c.conn = nil
+	}
+	c.connMutex.Unlock()
+
+	if !lost {
+		return
+	}
+
+	_ = conn.Close()
+
+	c.subscriptionMutex.Lock()
+	defer c.subscriptionMutex.Unlock()
+
+	for _, s := range c.existingSubscriptions {
+		select {
+		case s.errors <- fmt.Errorf("connection to the WebSocket handler lost: %w", err):
+		default:
+		}
+	}
+}
+
+func (c *Client) isConnected() bool {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+
+	return c.conn != nil
+}
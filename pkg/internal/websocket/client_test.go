@@ -0,0 +1,203 @@
+package websocket
+
+import (
+	"encoding/pem"
+	"messaging/pkg/internal/memory"
+	"messaging/pkg/internal/testutil"
+	"messaging/pkg/types"
+	"messaging/pkg/websocket"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+// startHandler serves the WebSocket handler on top of a client of a dedicated in-process bus.
+func startHandler(t *testing.T) (*websocket.Handler, types.MessageBusConfig) {
+	handler, _, config := serveHandler(t, httptest.NewServer, ProtocolWS)
+
+	return handler, config
+}
+
+// serveHandler serves the WebSocket handler with the server created by newServer, e.g. httptest.NewTLSServer.
+func serveHandler(t *testing.T, newServer func(http.Handler) *httptest.Server, protocol string) (*websocket.Handler, *httptest.Server, types.MessageBusConfig) {
+	bus, err := memory.NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: t.Name()}})
+	require.NoError(t, err)
+
+	handler := websocket.NewHandler(bus)
+	server := newServer(handler)
+	t.Cleanup(func() {
+		require.NoError(t, handler.Close())
+		server.Close()
+		_ = bus.Disconnect()
+	})
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return handler, server, types.MessageBusConfig{Broker: types.HostInfo{Host: host, Port: portNumber, Protocol: protocol}}
+}
+
+func newConnectedClient(t *testing.T, config types.MessageBusConfig) *Client {
+	client, err := NewClient(config)
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	_, config := startHandler(t)
+	publisher := newConnectedClient(t, config)
+	subscriber := newConnectedClient(t, config)
+
+	exact := make(chan types.MessageEnvelope, 1)
+	wildcard := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{
+		{Topic: "edgex/events/device1", Messages: exact},
+		{Topic: "edgex/+/#", Messages: wildcard},
+	}, make(chan error)))
+
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "123", Payload: []byte("data")}, "edgex/events/device1"))
+
+	for _, messages := range []chan types.MessageEnvelope{exact, wildcard} {
+		message := testutil.ReceiveMessage(t, messages, testTimeout)
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, []byte("data"), message.Payload)
+		assert.Equal(t, "edgex/events/device1", message.ReceivedTopic)
+	}
+}
+
+func TestClientFormat(t *testing.T) {
+	_, config := startHandler(t)
+	responder := newConnectedClient(t, config)
+	config.Optional = map[string]string{"Format": "cbor"}
+	requester := newConnectedClient(t, config)
+
+	requests := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse(request.Payload, request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("request"), response.Payload)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, requester.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+	require.NoError(t, requester.Publish(types.MessageEnvelope{CorrelationID: "cbor", Payload: []byte("data")}, "edgex/events"))
+
+	message := testutil.ReceiveMessage(t, messages, testTimeout)
+	assert.Equal(t, "cbor", message.CorrelationID)
+	assert.Equal(t, []byte("data"), message.Payload)
+}
+
+func TestClientRequest(t *testing.T) {
+	_, config := startHandler(t)
+	responder := newConnectedClient(t, config)
+	requester := newConnectedClient(t, config)
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = responder.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response, err := requester.Request(request, "edgex/request", "edgex/response", testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, request.RequestID, response.RequestID)
+	assert.Equal(t, []byte("response"), response.Payload)
+
+	// Without responder the request times out
+	_, err = requester.Request(types.NewMessageEnvelopeForRequest(nil, nil), "edgex/nobody", "edgex/response", 100*time.Millisecond)
+	require.Error(t, err)
+}
+
+func TestClientTLS(t *testing.T) {
+	_, server, config := serveHandler(t, httptest.NewTLSServer, ProtocolWSS)
+
+	untrusted, err := NewClient(config)
+	require.NoError(t, err)
+	require.Error(t, untrusted.Connect())
+
+	config.Optional = map[string]string{
+		"CaPEMBlock": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+	}
+	client := newConnectedClient(t, config)
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "edgex/events"))
+}
+
+func TestClientUnsubscribe(t *testing.T) {
+	_, config := startHandler(t)
+	client := newConnectedClient(t, config)
+
+	messages := make(chan types.MessageEnvelope)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Unsubscribe("test"))
+	close(messages)
+
+	// Publishing after the channel is closed must not panic
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test"))
+
+	// The topic can be subscribed again
+	messages = make(chan types.MessageEnvelope, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: messages}}, make(chan error)))
+	require.NoError(t, client.Publish(types.MessageEnvelope{CorrelationID: "again"}, "test"))
+	assert.Equal(t, "again", testutil.ReceiveMessage(t, messages, testTimeout).CorrelationID)
+}
+
+func TestClientConnectionLost(t *testing.T) {
+	handler, config := startHandler(t)
+	client := newConnectedClient(t, config)
+
+	errs := make(chan error, 1)
+	require.NoError(t, client.Subscribe([]types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}, errs))
+	require.NoError(t, handler.Close())
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "connection to the WebSocket handler lost")
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for the connection loss")
+	}
+
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "test"))
+}
+
+func TestClientInvalidOperations(t *testing.T) {
+	_, config := startHandler(t)
+
+	disconnected, err := NewClient(config)
+	require.NoError(t, err)
+	require.Error(t, disconnected.Publish(types.MessageEnvelope{}, "test"))
+	require.Error(t, disconnected.Subscribe([]types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+	require.NoError(t, disconnected.Disconnect())
+
+	client := newConnectedClient(t, config)
+	require.Error(t, client.Publish(types.MessageEnvelope{}, "test/#"))
+	require.Error(t, client.Subscribe([]types.TopicChannel{{Topic: "test/#/invalid", Messages: make(chan types.MessageEnvelope)}}, make(chan error)))
+
+	topics := []types.TopicChannel{{Topic: "test", Messages: make(chan types.MessageEnvelope)}}
+	require.NoError(t, client.Subscribe(topics, make(chan error)))
+	require.Error(t, client.Subscribe(topics, make(chan error)))
+}
@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"messaging/pkg/websocket"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolWS connects to the handler without TLS.
+	ProtocolWS = "ws"
+	// ProtocolWSS connects to the handler over TLS.
+	ProtocolWSS = "wss"
+
+	// DefaultPath is the HTTP path of the handler when not configured.
+	DefaultPath = "/"
+	// DefaultConnectTimeout is the number of seconds to wait for the connection when not configured.
+	DefaultConnectTimeout = 30
+)
+
+// ClientConfig contains the settings needed to connect to the WebSocket handler.
+type ClientConfig struct {
+	URL string
+	ClientOptions
+	internal.TlsConfigurationOptions
+}
+
+// ClientOptions contains the client options which are loaded via the MessageBus.Optional's field.
+type ClientOptions struct {
+	Path           string // HTTP path of the handler, e.g. "/messagebus"
+	Username       string // Sent with the Password as basic authentication when set
+	Password       string
+	ConnectTimeout int    // Seconds
+	Format         string // Name of the codec of the frames' envelopes, JSON when empty, see codec.Lookup
+}
+
+// NewClientConfiguration creates a ClientConfig based on the MessageBus configuration, the Broker's Protocol selects
+// between "ws", the default, and "wss". The TLS options, such as CaFile or CertFile and KeyFile, apply to "wss".
+func NewClientConfiguration(messageBusConfig types.MessageBusConfig) (ClientConfig, error) {
+	config := ClientConfig{
+		ClientOptions: ClientOptions{Path: DefaultPath, ConnectTimeout: DefaultConnectTimeout},
+	}
+
+	if err := internal.Load(messageBusConfig.Optional, &config.ClientOptions); err != nil {
+		return ClientConfig{}, err
+	}
+
+	envelopeCodec, err := codec.Lookup(config.Format)
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	config.TlsConfigurationOptions = internal.CreateDefaultTlsConfigurationOptions()
+	if err := internal.Load(messageBusConfig.Optional, &config.TlsConfigurationOptions); err != nil {
+		return ClientConfig{}, err
+	}
+
+	broker := messageBusConfig.Broker
+	if broker.Host == "" {
+		return ClientConfig{}, internal.NewBrokerURLErr("Host is required")
+	}
+
+	scheme := strings.ToLower(broker.Protocol)
+	switch scheme {
+	case "":
+		scheme = ProtocolWS
+	case ProtocolWS, ProtocolWSS:
+	default:
+		return ClientConfig{}, internal.NewBrokerURLErr(fmt.Sprintf("unsupported protocol '%s', must be '%s' or '%s'",
+			broker.Protocol, ProtocolWS, ProtocolWSS))
+	}
+
+	host := broker.Host
+	if broker.Port != 0 {
+		host = net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port))
+	}
+
+	path := config.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	handlerURL := url.URL{Scheme: scheme, Host: host, Path: path}
+	// The handler carries the envelopes as is unless the connection's URL names another format
+	if envelopeCodec != codec.JSON {
+		handlerURL.RawQuery = url.Values{websocket.FormatParameter: {envelopeCodec.Name()}}.Encode()
+	}
+	config.URL = handlerURL.String()
+
+	return config, nil
+}
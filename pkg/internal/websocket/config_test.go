@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientConfiguration(t *testing.T) {
+	defaultOptions := ClientOptions{Path: DefaultPath, ConnectTimeout: DefaultConnectTimeout}
+	defaultTlsOptions := internal.CreateDefaultTlsConfigurationOptions()
+
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		want    ClientConfig
+		wantErr bool
+	}{
+		{
+			name:   "Default protocol is ws",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 8080}},
+			want:   ClientConfig{URL: "ws://localhost:8080/", ClientOptions: defaultOptions, TlsConfigurationOptions: defaultTlsOptions},
+		},
+		{
+			name: "wss with options",
+			config: types.MessageBusConfig{
+				Broker: types.HostInfo{Host: "gateway", Protocol: "WSS"},
+				Optional: map[string]string{
+					"Path":           "messagebus",
+					"Username":       "user",
+					"Password":       "secret",
+					"SkipCertVerify": "true",
+					"CaFile":         "ca.pem",
+					"ConnectTimeout": "5",
+					"Format":         "json",
+				},
+			},
+			want: ClientConfig{
+				URL: "wss://gateway/messagebus",
+				ClientOptions: ClientOptions{
+					Path:           "messagebus",
+					Username:       "user",
+					Password:       "secret",
+					ConnectTimeout: 5,
+					Format:         "json",
+				},
+				TlsConfigurationOptions: internal.TlsConfigurationOptions{SkipCertVerify: true, CaFile: "ca.pem"},
+			},
+		},
+		{
+			name:   "IPv6 host",
+			config: types.MessageBusConfig{Broker: types.HostInfo{Host: "::1", Port: 8080, Protocol: "ws"}},
+			want:   ClientConfig{URL: "ws://[::1]:8080/", ClientOptions: defaultOptions, TlsConfigurationOptions: defaultTlsOptions},
+		},
+		{
+			name:    "Missing host",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Port: 8080}},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported protocol",
+			config:  types.MessageBusConfig{Broker: types.HostInfo{Host: "localhost", Port: 8080, Protocol: "http"}},
+			wantErr: true,
+		},
+		{
+			name: "MessagePack format",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 8080},
+				Optional: map[string]string{"Format": "MsgPack"},
+			},
+			want: ClientConfig{
+				URL:                     "ws://localhost:8080/?format=msgpack",
+				ClientOptions:           ClientOptions{Path: DefaultPath, ConnectTimeout: DefaultConnectTimeout, Format: "MsgPack"},
+				TlsConfigurationOptions: defaultTlsOptions,
+			},
+		},
+		{
+			name: "Unknown format",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 8080},
+				Optional: map[string]string{"Format": "xml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid option",
+			config: types.MessageBusConfig{
+				Broker:   types.HostInfo{Host: "localhost", Port: 8080},
+				Optional: map[string]string{"ConnectTimeout": "soon"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientConfiguration(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package amqp
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+)
+
+type amqpOptionalConfigurationBuilder struct {
+	options map[string]string
+}
+
+// NewAMQPOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewAMQPOptionalConfigurationBuilder() *amqpOptionalConfigurationBuilder {
+	return &amqpOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (a *amqpOptionalConfigurationBuilder) Build() map[string]string {
+	return a.options
+}
+
+// Username adds a username to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) Username(username string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.Username] = username
+
+	return a
+}
+
+// Password adds a password to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) Password(password string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.Password] = password
+
+	return a
+}
+
+// ClientId adds the connection name reported to the broker to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) ClientId(clientId string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.ClientId] = clientId
+
+	return a
+}
+
+// Exchange adds the topic exchange the topics are mapped onto to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) Exchange(exchange string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.Exchange] = exchange
+
+	return a
+}
+
+// Durable adds the name of the durable queues, which keep the messages while the subscriber is down, to the optional
+// configuration properties.
+func (a *amqpOptionalConfigurationBuilder) Durable(durable string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.Durable] = durable
+
+	return a
+}
+
+// QueueGroup adds the name of the queues shared to load balance messages between subscribers to the optional
+// configuration properties.
+func (a *amqpOptionalConfigurationBuilder) QueueGroup(queueGroup string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.QueueGroup] = queueGroup
+
+	return a
+}
+
+// PublisherConfirms adds the flag to wait for the broker to confirm each published message to the optional
+// configuration properties.
+func (a *amqpOptionalConfigurationBuilder) PublisherConfirms(confirms bool) *amqpOptionalConfigurationBuilder {
+	a.options[internal.PublisherConfirms] = strconv.FormatBool(confirms)
+
+	return a
+}
+
+// Prefetch adds the number of deliveries a subscription may have unacknowledged, 0 for unlimited, to the optional
+// configuration properties.
+func (a *amqpOptionalConfigurationBuilder) Prefetch(count int) *amqpOptionalConfigurationBuilder {
+	a.options[internal.Prefetch] = strconv.Itoa(count)
+
+	return a
+}
+
+// ConnectTimeout adds the connect timeout, in seconds, to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) ConnectTimeout(timeout int) *amqpOptionalConfigurationBuilder {
+	a.options[internal.ConnectTimeout] = strconv.Itoa(timeout)
+
+	return a
+}
+
+// Format adds the name of the codec encoding the published envelopes, e.g. "json", "cbor", "msgpack", "protobuf" or
+// the name of a codec registered with codec.Register, to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) Format(format string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.Format] = format
+
+	return a
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (a *amqpOptionalConfigurationBuilder) RequestMode(mode string) *amqpOptionalConfigurationBuilder {
+	a.options[internal.RequestMode] = mode
+
+	return a
+}
@@ -0,0 +1,70 @@
+package amqp
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *amqpOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name:           "Username and Password",
+			builder:        NewAMQPOptionalConfigurationBuilder().Username("MyUser").Password("MyPassword"),
+			expectedValues: map[string]string{internal.Username: "MyUser", internal.Password: "MyPassword"},
+		},
+		{
+			name:           "ClientId",
+			builder:        NewAMQPOptionalConfigurationBuilder().ClientId("MyClient"),
+			expectedValues: map[string]string{internal.ClientId: "MyClient"},
+		},
+		{
+			name: "Routing and queues",
+			builder: NewAMQPOptionalConfigurationBuilder().
+				Exchange("edgex").
+				Durable("MyDurable").
+				QueueGroup("MyGroup"),
+			expectedValues: map[string]string{
+				internal.Exchange:   "edgex",
+				internal.Durable:    "MyDurable",
+				internal.QueueGroup: "MyGroup",
+			},
+		},
+		{
+			name: "Connection settings",
+			builder: NewAMQPOptionalConfigurationBuilder().
+				PublisherConfirms(false).
+				Prefetch(10).
+				ConnectTimeout(5),
+			expectedValues: map[string]string{
+				internal.PublisherConfirms: "false",
+				internal.Prefetch:          "10",
+				internal.ConnectTimeout:    "5",
+			},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewAMQPOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+		{
+			name:           "Format",
+			builder:        NewAMQPOptionalConfigurationBuilder().Format("cbor"),
+			expectedValues: map[string]string{internal.Format: "cbor"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+		})
+	}
+}
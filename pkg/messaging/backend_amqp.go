@@ -0,0 +1,15 @@
+//go:build !no_messagebus && !no_amqp
+// +build !no_messagebus,!no_amqp
+
+package messaging
+
+import (
+	"messaging/pkg/internal/amqp"
+	"messaging/pkg/types"
+)
+
+func init() {
+	RegisterBackend(AMQP, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return amqp.NewClient(msgConfig)
+	}))
+}
@@ -0,0 +1,16 @@
+//go:build !no_messagebus && !no_builtin
+// +build !no_messagebus,!no_builtin
+
+package messaging
+
+import (
+	"messaging/pkg/internal/builtin"
+	"messaging/pkg/types"
+)
+
+func init() {
+	// The Broker Port isn't needed for Unix sockets, so the Broker info is validated by the client
+	RegisterBackend(Builtin, func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return builtin.NewClient(msgConfig)
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !no_messagebus && !no_kafka
+// +build !no_messagebus,!no_kafka
+
+package messaging
+
+import (
+	"messaging/pkg/internal/kafka"
+	"messaging/pkg/types"
+)
+
+func init() {
+	RegisterBackend(Kafka, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return kafka.NewClient(msgConfig)
+	}))
+}
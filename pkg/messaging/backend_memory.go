@@ -0,0 +1,16 @@
+//go:build !no_messagebus && !no_memory
+// +build !no_messagebus,!no_memory
+
+package messaging
+
+import (
+	"messaging/pkg/internal/memory"
+	"messaging/pkg/types"
+)
+
+func init() {
+	// The in-process bus has no broker to connect to
+	RegisterBackend(Memory, func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return memory.NewClient(msgConfig)
+	})
+}
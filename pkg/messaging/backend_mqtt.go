@@ -0,0 +1,15 @@
+//go:build !no_messagebus && !no_mqtt
+// +build !no_messagebus,!no_mqtt
+
+package messaging
+
+import (
+	"messaging/pkg/internal/mqtt"
+	"messaging/pkg/types"
+)
+
+func init() {
+	RegisterBackend(MQTT, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return mqtt.NewMQTTClient(msgConfig)
+	}))
+}
@@ -0,0 +1,19 @@
+//go:build !no_messagebus && !no_nats
+// +build !no_messagebus,!no_nats
+
+package messaging
+
+import (
+	"messaging/pkg/internal/nats"
+	"messaging/pkg/internal/nats/jetstream"
+	"messaging/pkg/types"
+)
+
+func init() {
+	RegisterBackend(NatsCore, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return nats.NewClient(msgConfig)
+	}))
+	RegisterBackend(NatsJetStream, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return jetstream.NewClient(msgConfig)
+	}))
+}
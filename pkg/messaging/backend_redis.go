@@ -0,0 +1,18 @@
+//go:build !no_messagebus && !no_redis
+// +build !no_messagebus,!no_redis
+
+package messaging
+
+import (
+	"messaging/pkg/internal/redis"
+	"messaging/pkg/types"
+)
+
+func init() {
+	RegisterBackend(Redis, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return redis.NewClient(msgConfig)
+	}))
+	RegisterBackend(RedisStreams, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return redis.NewStreamsClient(msgConfig)
+	}))
+}
@@ -0,0 +1,17 @@
+//go:build !no_messagebus && !no_sqlite
+// +build !no_messagebus,!no_sqlite
+
+package messaging
+
+import (
+	"messaging/pkg/internal/sqlite"
+	"messaging/pkg/types"
+)
+
+func init() {
+	// The Broker Host is the path of the database file and there is no Port, so the Broker info is validated by the
+	// client
+	RegisterBackend(SQLite, func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return sqlite.NewClient(msgConfig)
+	})
+}
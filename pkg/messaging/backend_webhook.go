@@ -0,0 +1,16 @@
+//go:build !no_messagebus && !no_webhook
+// +build !no_messagebus,!no_webhook
+
+package messaging
+
+import (
+	"messaging/pkg/internal/webhook"
+	"messaging/pkg/types"
+)
+
+func init() {
+	// The Broker is only needed for the ingest endpoint, so publish only clients have none
+	RegisterBackend(Webhook, func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return webhook.NewClient(msgConfig)
+	})
+}
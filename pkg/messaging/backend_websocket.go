@@ -0,0 +1,15 @@
+//go:build !no_messagebus && !no_websocket
+// +build !no_messagebus,!no_websocket
+
+package messaging
+
+import (
+	"messaging/pkg/internal/websocket"
+	"messaging/pkg/types"
+)
+
+func init() {
+	RegisterBackend(WebSocket, requireBroker(func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return websocket.NewClient(msgConfig)
+	}))
+}
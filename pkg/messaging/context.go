@@ -0,0 +1,65 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+)
+
+// contextClient adapts a MessageClient which doesn't implement ContextMessageClient.
+type contextClient struct {
+	MessageClient
+}
+
+// WithContext returns the client as a ContextMessageClient, the client itself when it implements it. Otherwise the
+// client is adapted: PublishContext stops waiting for Publish once the context is done, SubscribeContext only checks
+// the context before subscribing, since a subscription created after giving up would be left behind, and the requests
+// are implemented with Subscribe, Publish and Unsubscribe.
+func WithContext(client MessageClient) ContextMessageClient {
+	if contextMessageClient, ok := client.(ContextMessageClient); ok {
+		return contextMessageClient
+	}
+
+	return &contextClient{MessageClient: client}
+}
+
+// RequestAll publishes the request with the client once and collects the responses of all the responders, see
+// ContextMessageClient.RequestAll, adapting the client with WithContext.
+func RequestAll(
+	ctx context.Context,
+	client MessageClient,
+	message types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string,
+	options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return WithContext(client).RequestAll(ctx, message, requestTopic, responseTopicPrefix, options)
+}
+
+// PublishContext is Publish which gives up waiting for it when the context is done.
+func (c *contextClient) PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error {
+	return internal.RunWithContext(ctx, func() error {
+		return c.Publish(message, topic)
+	})
+}
+
+// SubscribeContext is Subscribe which fails with the context's error when the context is already done.
+func (c *contextClient) SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Subscribe(topics, messageErrors)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *contextClient) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return internal.DoRequestContext(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestAll publishes the request once and collects the responses of all the responders.
+func (c *contextClient) RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error) {
+	return internal.DoRequestAll(ctx, c.SubscribeContext, c.Unsubscribe, c.PublishContext, message, requestTopic, responseTopicPrefix, options)
+}
@@ -0,0 +1,61 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainClient hides the context-aware operations of the client, as a client of an out-of-tree backend may lack them.
+type plainClient struct {
+	MessageClient
+}
+
+func TestWithContext(t *testing.T) {
+	bus := uuid.NewString()
+	client := newMemoryTestClient(t, bus)
+	assert.Same(t, client, WithContext(client))
+
+	requester := WithContext(plainClient{MessageClient: newMemoryTestClient(t, bus)})
+	require.IsType(t, &contextClient{}, requester)
+
+	responder := newMemoryTestClient(t, bus)
+	server, err := Serve(responder, "test/request", "test/response", func(_ context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+		return request.Payload, types.ContentTypeText, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	response, err := requester.RequestContext(ctx, types.NewMessageEnvelopeForRequest([]byte("one"), nil), "test/request", "test/response")
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(response.Payload))
+
+	responses, err := requester.RequestAll(ctx, types.NewMessageEnvelopeForRequest([]byte("all"), nil), "test/request", "test/response", types.RequestAllOptions{MaxResponses: 1})
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "all", string(responses[0].Payload))
+
+	// Also available to the callers holding a MessageClient
+	plain := plainClient{MessageClient: newMemoryTestClient(t, bus)}
+	responses, err = RequestAll(ctx, plain, types.NewMessageEnvelopeForRequest([]byte("plain"), nil), "test/request", "test/response", types.RequestAllOptions{MaxResponses: 1})
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "plain", string(responses[0].Payload))
+
+	done, cancelDone := context.WithCancel(context.Background())
+	cancelDone()
+	require.ErrorIs(t, requester.PublishContext(done, types.MessageEnvelope{}, "test/request"), context.Canceled)
+	topics := []types.TopicChannel{{Topic: "test/other", Messages: make(chan types.MessageEnvelope)}}
+	require.ErrorIs(t, requester.SubscribeContext(done, topics, make(chan error)), context.Canceled)
+}
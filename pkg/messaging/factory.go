@@ -21,11 +21,14 @@ package messaging
 
 import (
 	"fmt"
-	"messaging/pkg/internal/redis"
+	"messaging/pkg/internal"
 	"messaging/pkg/types"
 	"strings"
 )
 
+// Names of the built-in backends. Each one can be excluded from the build with its build tag, i.e. no_mqtt, no_redis,
+// no_nats, no_memory, no_builtin, no_amqp, no_kafka, no_webhook, no_websocket or no_sqlite, while no_messagebus excludes
+// the message bus entirely.
 const (
 	// MQTT messaging implementation
 	MQTT = "mqtt"
@@ -33,31 +36,63 @@ const (
 	// Redis Pub/Sub messaging implementation
 	Redis = "redis"
 
+	// RedisStreams messaging implementation using Redis Streams and consumer groups
+	RedisStreams = "redis-streams"
+
 	// NatsCore implementation
 	NatsCore = "nats-core"
 
 	// NatsJetStream implementation
 	NatsJetStream = "nats-jetstream"
+
+	// Memory in-process messaging implementation, clients with the same Broker Host share the same bus
+	Memory = "memory"
+
+	// Builtin messaging implementation connecting to the broker of the broker package, the Broker Protocol selects
+	// between "tcp" and "unix"
+	Builtin = "builtin"
+
+	// AMQP 0-9-1 messaging implementation, e.g. RabbitMQ, mapping the topics onto the routing keys of a topic exchange
+	AMQP = "amqp"
+
+	// Kafka messaging implementation, the topics are mapped onto Kafka topics and consumed with consumer groups
+	Kafka = "kafka"
+
+	// Webhook messaging implementation POSTing the messages to the webhooks configured per topic, the Broker is the
+	// address of the HTTP ingest endpoint receiving the messages
+	Webhook = "webhook"
+
+	// WebSocket messaging implementation connecting to the handler of the websocket package, the Broker Protocol
+	// selects between "ws" and "wss"
+	WebSocket = "websocket"
+
+	// SQLite durable messaging implementation without broker, the Broker Host is the path of the database file which
+	// the processes of the host share
+	SQLite = "sqlite"
 )
 
 // NewMessageClient is a factory function to instantiate different message client depending on
-// the "Type" from the configuration
+// the "Type" from the configuration, which is the name of a backend registered with RegisterBackend. The client
+// is wrapped with NewInboxClient when the RequestMode optional property is RequestModeInbox.
 func NewMessageClient(msgConfig types.MessageBusConfig) (MessageClient, error) {
+	factory, exists := LookupBackend(msgConfig.Type)
+	if !exists {
+		return nil, fmt.Errorf("unknown message type '%s' requested", msgConfig.Type)
+	}
 
-	if msgConfig.Broker.IsHostInfoEmpty() {
-		return nil, fmt.Errorf("unable to create messageClient: Broker info not set")
+	mode := strings.ToLower(strings.TrimSpace(msgConfig.Optional[internal.RequestMode]))
+	if mode != "" && mode != RequestModeSubscribe && mode != RequestModeInbox {
+		return nil, fmt.Errorf("unknown request mode '%s' requested", msgConfig.Optional[internal.RequestMode])
 	}
 
-	switch lowerMsgType := strings.ToLower(msgConfig.Type); lowerMsgType {
-	//case MQTT:
-	//	return mqtt.NewMQTTClient(msgConfig)
-	case Redis:
-		return redis.NewClient(msgConfig)
-	//case NatsCore:
-	//	return nats.NewClient(msgConfig)
-	//case NatsJetStream:
-	//	return jetstream.NewClient(msgConfig)
-	default:
-		return nil, fmt.Errorf("unknown message type '%s' requested", msgConfig.Type)
+	client, err := factory(msgConfig)
+	if err != nil {
+		return nil, err
 	}
+
+	if mode == RequestModeInbox {
+		return NewInboxClient(client), nil
+	}
+
+	return client, nil
 }
@@ -0,0 +1,84 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"messaging/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessageClient(t *testing.T) {
+	broker := types.HostInfo{Host: "localhost", Port: 6379, Protocol: "redis"}
+
+	tests := []struct {
+		name    string
+		config  types.MessageBusConfig
+		wantErr bool
+	}{
+		{"Redis", types.MessageBusConfig{Type: Redis, Broker: broker}, false},
+		{"Redis Streams", types.MessageBusConfig{Type: RedisStreams, Broker: broker}, false},
+		{"MQTT", types.MessageBusConfig{Type: MQTT, Broker: types.HostInfo{Host: "localhost", Port: 1883}}, false},
+		{"NATS Core", types.MessageBusConfig{Type: NatsCore, Broker: types.HostInfo{Host: "localhost", Port: 4222}}, false},
+		{"NATS JetStream", types.MessageBusConfig{Type: NatsJetStream, Broker: types.HostInfo{Host: "localhost", Port: 4222}}, false},
+		{"Memory without broker", types.MessageBusConfig{Type: Memory}, false},
+		{"Type is case insensitive", types.MessageBusConfig{Type: "MEMORY"}, false},
+		{"Builtin over TCP", types.MessageBusConfig{Type: Builtin, Broker: types.HostInfo{Host: "localhost", Port: 5563}}, false},
+		{"Builtin over Unix socket", types.MessageBusConfig{Type: Builtin, Broker: types.HostInfo{Host: "/tmp/broker.sock", Protocol: "unix"}}, false},
+		{"AMQP", types.MessageBusConfig{Type: AMQP, Broker: types.HostInfo{Host: "localhost", Port: 5672, Protocol: "amqp"}}, false},
+		{"Kafka", types.MessageBusConfig{Type: Kafka, Broker: types.HostInfo{Host: "localhost", Port: 9092, Protocol: "kafka"}}, false},
+		{"Webhook publish only", types.MessageBusConfig{Type: Webhook}, false},
+		{"WebSocket", types.MessageBusConfig{Type: WebSocket, Broker: types.HostInfo{Host: "localhost", Port: 8080, Protocol: "ws"}}, false},
+		{"SQLite without port", types.MessageBusConfig{Type: SQLite, Broker: types.HostInfo{Host: "/var/lib/edge/bus.db"}}, false},
+		{"Missing broker", types.MessageBusConfig{Type: Redis}, true},
+		{"Unknown type", types.MessageBusConfig{Type: "unknown", Broker: broker}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, exists := LookupBackend(tt.config.Type); !exists && !tt.wantErr {
+				t.Skipf("backend '%s' excluded from the build", tt.config.Type)
+			}
+
+			client, err := NewMessageClient(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, client)
+		})
+	}
+}
+
+func TestMemoryMessageClient(t *testing.T) {
+	if _, exists := LookupBackend(Memory); !exists {
+		t.Skip("memory backend excluded from the build")
+	}
+
+	config := types.MessageBusConfig{Type: Memory, Broker: types.HostInfo{Host: "factory-test"}}
+
+	publisher, err := NewMessageClient(config)
+	require.NoError(t, err)
+	subscriber, err := NewMessageClient(config)
+	require.NoError(t, err)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, subscriber.Subscribe([]types.TopicChannel{{Topic: "test/#", Messages: messages}}, make(chan error)))
+	require.NoError(t, publisher.Publish(types.MessageEnvelope{CorrelationID: "123"}, "test/topic"))
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, "test/topic", message.ReceivedTopic)
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for message")
+	}
+
+	require.NoError(t, subscriber.Disconnect())
+	require.NoError(t, publisher.Disconnect())
+}
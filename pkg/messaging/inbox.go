@@ -0,0 +1,60 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"time"
+)
+
+// Request modes selected with the RequestMode optional configuration property.
+const (
+	// RequestModeSubscribe subscribes to the response topic of each request and unsubscribes once responded, which is
+	// the default
+	RequestModeSubscribe = "subscribe"
+
+	// RequestModeInbox keeps one subscription to <prefix>/# per response topic prefix for all the requests, see
+	// NewInboxClient
+	RequestModeInbox = "inbox"
+)
+
+// inboxClient is a MessageClient whose requests wait for their response with a reply inbox.
+type inboxClient struct {
+	ContextMessageClient
+	inbox *internal.ReplyInbox
+}
+
+// NewInboxClient wraps the client so that its requests share one long-lived subscription to <prefix>/# per response
+// topic prefix, rather than subscribing to and unsubscribing from the response topic of each request. The responses
+// are routed to the waiting requests by RequestID and the others are dropped, so the response topic prefix should be
+// unique to the client to avoid receiving the responses to the requests of other clients. The subscriptions are
+// removed when the client is disconnected.
+func NewInboxClient(client MessageClient) MessageClient {
+	contextClient := WithContext(client)
+	return &inboxClient{
+		ContextMessageClient: contextClient,
+		inbox:                internal.NewReplyInbox(contextClient.SubscribeContext, contextClient.Unsubscribe, contextClient.PublishContext),
+	}
+}
+
+// Request publishes the request and waits for its response on the subscription of the response topic prefix.
+func (c *inboxClient) Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return c.RequestContext(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// RequestContext is Request which waits for the response until the context is done.
+func (c *inboxClient) RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error) {
+	return c.inbox.Request(ctx, message, requestTopic, responseTopicPrefix)
+}
+
+// Disconnect removes the subscriptions of the inbox and disconnects the client.
+func (c *inboxClient) Disconnect() error {
+	return errors.Join(c.inbox.Close(), c.ContextMessageClient.Disconnect())
+}
@@ -0,0 +1,92 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInboxClient(t *testing.T) {
+	bus := uuid.NewString()
+	responder := newMemoryTestClient(t, bus)
+
+	server, err := Serve(responder, "test/request", "test/response/requester", func(_ context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+		return request.Payload, types.ContentTypeText, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	requester, err := NewMessageClient(types.MessageBusConfig{
+		Type:     Memory,
+		Broker:   types.HostInfo{Host: bus},
+		Optional: map[string]string{internal.RequestMode: "Inbox"},
+	})
+	require.NoError(t, err)
+	require.IsType(t, &inboxClient{}, requester)
+	require.NoError(t, requester.Connect())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(payload string) {
+			defer wg.Done()
+
+			request := types.NewMessageEnvelopeForRequest([]byte(payload), nil)
+			response, err := requester.Request(request, "test/request", "test/response/requester", time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, request.RequestID, response.RequestID)
+			assert.Equal(t, payload, string(response.Payload))
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	// Requests to a topic nobody responds to time out, and their late responses are dropped
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = WithContext(requester).RequestContext(ctx, types.NewMessageEnvelopeForRequest(nil, nil), "test/unhandled", "test/response/requester")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, requester.Disconnect())
+}
+
+func TestNewMessageClientRequestMode(t *testing.T) {
+	if _, exists := LookupBackend(Memory); !exists {
+		t.Skip("memory backend excluded from the build")
+	}
+
+	tests := []struct {
+		name      string
+		mode      string
+		wantInbox bool
+		wantErr   bool
+	}{
+		{"Default", "", false, false},
+		{"Subscribe", RequestModeSubscribe, false, false},
+		{"Inbox", RequestModeInbox, true, false},
+		{"Unknown", "unknown", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewMessageClient(types.MessageBusConfig{Type: Memory, Optional: map[string]string{internal.RequestMode: tt.mode}})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			_, isInbox := client.(*inboxClient)
+			assert.Equal(t, tt.wantInbox, isInbox)
+		})
+	}
+}
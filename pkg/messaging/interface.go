@@ -17,6 +17,7 @@
 package messaging
 
 import (
+	"context"
 	"messaging/pkg/types"
 	"time"
 )
@@ -44,6 +45,7 @@ type MessageClient interface {
 	// then subscribes to a response topic which contains the RequestID. Once the response is received, the
 	// response topic is unsubscribed and the response data is returned. If no response is received within
 	// the timeout period, a timed out  error returned.
+	// Use RequestAll to collect the responses of several responders instead.
 	Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error)
 
 	// Unsubscribe to unsubscribe from the specified topics.
@@ -53,3 +55,28 @@ type MessageClient interface {
 	// and TopicChannel will also be closed
 	Disconnect() error
 }
+
+// ContextMessageClient is a MessageClient whose operations are bounded by a context. It is kept apart from
+// MessageClient so that the implementations of the out-of-tree backends don't have to implement it, the clients of
+// the built-in backends all do. Use WithContext to get it from any MessageClient.
+type ContextMessageClient interface {
+	MessageClient
+
+	// PublishContext is Publish which gives up when the context is done, returning the context's error
+	PublishContext(ctx context.Context, message types.MessageEnvelope, topic string) error
+
+	// SubscribeContext is Subscribe which gives up creating the subscriptions when the context is done, returning the
+	// context's error. The context only bounds creating the subscriptions, use Unsubscribe to remove them.
+	SubscribeContext(ctx context.Context, topics []types.TopicChannel, messageErrors chan error) error
+
+	// RequestContext is Request which waits for the response until the context is done rather than a timeout. The
+	// error returned when the context is done wraps the context's error.
+	RequestContext(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string) (*types.MessageEnvelope, error)
+
+	// RequestAll publishes a request containing a RequestID to the specified topic once and collects the responses
+	// of every responder published to the response topic which contains the RequestID, each with its ReceivedTopic.
+	// It completes after options.MaxResponses responses, after options.QuietPeriod without a new response or when
+	// the context is done. When the context's deadline is reached first, the responses received so far are returned
+	// along with a *types.RequestTimeoutErr.
+	RequestAll(ctx context.Context, message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, options types.RequestAllOptions) ([]types.MessageEnvelope, error)
+}
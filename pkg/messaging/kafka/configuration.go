@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+)
+
+type kafkaOptionalConfigurationBuilder struct {
+	options map[string]string
+}
+
+// NewKafkaOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewKafkaOptionalConfigurationBuilder() *kafkaOptionalConfigurationBuilder {
+	return &kafkaOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (k *kafkaOptionalConfigurationBuilder) Build() map[string]string {
+	return k.options
+}
+
+// Username adds the SASL PLAIN username to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) Username(username string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.Username] = username
+
+	return k
+}
+
+// Password adds the SASL PLAIN password to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) Password(password string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.Password] = password
+
+	return k
+}
+
+// ClientId adds the client identifier, which is also the consumer group when no QueueGroup is set, to the optional
+// configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) ClientId(clientId string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.ClientId] = clientId
+
+	return k
+}
+
+// QueueGroup adds the consumer group shared to load balance messages between subscribers to the optional
+// configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) QueueGroup(queueGroup string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.QueueGroup] = queueGroup
+
+	return k
+}
+
+// Deliver adds where new consumer groups start, "new" or "all", to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) Deliver(deliver string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.Deliver] = deliver
+
+	return k
+}
+
+// PartitionKey adds the envelope field used as partition key, "CorrelationID", "RequestID" or "QueryParams.<name>",
+// to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) PartitionKey(partitionKey string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.PartitionKey] = partitionKey
+
+	return k
+}
+
+// AutoProvision adds the flag to create the topics when missing to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) AutoProvision(autoProvision bool) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.AutoProvision] = strconv.FormatBool(autoProvision)
+
+	return k
+}
+
+// ConnectTimeout adds the connect timeout, in seconds, to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) ConnectTimeout(timeout int) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.ConnectTimeout] = strconv.Itoa(timeout)
+
+	return k
+}
+
+// Format adds the name of the codec encoding the published envelopes, e.g. "json", "cbor", "msgpack", "protobuf" or
+// the name of a codec registered with codec.Register, to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) Format(format string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.Format] = format
+
+	return k
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (k *kafkaOptionalConfigurationBuilder) RequestMode(mode string) *kafkaOptionalConfigurationBuilder {
+	k.options[internal.RequestMode] = mode
+
+	return k
+}
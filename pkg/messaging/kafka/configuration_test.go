@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *kafkaOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name:           "Username and Password",
+			builder:        NewKafkaOptionalConfigurationBuilder().Username("MyUser").Password("MyPassword"),
+			expectedValues: map[string]string{internal.Username: "MyUser", internal.Password: "MyPassword"},
+		},
+		{
+			name:           "ClientId",
+			builder:        NewKafkaOptionalConfigurationBuilder().ClientId("MyClient"),
+			expectedValues: map[string]string{internal.ClientId: "MyClient"},
+		},
+		{
+			name: "Consumer groups",
+			builder: NewKafkaOptionalConfigurationBuilder().
+				QueueGroup("MyGroup").
+				Deliver("all"),
+			expectedValues: map[string]string{
+				internal.QueueGroup: "MyGroup",
+				internal.Deliver:    "all",
+			},
+		},
+		{
+			name: "Producer settings",
+			builder: NewKafkaOptionalConfigurationBuilder().
+				PartitionKey("QueryParams.deviceName").
+				AutoProvision(false).
+				ConnectTimeout(5),
+			expectedValues: map[string]string{
+				internal.PartitionKey:   "QueryParams.deviceName",
+				internal.AutoProvision:  "false",
+				internal.ConnectTimeout: "5",
+			},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewKafkaOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+		{
+			name:           "Format",
+			builder:        NewKafkaOptionalConfigurationBuilder().Format("cbor"),
+			expectedValues: map[string]string{internal.Format: "cbor"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package mqtt
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+)
+
+type mqttOptionalConfigurationBuilder struct {
+	options map[string]string
+}
+
+// NewMQTTOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewMQTTOptionalConfigurationBuilder() *mqttOptionalConfigurationBuilder {
+	return &mqttOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (m *mqttOptionalConfigurationBuilder) Build() map[string]string {
+	return m.options
+}
+
+// Username adds a username to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) Username(username string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.Username] = username
+
+	return m
+}
+
+// Password adds a password to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) Password(password string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.Password] = password
+
+	return m
+}
+
+// ClientId adds the client identifier to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) ClientId(clientId string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.ClientId] = clientId
+
+	return m
+}
+
+// Qos adds the quality of service level (0, 1 or 2) to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) Qos(qos int) *mqttOptionalConfigurationBuilder {
+	m.options[internal.Qos] = strconv.Itoa(qos)
+
+	return m
+}
+
+// KeepAlive adds the keep alive interval, in seconds, to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) KeepAlive(keepAlive int) *mqttOptionalConfigurationBuilder {
+	m.options[internal.KeepAlive] = strconv.Itoa(keepAlive)
+
+	return m
+}
+
+// Retained adds the retained flag used when publishing to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) Retained(retained bool) *mqttOptionalConfigurationBuilder {
+	m.options[internal.Retained] = strconv.FormatBool(retained)
+
+	return m
+}
+
+// CleanSession adds the clean session flag to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) CleanSession(cleanSession bool) *mqttOptionalConfigurationBuilder {
+	m.options[internal.CleanSession] = strconv.FormatBool(cleanSession)
+
+	return m
+}
+
+// ConnectTimeout adds the connect timeout, in seconds, to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) ConnectTimeout(timeout int) *mqttOptionalConfigurationBuilder {
+	m.options[internal.ConnectTimeout] = strconv.Itoa(timeout)
+
+	return m
+}
+
+// AutoReconnect adds the auto reconnect flag to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) AutoReconnect(autoReconnect bool) *mqttOptionalConfigurationBuilder {
+	m.options[internal.AutoReconnect] = strconv.FormatBool(autoReconnect)
+
+	return m
+}
+
+// SkipCertVerify adds the flag to skip the verification of the server certificate to the optional configuration
+// properties.
+func (m *mqttOptionalConfigurationBuilder) SkipCertVerify(skipCertVerify bool) *mqttOptionalConfigurationBuilder {
+	m.options[internal.SkipCertVerify] = strconv.FormatBool(skipCertVerify)
+
+	return m
+}
+
+// CertFile adds the client certificate file to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) CertFile(certFile string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.CertFile] = certFile
+
+	return m
+}
+
+// KeyFile adds the client private key file to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) KeyFile(keyFile string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.KeyFile] = keyFile
+
+	return m
+}
+
+// CaFile adds the CA certificate file to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) CaFile(caFile string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.CaFile] = caFile
+
+	return m
+}
+
+// Format adds the name of the codec encoding the published envelopes, e.g. "json", "cbor", "msgpack", "protobuf" or
+// the name of a codec registered with codec.Register, to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) Format(format string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.Format] = format
+
+	return m
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (m *mqttOptionalConfigurationBuilder) RequestMode(mode string) *mqttOptionalConfigurationBuilder {
+	m.options[internal.RequestMode] = mode
+
+	return m
+}
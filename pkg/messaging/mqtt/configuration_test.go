@@ -0,0 +1,84 @@
+package mqtt
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *mqttOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name:           "Username",
+			builder:        NewMQTTOptionalConfigurationBuilder().Username("MyUser"),
+			expectedValues: map[string]string{internal.Username: "MyUser"},
+		},
+		{
+			name:           "Password",
+			builder:        NewMQTTOptionalConfigurationBuilder().Password("MyPassword"),
+			expectedValues: map[string]string{internal.Password: "MyPassword"},
+		},
+		{
+			name:           "ClientId",
+			builder:        NewMQTTOptionalConfigurationBuilder().ClientId("MyClient"),
+			expectedValues: map[string]string{internal.ClientId: "MyClient"},
+		},
+		{
+			name: "Connection settings",
+			builder: NewMQTTOptionalConfigurationBuilder().
+				Qos(2).
+				KeepAlive(10).
+				Retained(true).
+				CleanSession(false).
+				ConnectTimeout(5).
+				AutoReconnect(true),
+			expectedValues: map[string]string{
+				internal.Qos:            "2",
+				internal.KeepAlive:      "10",
+				internal.Retained:       "true",
+				internal.CleanSession:   "false",
+				internal.ConnectTimeout: "5",
+				internal.AutoReconnect:  "true",
+			},
+		},
+		{
+			name: "TLS settings",
+			builder: NewMQTTOptionalConfigurationBuilder().
+				SkipCertVerify(true).
+				CertFile("cert.pem").
+				KeyFile("key.pem").
+				CaFile("ca.pem"),
+			expectedValues: map[string]string{
+				internal.SkipCertVerify: "true",
+				internal.CertFile:       "cert.pem",
+				internal.KeyFile:        "key.pem",
+				internal.CaFile:         "ca.pem",
+			},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewMQTTOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+		{
+			name:           "Format",
+			builder:        NewMQTTOptionalConfigurationBuilder().Format("cbor"),
+			expectedValues: map[string]string{internal.Format: "cbor"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+
+		})
+	}
+}
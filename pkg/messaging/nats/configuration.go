@@ -0,0 +1,119 @@
+package nats
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+)
+
+type natsOptionalConfigurationBuilder struct {
+	options map[string]string
+}
+
+// NewNatsOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewNatsOptionalConfigurationBuilder() *natsOptionalConfigurationBuilder {
+	return &natsOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (n *natsOptionalConfigurationBuilder) Build() map[string]string {
+	return n.options
+}
+
+// Username adds a username to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) Username(username string) *natsOptionalConfigurationBuilder {
+	n.options[internal.Username] = username
+
+	return n
+}
+
+// Password adds a password to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) Password(password string) *natsOptionalConfigurationBuilder {
+	n.options[internal.Password] = password
+
+	return n
+}
+
+// ClientId adds the client name reported to the NATS server to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) ClientId(clientId string) *natsOptionalConfigurationBuilder {
+	n.options[internal.ClientId] = clientId
+
+	return n
+}
+
+// Format adds the message format, "nats" or the name of a codec such as "json", "cbor" or "msgpack", to the optional
+// configuration properties.
+func (n *natsOptionalConfigurationBuilder) Format(format string) *natsOptionalConfigurationBuilder {
+	n.options[internal.Format] = format
+
+	return n
+}
+
+// ConnectTimeout adds the connect timeout, in seconds, to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) ConnectTimeout(timeout int) *natsOptionalConfigurationBuilder {
+	n.options[internal.ConnectTimeout] = strconv.Itoa(timeout)
+
+	return n
+}
+
+// RetryOnFailedConnect adds the flag to keep retrying the initial connection to the optional configuration
+// properties.
+func (n *natsOptionalConfigurationBuilder) RetryOnFailedConnect(retry bool) *natsOptionalConfigurationBuilder {
+	n.options[internal.RetryOnFailedConnect] = strconv.FormatBool(retry)
+
+	return n
+}
+
+// QueueGroup adds the queue group used to load balance messages between subscribers to the optional configuration
+// properties.
+func (n *natsOptionalConfigurationBuilder) QueueGroup(queueGroup string) *natsOptionalConfigurationBuilder {
+	n.options[internal.QueueGroup] = queueGroup
+
+	return n
+}
+
+// Durable adds the JetStream durable consumer name to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) Durable(durable string) *natsOptionalConfigurationBuilder {
+	n.options[internal.Durable] = durable
+
+	return n
+}
+
+// Subject adds the topic captured by the auto provisioned JetStream stream to the optional configuration
+// properties, for example "edgex/#".
+func (n *natsOptionalConfigurationBuilder) Subject(subject string) *natsOptionalConfigurationBuilder {
+	n.options[internal.Subject] = subject
+
+	return n
+}
+
+// AutoProvision adds the flag to create the JetStream stream when missing to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) AutoProvision(autoProvision bool) *natsOptionalConfigurationBuilder {
+	n.options[internal.AutoProvision] = strconv.FormatBool(autoProvision)
+
+	return n
+}
+
+// Deliver adds the JetStream deliver policy, "all", "new" or "last", to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) Deliver(deliver string) *natsOptionalConfigurationBuilder {
+	n.options[internal.Deliver] = deliver
+
+	return n
+}
+
+// DefaultPubRetryAttempts adds the number of JetStream publish retries to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) DefaultPubRetryAttempts(attempts int) *natsOptionalConfigurationBuilder {
+	n.options[internal.DefaultPubRetryAttempts] = strconv.Itoa(attempts)
+
+	return n
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (n *natsOptionalConfigurationBuilder) RequestMode(mode string) *natsOptionalConfigurationBuilder {
+	n.options[internal.RequestMode] = mode
+
+	return n
+}
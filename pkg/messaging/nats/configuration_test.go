@@ -0,0 +1,72 @@
+package nats
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *natsOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name:           "Username and Password",
+			builder:        NewNatsOptionalConfigurationBuilder().Username("MyUser").Password("MyPassword"),
+			expectedValues: map[string]string{internal.Username: "MyUser", internal.Password: "MyPassword"},
+		},
+		{
+			name:           "ClientId",
+			builder:        NewNatsOptionalConfigurationBuilder().ClientId("MyClient"),
+			expectedValues: map[string]string{internal.ClientId: "MyClient"},
+		},
+		{
+			name: "Connection settings",
+			builder: NewNatsOptionalConfigurationBuilder().
+				Format("nats").
+				ConnectTimeout(5).
+				RetryOnFailedConnect(true).
+				QueueGroup("MyGroup"),
+			expectedValues: map[string]string{
+				internal.Format:               "nats",
+				internal.ConnectTimeout:       "5",
+				internal.RetryOnFailedConnect: "true",
+				internal.QueueGroup:           "MyGroup",
+			},
+		},
+		{
+			name: "JetStream settings",
+			builder: NewNatsOptionalConfigurationBuilder().
+				Durable("MyDurable").
+				Subject("edgex/#").
+				AutoProvision(true).
+				Deliver("all").
+				DefaultPubRetryAttempts(3),
+			expectedValues: map[string]string{
+				internal.Durable:                 "MyDurable",
+				internal.Subject:                 "edgex/#",
+				internal.AutoProvision:           "true",
+				internal.Deliver:                 "all",
+				internal.DefaultPubRetryAttempts: "3",
+			},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewNatsOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+
+		})
+	}
+}
@@ -2,6 +2,8 @@ package redis
 
 import (
 	"messaging/pkg/internal"
+	"strconv"
+	"strings"
 )
 
 type redisOptionalConfigurationBuilder struct {
@@ -27,3 +29,80 @@ func (r *redisOptionalConfigurationBuilder) Password(password string) *redisOpti
 
 	return r
 }
+
+// ClientId adds the Redis Streams consumer name to the optional configuration properties. Without a QueueGroup it is
+// also used as the consumer group so that the client resumes where it left off after a restart.
+func (r *redisOptionalConfigurationBuilder) ClientId(clientId string) *redisOptionalConfigurationBuilder {
+	r.options[internal.ClientId] = clientId
+
+	return r
+}
+
+// QueueGroup adds the Redis Streams consumer group to the optional configuration properties. Clients of the same group
+// compete for the messages.
+func (r *redisOptionalConfigurationBuilder) QueueGroup(queueGroup string) *redisOptionalConfigurationBuilder {
+	r.options[internal.QueueGroup] = queueGroup
+
+	return r
+}
+
+// MaxLen adds the approximate maximum number of entries kept per Redis stream to the optional configuration
+// properties.
+func (r *redisOptionalConfigurationBuilder) MaxLen(maxLen int) *redisOptionalConfigurationBuilder {
+	r.options[internal.MaxLen] = strconv.Itoa(maxLen)
+
+	return r
+}
+
+// ClaimMinIdle adds the number of seconds after which the pending Redis Streams entries of another consumer are
+// reclaimed to the optional configuration properties.
+func (r *redisOptionalConfigurationBuilder) ClaimMinIdle(seconds int) *redisOptionalConfigurationBuilder {
+	r.options[internal.ClaimMinIdle] = strconv.Itoa(seconds)
+
+	return r
+}
+
+// SentinelAddrs adds the host:port addresses of the Redis Sentinel nodes to the optional configuration properties.
+// The master is then resolved through the sentinels and MasterName must be set as well.
+func (r *redisOptionalConfigurationBuilder) SentinelAddrs(addrs ...string) *redisOptionalConfigurationBuilder {
+	r.options[internal.SentinelAddrs] = strings.Join(addrs, ",")
+
+	return r
+}
+
+// MasterName adds the name of the master monitored by Redis Sentinel to the optional configuration properties.
+func (r *redisOptionalConfigurationBuilder) MasterName(masterName string) *redisOptionalConfigurationBuilder {
+	r.options[internal.MasterName] = masterName
+
+	return r
+}
+
+// SentinelPassword adds the password of the Redis Sentinel nodes to the optional configuration properties.
+func (r *redisOptionalConfigurationBuilder) SentinelPassword(password string) *redisOptionalConfigurationBuilder {
+	r.options[internal.SentinelPassword] = password
+
+	return r
+}
+
+// ClusterAddrs adds the host:port addresses of the Redis Cluster seed nodes to the optional configuration properties.
+func (r *redisOptionalConfigurationBuilder) ClusterAddrs(addrs ...string) *redisOptionalConfigurationBuilder {
+	r.options[internal.ClusterAddrs] = strings.Join(addrs, ",")
+
+	return r
+}
+
+// Format adds the name of the codec encoding the published envelopes, e.g. "json", "cbor", "msgpack", "protobuf" or
+// the name of a codec registered with codec.Register, to the optional configuration properties.
+func (r *redisOptionalConfigurationBuilder) Format(format string) *redisOptionalConfigurationBuilder {
+	r.options[internal.Format] = format
+
+	return r
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (r *redisOptionalConfigurationBuilder) RequestMode(mode string) *redisOptionalConfigurationBuilder {
+	r.options[internal.RequestMode] = mode
+
+	return r
+}
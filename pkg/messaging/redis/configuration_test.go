@@ -18,6 +18,56 @@ func TestBuilderMethods(t *testing.T) {
 			builder:        NewRedisOptionalConfigurationBuilder().Password("MyPassword"),
 			expectedValues: map[string]string{internal.Password: "MyPassword"},
 		},
+		{
+			name:           "ClientId",
+			builder:        NewRedisOptionalConfigurationBuilder().ClientId("MyClientID"),
+			expectedValues: map[string]string{internal.ClientId: "MyClientID"},
+		},
+		{
+			name:           "QueueGroup",
+			builder:        NewRedisOptionalConfigurationBuilder().QueueGroup("MyGroup"),
+			expectedValues: map[string]string{internal.QueueGroup: "MyGroup"},
+		},
+		{
+			name:           "MaxLen",
+			builder:        NewRedisOptionalConfigurationBuilder().MaxLen(1000),
+			expectedValues: map[string]string{internal.MaxLen: "1000"},
+		},
+		{
+			name:           "ClaimMinIdle",
+			builder:        NewRedisOptionalConfigurationBuilder().ClaimMinIdle(60),
+			expectedValues: map[string]string{internal.ClaimMinIdle: "60"},
+		},
+		{
+			name:           "SentinelAddrs",
+			builder:        NewRedisOptionalConfigurationBuilder().SentinelAddrs("sentinel1:26379", "sentinel2:26379"),
+			expectedValues: map[string]string{internal.SentinelAddrs: "sentinel1:26379,sentinel2:26379"},
+		},
+		{
+			name:           "MasterName",
+			builder:        NewRedisOptionalConfigurationBuilder().MasterName("mymaster"),
+			expectedValues: map[string]string{internal.MasterName: "mymaster"},
+		},
+		{
+			name:           "SentinelPassword",
+			builder:        NewRedisOptionalConfigurationBuilder().SentinelPassword("secret"),
+			expectedValues: map[string]string{internal.SentinelPassword: "secret"},
+		},
+		{
+			name:           "ClusterAddrs",
+			builder:        NewRedisOptionalConfigurationBuilder().ClusterAddrs("node1:6379", "node2:6379"),
+			expectedValues: map[string]string{internal.ClusterAddrs: "node1:6379,node2:6379"},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewRedisOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+		{
+			name:           "Format",
+			builder:        NewRedisOptionalConfigurationBuilder().Format("cbor"),
+			expectedValues: map[string]string{internal.Format: "cbor"},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -0,0 +1,77 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"fmt"
+	"messaging/pkg/types"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BackendFactory creates the MessageClient of a backend from the MessageBus configuration.
+type BackendFactory func(msgConfig types.MessageBusConfig) (MessageClient, error)
+
+var (
+	backends      = make(map[string]BackendFactory)
+	backendsMutex sync.RWMutex
+)
+
+// RegisterBackend makes a backend available to NewMessageClient under the provided name, which is matched case
+// insensitively against MessageBusConfig.Type. It is meant to be called from the init function of the package
+// implementing the backend and panics if the name is empty, already registered or the factory is nil.
+//
+// The factory is responsible for validating the configuration, including the Broker info if the backend needs one.
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+
+	name = strings.ToLower(name)
+	if name == "" {
+		panic("messaging: RegisterBackend name is empty")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("messaging: RegisterBackend factory is nil for backend '%s'", name))
+	}
+	if _, exists := backends[name]; exists {
+		panic(fmt.Sprintf("messaging: RegisterBackend called twice for backend '%s'", name))
+	}
+
+	backends[name] = factory
+}
+
+// LookupBackend returns the factory registered for the backend name, the name is case insensitive.
+func LookupBackend(name string) (BackendFactory, bool) {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	factory, exists := backends[strings.ToLower(name)]
+	return factory, exists
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// requireBroker wraps the factory of a backend which connects to a broker so that the Broker info is validated first.
+func requireBroker(factory BackendFactory) BackendFactory {
+	return func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		if msgConfig.Broker.IsHostInfoEmpty() {
+			return nil, fmt.Errorf("unable to create messageClient: Broker info not set")
+		}
+
+		return factory(msgConfig)
+	}
+}
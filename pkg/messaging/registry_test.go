@@ -0,0 +1,60 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"errors"
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterBackend(t *testing.T) {
+	expectedErr := errors.New("custom backend")
+	RegisterBackend("Custom-Test", func(msgConfig types.MessageBusConfig) (MessageClient, error) {
+		return nil, expectedErr
+	})
+
+	factory, exists := LookupBackend("custom-test")
+	require.True(t, exists)
+	_, err := factory(types.MessageBusConfig{})
+	assert.Equal(t, expectedErr, err)
+
+	_, err = NewMessageClient(types.MessageBusConfig{Type: "CUSTOM-TEST"})
+	assert.Equal(t, expectedErr, err)
+
+	assert.Contains(t, Backends(), "custom-test")
+}
+
+func TestRegisterBackendPanics(t *testing.T) {
+	factory := func(msgConfig types.MessageBusConfig) (MessageClient, error) { return nil, nil }
+	RegisterBackend("duplicate-test", factory)
+
+	tests := []struct {
+		name        string
+		backendName string
+		factory     BackendFactory
+	}{
+		{"Empty name", "", factory},
+		{"Nil factory", "nil-factory-test", nil},
+		{"Duplicate", "Duplicate-Test", factory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Panics(t, func() { RegisterBackend(tt.backendName, tt.factory) })
+		})
+	}
+}
+
+func TestLookupBackendUnknown(t *testing.T) {
+	_, exists := LookupBackend("unknown")
+	assert.False(t, exists)
+}
+
+func TestBackendsSorted(t *testing.T) {
+	names := Backends()
+	assert.IsIncreasing(t, names)
+}
@@ -0,0 +1,375 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxConcurrency is the number of requests a Server handles at once when MaxConcurrency isn't set.
+const DefaultMaxConcurrency = 10
+
+// cancelledCapacity is the number of cancel notices a Server remembers for the requests it hasn't handled yet.
+const cancelledCapacity = 256
+
+// RequestHandler handles a request received by a Server and returns the payload of the response along with its
+// content type, types.ContentTypeJSON being used when empty. When an error is returned the error response created by
+// types.NewMessageEnvelopeFromError is sent instead, so wrapping e.g. types.ErrNotFound sets its error code.
+type RequestHandler func(ctx context.Context, request types.MessageEnvelope) (payload []byte, contentType string, err error)
+
+// StreamHandler handles a request received by a Server by sending the chunks of its response to the stream. The stream
+// is closed once the handler returns, or failed with the error returned.
+type StreamHandler func(ctx context.Context, request types.MessageEnvelope, stream *ResponseStream) error
+
+// Server is the responder side of the request-reply pattern implemented by MessageClient.Request. It handles the
+// requests received on RequestTopic and publishes the responses to <ResponseTopicPrefix>/<RequestID>, which is where
+// the requester waits for them. When the requester abandons a request it publishes a cancel notice carrying its
+// RequestID to <ResponseTopicPrefix>/cancel, the context given to the handler of the request is then cancelled and no
+// response is published. The same goes once the Deadline of the request is reached, and the requests already expired
+// when received aren't handled.
+type Server struct {
+	// Client is the connected MessageClient the requests are received and the responses published with.
+	Client MessageClient
+	// RequestTopic is the topic the requests are received on, the MQTT '+' and '#' wildcards are supported.
+	RequestTopic string
+	// ResponseTopicPrefix is the prefix of the topics the responses are published to.
+	ResponseTopicPrefix string
+	// Handler handles the requests, concurrently up to MaxConcurrency of them.
+	Handler RequestHandler
+	// StreamHandler handles the requests with streamed responses instead of Handler, only one of them can be set.
+	StreamHandler StreamHandler
+	// MaxConcurrency is the maximum number of requests handled at once, DefaultMaxConcurrency when not positive.
+	// Further requests wait in the subscription until a handler completes.
+	MaxConcurrency int
+	// OnError is called with the errors which can't be reported to the requester, such as subscription errors,
+	// requests without RequestID or failures to publish a response. The errors are dropped when nil.
+	OnError func(err error)
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	messages chan types.MessageEnvelope
+	notices  chan types.MessageEnvelope
+	errors   chan error
+	handlers sync.WaitGroup
+	done     chan struct{}
+	mutex    sync.Mutex
+
+	// requests holds the cancel functions of the requests being handled, and cancelled the RequestIDs of the last
+	// cancel notices received for requests not handled yet
+	requests       map[string]context.CancelFunc
+	cancelled      map[string]struct{}
+	cancelledOrder []string
+	requestsMutex  sync.Mutex
+}
+
+// Serve starts a Server handling the requests received on the request topic with the handler and publishing the
+// responses to the topics starting with the response topic prefix. Use Stop to stop it.
+func Serve(client MessageClient, requestTopic string, responseTopicPrefix string, handler RequestHandler) (*Server, error) {
+	server := &Server{
+		Client:              client,
+		RequestTopic:        requestTopic,
+		ResponseTopicPrefix: responseTopicPrefix,
+		Handler:             handler,
+	}
+
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// ServeStream starts a Server handling the requests received on the request topic with the stream handler, which
+// streams the responses to the topics starting with the response topic prefix. Use Stop to stop it.
+func ServeStream(client MessageClient, requestTopic string, responseTopicPrefix string, handler StreamHandler) (*Server, error) {
+	server := &Server{
+		Client:              client,
+		RequestTopic:        requestTopic,
+		ResponseTopicPrefix: responseTopicPrefix,
+		StreamHandler:       handler,
+	}
+
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// Start subscribes to the request topic and starts handling the requests.
+func (s *Server) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.done != nil {
+		return errors.New("server already started")
+	}
+
+	if s.Client == nil {
+		return errors.New("server requires a Client")
+	}
+
+	if (s.Handler == nil) == (s.StreamHandler == nil) {
+		return errors.New("server requires either a Handler or a StreamHandler")
+	}
+
+	if strings.TrimSpace(s.ResponseTopicPrefix) == "" {
+		return errors.New("server requires a ResponseTopicPrefix")
+	}
+
+	concurrency := s.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultMaxConcurrency
+	}
+
+	s.messages = make(chan types.MessageEnvelope, concurrency)
+	s.notices = make(chan types.MessageEnvelope, concurrency)
+	s.errors = make(chan error, 1)
+	s.requests = make(map[string]context.CancelFunc)
+	s.cancelled = make(map[string]struct{})
+	s.cancelledOrder = nil
+
+	topics := []types.TopicChannel{
+		{Topic: s.RequestTopic, Messages: s.messages},
+		{Topic: s.cancelTopic(), Messages: s.notices},
+	}
+	if err := s.Client.Subscribe(topics, s.errors); err != nil {
+		return fmt.Errorf("unable to subscribe to the '%s' request topic: %w", s.RequestTopic, err)
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	go s.dispatch(make(chan struct{}, concurrency))
+
+	return nil
+}
+
+// Stop unsubscribes from the request topic and waits for the requests being handled to complete and their responses
+// to be published.
+func (s *Server) Stop() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.done == nil {
+		return nil
+	}
+
+	err := s.Client.Unsubscribe(s.RequestTopic, s.cancelTopic())
+
+	// No more messages are sent to the channels once unsubscribed
+	close(s.messages)
+	close(s.notices)
+	close(s.errors)
+	<-s.done
+
+	s.cancel()
+	s.done = nil
+
+	return err
+}
+
+// dispatch handles the requests received until the subscription is stopped, the slots bounding how many are handled
+// at once.
+func (s *Server) dispatch(slots chan struct{}) {
+	defer close(s.done)
+
+	go func() {
+		for err := range s.errors {
+			s.reportError(fmt.Errorf("request subscription error: %w", err))
+		}
+	}()
+
+	go func() {
+		for notice := range s.notices {
+			s.cancelRequest(notice.RequestID)
+		}
+	}()
+
+	for request := range s.messages {
+		slots <- struct{}{}
+		s.handlers.Add(1)
+		go func(request types.MessageEnvelope) {
+			defer func() {
+				<-slots
+				s.handlers.Done()
+			}()
+
+			s.handle(request)
+		}(request)
+	}
+
+	s.handlers.Wait()
+}
+
+// handle calls the handler for the request and publishes its response.
+func (s *Server) handle(request types.MessageEnvelope) {
+	if strings.TrimSpace(request.RequestID) == "" {
+		s.reportError(fmt.Errorf("request received on '%s' without RequestID can't be responded to", request.ReceivedTopic))
+		return
+	}
+
+	if request.Expired() {
+		s.reportError(fmt.Errorf("request %s received on '%s' expired before being handled", request.RequestID, request.ReceivedTopic))
+		return
+	}
+
+	ctx, done := s.startRequest(request)
+	defer done()
+
+	// The request was cancelled while waiting to be handled
+	if ctx.Err() != nil {
+		return
+	}
+
+	if s.StreamHandler != nil {
+		s.stream(ctx, request)
+		return
+	}
+
+	response := s.response(ctx, request)
+
+	// Nobody waits for the response of a cancelled or expired request
+	if ctx.Err() != nil && s.ctx.Err() == nil {
+		return
+	}
+
+	responseTopic := strings.Join([]string{s.ResponseTopicPrefix, request.RequestID}, "/")
+	if err := WithContext(s.Client).PublishContext(s.ctx, response, responseTopic); err != nil {
+		s.reportError(fmt.Errorf("unable to publish the response to '%s': %w", responseTopic, err))
+	}
+}
+
+// startRequest returns the context of the request, which is cancelled by its cancel notice or once its Deadline is
+// reached, and the function to call once the request is handled.
+func (s *Server) startRequest(request types.MessageEnvelope) (context.Context, func()) {
+	requestID := request.RequestID
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	if deadline, ok := request.DeadlineTime(); ok {
+		ctx, cancel = context.WithDeadline(s.ctx, deadline)
+	}
+
+	s.requestsMutex.Lock()
+	defer s.requestsMutex.Unlock()
+
+	if _, cancelled := s.cancelled[requestID]; cancelled {
+		cancel()
+	} else {
+		s.requests[requestID] = cancel
+	}
+
+	return ctx, func() {
+		s.requestsMutex.Lock()
+		delete(s.requests, requestID)
+		s.requestsMutex.Unlock()
+		cancel()
+	}
+}
+
+// cancelRequest cancels the context of the request, or remembers the request is cancelled when it isn't handled yet.
+func (s *Server) cancelRequest(requestID string) {
+	s.requestsMutex.Lock()
+	defer s.requestsMutex.Unlock()
+
+	if cancel, exists := s.requests[requestID]; exists {
+		cancel()
+		return
+	}
+
+	if _, exists := s.cancelled[requestID]; exists || requestID == "" {
+		return
+	}
+
+	if len(s.cancelledOrder) == cancelledCapacity {
+		delete(s.cancelled, s.cancelledOrder[0])
+		s.cancelledOrder = s.cancelledOrder[1:]
+	}
+	s.cancelled[requestID] = struct{}{}
+	s.cancelledOrder = append(s.cancelledOrder, requestID)
+}
+
+func (s *Server) cancelTopic() string {
+	return internal.CancelTopic(s.ResponseTopicPrefix)
+}
+
+// response calls the handler and builds the response, or the error response when the handler fails or panics.
+func (s *Server) response(ctx context.Context, request types.MessageEnvelope) (response types.MessageEnvelope) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			response = errorResponse(request, fmt.Errorf("request handler panicked: %v", recovered))
+		}
+	}()
+
+	payload, contentType, err := s.Handler(ctx, request)
+	if err != nil {
+		return errorResponse(request, err)
+	}
+
+	if contentType == "" {
+		contentType = types.ContentTypeJSON
+	}
+
+	// Built directly rather than with types.NewMessageEnvelopeForResponse, which only accepts UUIDs, since any
+	// non-empty RequestID is responded to
+	return types.MessageEnvelope{
+		CorrelationID: request.CorrelationID,
+		ApiVersion:    types.ApiVersion,
+		RequestID:     request.RequestID,
+		Payload:       payload,
+		ContentType:   contentType,
+		QueryParams:   make(map[string]string),
+	}
+}
+
+// stream calls the stream handler and ends the stream, with an error response when the handler fails or panics.
+func (s *Server) stream(ctx context.Context, request types.MessageEnvelope) {
+	stream := NewResponseStream(s.Client, request, s.ResponseTopicPrefix)
+
+	err := func() (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("stream handler panicked: %v", recovered)
+			}
+		}()
+
+		return s.StreamHandler(ctx, request, stream)
+	}()
+
+	// Nobody reads the rest of the stream of a cancelled or expired request
+	if ctx.Err() != nil && s.ctx.Err() == nil {
+		return
+	}
+
+	if err != nil {
+		err = stream.Fail(s.ctx, err)
+	} else {
+		err = stream.Close(s.ctx)
+	}
+
+	if err != nil {
+		s.reportError(err)
+	}
+}
+
+func (s *Server) reportError(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// errorResponse creates the error response to the request, keeping its CorrelationID when set.
+func errorResponse(request types.MessageEnvelope, err error) types.MessageEnvelope {
+	response := types.NewMessageEnvelopeFromError(request.RequestID, err)
+	if request.CorrelationID != "" {
+		response.CorrelationID = request.CorrelationID
+	}
+
+	return response
+}
@@ -0,0 +1,381 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"messaging/pkg/types"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryTestClient(t *testing.T, bus string) MessageClient {
+	if _, exists := LookupBackend(Memory); !exists {
+		t.Skip("memory backend excluded from the build")
+	}
+
+	client, err := NewMessageClient(types.MessageBusConfig{Type: Memory, Broker: types.HostInfo{Host: bus}})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func TestServe(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newMemoryTestClient(t, bus)
+	responder := newMemoryTestClient(t, bus)
+
+	server, err := Serve(responder, "test/request/#", "test/response", func(_ context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+		switch string(request.Payload) {
+		case "fail":
+			return nil, "", errors.New("handler failed")
+		case "panic":
+			panic("handler panicked")
+		}
+
+		return append([]byte("echo "), request.Payload...), types.ContentTypeText, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	tests := []struct {
+		name              string
+		payload           string
+		expectedErrorCode int
+		expectedPayload   string
+	}{
+		{"Response", "hello", 0, "echo hello"},
+		{"Handler error", "fail", 1, "handler failed"},
+		{"Handler panic", "panic", 1, "request handler panicked: handler panicked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := types.NewMessageEnvelopeForRequest([]byte(tt.payload), nil)
+			response, err := requester.Request(request, "test/request/echo", "test/response", time.Second)
+			require.NoError(t, err)
+
+			assert.Equal(t, request.RequestID, response.RequestID)
+			assert.Equal(t, request.CorrelationID, response.CorrelationID)
+			assert.Equal(t, tt.expectedErrorCode, response.ErrorCode)
+			assert.Equal(t, tt.expectedPayload, string(response.Payload))
+		})
+	}
+}
+
+func TestServeNonUUIDIdentifiers(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newMemoryTestClient(t, bus)
+	responder := newMemoryTestClient(t, bus)
+
+	server, err := Serve(responder, "test/request", "test/response", func(_ context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+		return request.Payload, types.ContentTypeText, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	tests := []struct {
+		name          string
+		requestID     string
+		correlationID string
+	}{
+		{"Empty CorrelationID", "req-1", ""},
+		{"Caller chosen identifiers", "abc-123", "correlation-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := types.NewMessageEnvelope([]byte("hello"), context.Background())
+			request.RequestID = tt.requestID
+			request.CorrelationID = tt.correlationID
+
+			response, err := requester.Request(request, "test/request", "test/response", time.Second)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.requestID, response.RequestID)
+			assert.Equal(t, tt.correlationID, response.CorrelationID)
+			assert.Zero(t, response.ErrorCode)
+			assert.Equal(t, "hello", string(response.Payload))
+			assert.Equal(t, types.ContentTypeText, response.ContentType)
+		})
+	}
+}
+
+func TestServerMaxConcurrency(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newMemoryTestClient(t, bus)
+	responder := newMemoryTestClient(t, bus)
+
+	var running, maxRunning int32
+	server := &Server{
+		Client:              responder,
+		RequestTopic:        "test/request",
+		ResponseTopicPrefix: "test/response",
+		MaxConcurrency:      2,
+		Handler: func(_ context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+
+			for {
+				previous := atomic.LoadInt32(&maxRunning)
+				if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			return request.Payload, "", nil
+		},
+	}
+	require.NoError(t, server.Start())
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := requester.Request(types.NewMessageEnvelopeForRequest([]byte("{}"), nil), "test/request", "test/response", 5*time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, types.ContentTypeJSON, response.ContentType)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestServerStopWaitsForHandlers(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newMemoryTestClient(t, bus)
+	responder := newMemoryTestClient(t, bus)
+
+	started := make(chan struct{})
+	server, err := Serve(responder, "test/request", "test/response", func(ctx context.Context, _ types.MessageEnvelope) ([]byte, string, error) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		return nil, "", ctx.Err()
+	})
+	require.NoError(t, err)
+
+	responses := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, requester.Subscribe([]types.TopicChannel{{Topic: "test/response/#", Messages: responses}}, make(chan error)))
+
+	request := types.NewMessageEnvelopeForRequest(nil, nil)
+	require.NoError(t, requester.Publish(request, "test/request"))
+
+	<-started
+	require.NoError(t, server.Stop())
+
+	// The response of the request being handled is still published, the handler's context isn't cancelled until then
+	select {
+	case response := <-responses:
+		assert.Equal(t, request.RequestID, response.RequestID)
+		assert.Equal(t, 0, response.ErrorCode)
+	case <-time.After(time.Second):
+		require.Fail(t, "response not published")
+	}
+
+	// No more requests are handled once stopped
+	require.NoError(t, requester.Publish(types.NewMessageEnvelopeForRequest(nil, nil), "test/request"))
+	select {
+	case <-responses:
+		require.Fail(t, "request handled after Stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServerInvalid(t *testing.T) {
+	client := newMemoryTestClient(t, uuid.NewString())
+	handler := func(context.Context, types.MessageEnvelope) ([]byte, string, error) { return nil, "", nil }
+
+	_, err := Serve(nil, "test/request", "test/response", handler)
+	assert.Error(t, err)
+	_, err = Serve(client, "test/request", "test/response", nil)
+	assert.Error(t, err)
+	_, err = Serve(client, "test/request", "", handler)
+	assert.Error(t, err)
+	_, err = Serve(client, "test/#/invalid", "test/response", handler)
+	assert.Error(t, err)
+
+	reported := make(chan error, 1)
+	server := &Server{Client: client, RequestTopic: "test/request", ResponseTopicPrefix: "test/response", Handler: handler,
+		OnError: func(err error) { reported <- err }}
+	require.NoError(t, server.Start())
+	assert.Error(t, server.Start(), "already started")
+
+	// Requests without RequestID can't be responded to
+	require.NoError(t, client.Publish(types.MessageEnvelope{}, "test/request"))
+	select {
+	case err := <-reported:
+		assert.Contains(t, err.Error(), "without RequestID")
+	case <-time.After(time.Second):
+		require.Fail(t, "error not reported")
+	}
+
+	require.NoError(t, server.Stop())
+	require.NoError(t, server.Stop())
+}
+
+func TestRequestAll(t *testing.T) {
+	bus := uuid.NewString()
+	requester := WithContext(newMemoryTestClient(t, bus))
+
+	for _, name := range []string{"device-a", "device-b", "device-c"} {
+		name := name
+		server, err := Serve(newMemoryTestClient(t, bus), "test/request", "test/response", func(context.Context, types.MessageEnvelope) ([]byte, string, error) {
+			return []byte(name), types.ContentTypeText, nil
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = server.Stop() })
+	}
+
+	t.Run("Max responses", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		request := types.NewMessageEnvelopeForRequest(nil, nil)
+		responses, err := requester.RequestAll(ctx, request, "test/request", "test/response", types.RequestAllOptions{MaxResponses: 3})
+		require.NoError(t, err)
+
+		var names []string
+		for _, response := range responses {
+			assert.Equal(t, request.RequestID, response.RequestID)
+			assert.Equal(t, "test/response/"+request.RequestID, response.ReceivedTopic)
+			names = append(names, string(response.Payload))
+		}
+		assert.ElementsMatch(t, []string{"device-a", "device-b", "device-c"}, names)
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		responses, err := requester.RequestAll(ctx, types.NewMessageEnvelopeForRequest(nil, nil), "test/request", "test/response", types.RequestAllOptions{MaxResponses: 4})
+		var timeoutErr *types.RequestTimeoutErr
+		require.ErrorAs(t, err, &timeoutErr)
+		assert.Equal(t, 3, timeoutErr.Received)
+		assert.Len(t, responses, 3)
+	})
+}
+
+func TestServerCancellation(t *testing.T) {
+	bus := uuid.NewString()
+	requester := WithContext(newMemoryTestClient(t, bus))
+	responder := newMemoryTestClient(t, bus)
+
+	started := make(chan string, 2)
+	handled := make(chan error, 2)
+	server := &Server{
+		Client:              responder,
+		RequestTopic:        "test/request",
+		ResponseTopicPrefix: "test/response",
+		MaxConcurrency:      1,
+		Handler: func(ctx context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+			started <- request.RequestID
+			select {
+			case <-ctx.Done():
+				handled <- ctx.Err()
+			case <-time.After(time.Second):
+				handled <- nil
+			}
+			return nil, "", ctx.Err()
+		},
+	}
+	require.NoError(t, server.Start())
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	responses := make(chan types.MessageEnvelope, 4)
+	require.NoError(t, requester.Subscribe([]types.TopicChannel{{Topic: "test/response/+", Messages: responses}}, make(chan error)))
+
+	// The first request occupies the only handler while the second one waits
+	first := types.NewMessageEnvelopeForRequest(nil, nil)
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstResult := make(chan error, 1)
+	go func() {
+		_, err := requester.RequestContext(firstCtx, first, "test/request", "test/response")
+		firstResult <- err
+	}()
+	require.Equal(t, first.RequestID, <-started)
+
+	second := types.NewMessageEnvelopeForRequest(nil, nil)
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	secondResult := make(chan error, 1)
+	go func() {
+		_, err := requester.RequestContext(secondCtx, second, "test/request", "test/response")
+		secondResult <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The cancel notices are received in order, so the second request is known cancelled once the first is
+	cancelSecond()
+	require.ErrorIs(t, <-secondResult, context.Canceled)
+	cancelFirst()
+	require.ErrorIs(t, <-firstResult, context.Canceled)
+
+	select {
+	case err := <-handled:
+		require.ErrorIs(t, err, context.Canceled, "handler context cancelled by the cancel notice")
+	case <-time.After(time.Second):
+		require.Fail(t, "handler context not cancelled")
+	}
+
+	// The cancelled request waiting to be handled isn't handled, and no response is published. The subscription
+	// also receives the cancel notices, which share the response topic prefix.
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case requestID := <-started:
+			require.Failf(t, "cancelled request handled", "request %s", requestID)
+		case response := <-responses:
+			if response.ReceivedTopic != "test/response/cancel" {
+				require.Failf(t, "response published for a cancelled request", "request %s", response.RequestID)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func TestServerDeadline(t *testing.T) {
+	bus := uuid.NewString()
+	requester := WithContext(newMemoryTestClient(t, bus))
+	responder := newMemoryTestClient(t, bus)
+
+	deadlines := make(chan time.Time, 1)
+	server, err := Serve(responder, "test/request", "test/response", func(ctx context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "handler context without deadline")
+		deadlines <- deadline
+		return nil, types.ContentTypeJSON, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	expected, _ := ctx.Deadline()
+
+	_, err = requester.RequestContext(ctx, types.NewMessageEnvelopeForRequest(nil, nil), "test/request", "test/response")
+	require.NoError(t, err)
+	assert.Equal(t, expected.UnixMilli(), (<-deadlines).UnixMilli(), "remaining budget of the requester")
+
+	// An expired request isn't handled
+	expired := types.NewMessageEnvelopeForRequest(nil, nil)
+	expired.Deadline = time.Now().Add(-time.Second).UnixMilli()
+	require.NoError(t, requester.Publish(expired, "test/request"))
+
+	select {
+	case <-deadlines:
+		require.Fail(t, "expired request handled")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
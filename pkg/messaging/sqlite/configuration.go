@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+)
+
+type sqliteOptionalConfigurationBuilder struct {
+	options map[string]string
+}
+
+// NewSQLiteOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewSQLiteOptionalConfigurationBuilder() *sqliteOptionalConfigurationBuilder {
+	return &sqliteOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (s *sqliteOptionalConfigurationBuilder) Build() map[string]string {
+	return s.options
+}
+
+// ClientId adds the name the cursors are persisted under when no QueueGroup is set, so that the client resumes where
+// it left off after a restart, to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) ClientId(clientId string) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.ClientId] = clientId
+
+	return s
+}
+
+// QueueGroup adds the name of the cursors shared by competing consumers to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) QueueGroup(queueGroup string) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.QueueGroup] = queueGroup
+
+	return s
+}
+
+// Deliver adds where new cursors start, "new" or "all", to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) Deliver(deliver string) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.Deliver] = deliver
+
+	return s
+}
+
+// MaxLen adds the maximum number of messages kept per topic to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) MaxLen(maxLen int) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.MaxLen] = strconv.Itoa(maxLen)
+
+	return s
+}
+
+// MaxAge adds the number of seconds a message is kept to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) MaxAge(seconds int) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.MaxAge] = strconv.Itoa(seconds)
+
+	return s
+}
+
+// PollInterval adds the number of milliseconds between the polls of the database to the optional configuration
+// properties.
+func (s *sqliteOptionalConfigurationBuilder) PollInterval(milliseconds int) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.PollInterval] = strconv.Itoa(milliseconds)
+
+	return s
+}
+
+// Format adds the name of the codec encoding the published envelopes, e.g. "json", "cbor", "msgpack", "protobuf" or
+// the name of a codec registered with codec.Register, to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) Format(format string) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.Format] = format
+
+	return s
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (s *sqliteOptionalConfigurationBuilder) RequestMode(mode string) *sqliteOptionalConfigurationBuilder {
+	s.options[internal.RequestMode] = mode
+
+	return s
+}
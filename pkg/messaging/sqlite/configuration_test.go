@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *sqliteOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name: "Cursors",
+			builder: NewSQLiteOptionalConfigurationBuilder().
+				ClientId("service").
+				QueueGroup("workers").
+				Deliver("all"),
+			expectedValues: map[string]string{
+				internal.ClientId:   "service",
+				internal.QueueGroup: "workers",
+				internal.Deliver:    "all",
+			},
+		},
+		{
+			name: "Retention",
+			builder: NewSQLiteOptionalConfigurationBuilder().
+				MaxLen(1000).
+				MaxAge(3600),
+			expectedValues: map[string]string{
+				internal.MaxLen: "1000",
+				internal.MaxAge: "3600",
+			},
+		},
+		{
+			name:           "PollInterval",
+			builder:        NewSQLiteOptionalConfigurationBuilder().PollInterval(50),
+			expectedValues: map[string]string{internal.PollInterval: "50"},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewSQLiteOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+		{
+			name:           "Format",
+			builder:        NewSQLiteOptionalConfigurationBuilder().Format("cbor"),
+			expectedValues: map[string]string{internal.Format: "cbor"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxPendingChunks is the number of chunks received ahead of a missing one which a ResponseStreamReader holds
+// when StreamOptions.MaxPendingChunks isn't set.
+const DefaultMaxPendingChunks = 64
+
+// StreamOptions defines how a ResponseStreamReader waits for the chunks of a streamed response. The whole stream is
+// bounded by the context given to RequestStream.
+type StreamOptions struct {
+	// ChunkTimeout is the maximum time waited for the next chunk, no limit when not positive.
+	ChunkTimeout time.Duration
+	// MaxPendingChunks is the number of chunks received ahead of a missing one after which the stream fails with a
+	// *StreamGapErr, DefaultMaxPendingChunks when not positive.
+	MaxPendingChunks int
+}
+
+// StreamGapErr is returned by a ResponseStreamReader when a chunk of the stream is missing, i.e. later chunks were
+// received but the missing one didn't arrive in time.
+type StreamGapErr struct {
+	// ResponseTopic is the topic the chunks are received on.
+	ResponseTopic string
+	// Missing is the sequence number of the missing chunk.
+	Missing int
+}
+
+func (e *StreamGapErr) Error() string {
+	return fmt.Sprintf("chunk %d of the response stream on %s topic is missing", e.Missing, e.ResponseTopic)
+}
+
+// ResponseStream is the responder side of a streamed response. Each envelope sent to <prefix>/<RequestID> carries
+// its position in the stream as Sequence, starting at 1, and the last one is marked with EndOfStream.
+type ResponseStream struct {
+	client   ContextMessageClient
+	request  types.MessageEnvelope
+	topic    string
+	sequence int
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// NewResponseStream creates the stream responding to the request on the topic starting with the response topic
+// prefix.
+func NewResponseStream(client MessageClient, request types.MessageEnvelope, responseTopicPrefix string) *ResponseStream {
+	return &ResponseStream{
+		client:  WithContext(client),
+		request: request,
+		topic:   strings.Join([]string{responseTopicPrefix, request.RequestID}, "/"),
+	}
+}
+
+// Send publishes the next chunk of the stream, types.ContentTypeJSON being used when the content type is empty.
+func (s *ResponseStream) Send(ctx context.Context, payload []byte, contentType string) error {
+	if contentType == "" {
+		contentType = types.ContentTypeJSON
+	}
+
+	return s.publish(ctx, types.MessageEnvelope{
+		CorrelationID: s.request.CorrelationID,
+		ApiVersion:    types.ApiVersion,
+		RequestID:     s.request.RequestID,
+		Payload:       payload,
+		ContentType:   contentType,
+		QueryParams:   make(map[string]string),
+	}, false)
+}
+
+// Close ends the stream by publishing the end-of-stream marker. Closing a stream already ended does nothing.
+func (s *ResponseStream) Close(ctx context.Context) error {
+	return s.publish(ctx, types.MessageEnvelope{
+		CorrelationID: s.request.CorrelationID,
+		ApiVersion:    types.ApiVersion,
+		RequestID:     s.request.RequestID,
+		ContentType:   types.ContentTypeJSON,
+		QueryParams:   make(map[string]string),
+	}, true)
+}
+
+// Fail ends the stream with an error response containing the error message, which the requester receives as the last
+// envelope of the stream.
+func (s *ResponseStream) Fail(ctx context.Context, err error) error {
+	return s.publish(ctx, errorResponse(s.request, err), true)
+}
+
+func (s *ResponseStream) publish(ctx context.Context, envelope types.MessageEnvelope, end bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		if end {
+			return nil
+		}
+		return errors.New("response stream already ended")
+	}
+
+	s.sequence++
+	envelope.Sequence = s.sequence
+	envelope.EndOfStream = end
+
+	if err := s.client.PublishContext(ctx, envelope, s.topic); err != nil {
+		return fmt.Errorf("unable to publish chunk %d of the response stream to '%s': %w", s.sequence, s.topic, err)
+	}
+
+	s.closed = end
+	return nil
+}
+
+// ResponseStreamReader is the requester side of a streamed response, returning its chunks in order.
+type ResponseStreamReader struct {
+	ctx      context.Context
+	client   ContextMessageClient
+	request  types.MessageEnvelope
+	prefix   string
+	topic    string
+	options  StreamOptions
+	messages chan types.MessageEnvelope
+	errors   chan error
+
+	next    int
+	pending map[int]types.MessageEnvelope
+	err     error
+	once    sync.Once
+}
+
+// RequestStream publishes a request containing a RequestID to the specified topic and returns the reader of the
+// response streamed to <responseTopicPrefix>/<RequestID>. The context bounds the whole stream, the reader must be
+// read until it returns an error or be closed.
+func RequestStream(
+	ctx context.Context,
+	client MessageClient,
+	message types.MessageEnvelope,
+	requestTopic string,
+	responseTopicPrefix string,
+	options StreamOptions) (*ResponseStreamReader, error) {
+	if len(strings.TrimSpace(message.RequestID)) == 0 {
+		message.RequestID = uuid.NewString()
+	}
+	message = internal.WithDeadline(ctx, message)
+
+	if options.MaxPendingChunks <= 0 {
+		options.MaxPendingChunks = DefaultMaxPendingChunks
+	}
+
+	reader := &ResponseStreamReader{
+		ctx:      ctx,
+		client:   WithContext(client),
+		request:  message,
+		prefix:   responseTopicPrefix,
+		topic:    strings.Join([]string{responseTopicPrefix, message.RequestID}, "/"),
+		options:  options,
+		messages: make(chan types.MessageEnvelope, options.MaxPendingChunks),
+		errors:   make(chan error, 1),
+		next:     1,
+		pending:  make(map[int]types.MessageEnvelope),
+	}
+
+	// Must create the subscription first so that it is in place when the request is handled and chunks published back
+	topics := []types.TopicChannel{{Topic: reader.topic, Messages: reader.messages}}
+	if err := reader.client.SubscribeContext(internal.WithResponseSubscription(ctx), topics, reader.errors); err != nil {
+		return nil, fmt.Errorf("unable to create response subscription: %w", err)
+	}
+
+	if err := reader.client.PublishContext(ctx, message, requestTopic); err != nil {
+		err = fmt.Errorf("unable to create publish request to %s: %w", requestTopic, err)
+		reader.err = err
+		_ = reader.Close()
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// Next returns the next chunk of the stream, or io.EOF once the stream ended. The end-of-stream marker itself isn't
+// returned unless it is an error response. A response which isn't streamed is returned as the only chunk. When a chunk
+// is missing a *StreamGapErr is returned, and when the chunk timeout or the context's deadline is reached first the
+// error wraps context.DeadlineExceeded. The reader is closed once an error is returned.
+func (r *ResponseStreamReader) Next() (*types.MessageEnvelope, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var timeout <-chan time.Time
+	if r.options.ChunkTimeout > 0 {
+		timer := time.NewTimer(r.options.ChunkTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		if chunk, exists := r.pending[r.next]; exists {
+			delete(r.pending, r.next)
+			r.next++
+
+			if !chunk.EndOfStream {
+				return &chunk, nil
+			}
+
+			r.fail(io.EOF)
+			if chunk.ErrorCode != 0 {
+				return &chunk, nil
+			}
+			return nil, io.EOF
+		}
+
+		select {
+		case <-r.ctx.Done():
+			if errors.Is(r.ctx.Err(), context.DeadlineExceeded) {
+				return nil, r.fail(fmt.Errorf("timed out waiting for the response stream on %s topic: %w", r.topic, r.ctx.Err()))
+			}
+			return nil, r.fail(fmt.Errorf("response stream on %s topic cancelled: %w", r.topic, r.ctx.Err()))
+
+		case <-timeout:
+			if len(r.pending) > 0 {
+				return nil, r.fail(&StreamGapErr{ResponseTopic: r.topic, Missing: r.next})
+			}
+			return nil, r.fail(fmt.Errorf("timed out waiting for chunk %d of the response stream on %s topic: %w",
+				r.next, r.topic, context.DeadlineExceeded))
+
+		case err := <-r.errors:
+			return nil, r.fail(fmt.Errorf("encountered error waiting for the response stream on %s topic: %v", r.topic, err))
+
+		case chunk := <-r.messages:
+			if chunk.Sequence == 0 {
+				// Not a streamed response, e.g. sent by a Server without StreamHandler
+				r.fail(io.EOF)
+				return &chunk, nil
+			}
+
+			// Duplicated chunks are dropped
+			if chunk.Sequence >= r.next {
+				r.pending[chunk.Sequence] = chunk
+			}
+
+			if len(r.pending) > r.options.MaxPendingChunks {
+				return nil, r.fail(&StreamGapErr{ResponseTopic: r.topic, Missing: r.next})
+			}
+		}
+	}
+}
+
+// Close removes the subscription of the response topic. Closing the reader before the stream ended notifies the
+// responder that the request is abandoned with a cancel notice. Next returns io.EOF once closed.
+func (r *ResponseStreamReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		if r.err == nil {
+			internal.PublishCancel(r.client.PublishContext, r.request, r.prefix)
+		}
+
+		// The channels are left open, Next no longer reads them once closed and the backend may still be sending to them
+		err = r.client.Unsubscribe(r.topic)
+	})
+
+	if r.err == nil {
+		r.err = io.EOF
+	}
+
+	return err
+}
+
+// fail closes the reader, Next returning the error from then on. The responder is notified with a cancel notice
+// unless the stream ended.
+func (r *ResponseStreamReader) fail(err error) error {
+	if err == io.EOF {
+		r.err = err
+	}
+	_ = r.Close()
+	r.err = err
+
+	return err
+}
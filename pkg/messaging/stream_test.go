@@ -0,0 +1,186 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"messaging/pkg/types"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readStream reads the chunks of the stream until it returns an error.
+func readStream(reader *ResponseStreamReader) ([]types.MessageEnvelope, error) {
+	var chunks []types.MessageEnvelope
+	for {
+		chunk, err := reader.Next()
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, *chunk)
+	}
+}
+
+func TestServeStream(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newMemoryTestClient(t, bus)
+	responder := newMemoryTestClient(t, bus)
+
+	server, err := ServeStream(responder, "test/request", "test/response", func(ctx context.Context, request types.MessageEnvelope, stream *ResponseStream) error {
+		count, _ := strconv.Atoi(string(request.Payload))
+		for i := 1; i <= count; i++ {
+			if err := stream.Send(ctx, []byte(strconv.Itoa(i)), types.ContentTypeText); err != nil {
+				return err
+			}
+		}
+
+		if count == 0 {
+			return errors.New("nothing to stream")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, server.Stop()) }()
+
+	t.Run("Chunks in order", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		request := types.NewMessageEnvelopeForRequest([]byte("20"), nil)
+		reader, err := RequestStream(ctx, requester, request, "test/request", "test/response", StreamOptions{})
+		require.NoError(t, err)
+
+		chunks, err := readStream(reader)
+		require.ErrorIs(t, err, io.EOF)
+		require.Len(t, chunks, 20)
+		for i, chunk := range chunks {
+			assert.Equal(t, i+1, chunk.Sequence)
+			assert.Equal(t, strconv.Itoa(i+1), string(chunk.Payload))
+			assert.Equal(t, request.RequestID, chunk.RequestID)
+			assert.Equal(t, request.CorrelationID, chunk.CorrelationID)
+		}
+	})
+
+	t.Run("Handler error", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		reader, err := RequestStream(ctx, requester, types.NewMessageEnvelopeForRequest([]byte("0"), nil), "test/request", "test/response", StreamOptions{})
+		require.NoError(t, err)
+
+		chunks, err := readStream(reader)
+		require.ErrorIs(t, err, io.EOF)
+		require.Len(t, chunks, 1)
+		assert.Equal(t, 1, chunks[0].ErrorCode)
+		assert.Equal(t, "nothing to stream", string(chunks[0].Payload))
+	})
+}
+
+func TestResponseStreamReader(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newMemoryTestClient(t, bus)
+	responder := newMemoryTestClient(t, bus)
+
+	// Responds to the requests with the chunks of the sequence numbers, the last one ending the stream when end is set
+	respond := func(t *testing.T, sequences []int, end bool) {
+		requests := make(chan types.MessageEnvelope, 1)
+		require.NoError(t, responder.Subscribe([]types.TopicChannel{{Topic: "test/request", Messages: requests}}, make(chan error, 1)))
+		t.Cleanup(func() { _ = responder.Unsubscribe("test/request") })
+
+		go func() {
+			request := <-requests
+			for i, sequence := range sequences {
+				chunk := types.MessageEnvelope{RequestID: request.RequestID, Payload: []byte(strconv.Itoa(sequence)), Sequence: sequence}
+				chunk.EndOfStream = end && i == len(sequences)-1
+				_ = responder.Publish(chunk, "test/response/"+request.RequestID)
+			}
+		}()
+	}
+
+	tests := []struct {
+		name           string
+		sequences      []int
+		end            bool
+		expectedChunks int
+		expectedErr    error
+		expectGap      bool
+	}{
+		{"Reordered", []int{2, 1, 4, 3, 5}, true, 4, io.EOF, false},
+		{"Duplicated", []int{1, 1, 2, 3}, true, 2, io.EOF, false},
+		{"Gap", []int{1, 3, 4}, false, 1, nil, true},
+		{"Chunk timeout", []int{1, 2}, false, 2, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respond(t, tt.sequences, tt.end)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			reader, err := RequestStream(ctx, requester, types.NewMessageEnvelopeForRequest(nil, nil), "test/request", "test/response",
+				StreamOptions{ChunkTimeout: 50 * time.Millisecond})
+			require.NoError(t, err)
+
+			chunks, err := readStream(reader)
+			require.Len(t, chunks, tt.expectedChunks)
+			for i, chunk := range chunks {
+				assert.Equal(t, i+1, chunk.Sequence)
+			}
+
+			if tt.expectGap {
+				var gapErr *StreamGapErr
+				require.ErrorAs(t, err, &gapErr)
+				assert.Equal(t, 2, gapErr.Missing)
+				return
+			}
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+
+	t.Run("Not streamed", func(t *testing.T) {
+		server, err := Serve(responder, "test/request", "test/response", func(context.Context, types.MessageEnvelope) ([]byte, string, error) {
+			return []byte("single"), types.ContentTypeText, nil
+		})
+		require.NoError(t, err)
+		defer func() { require.NoError(t, server.Stop()) }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		reader, err := RequestStream(ctx, requester, types.NewMessageEnvelopeForRequest(nil, nil), "test/request", "test/response", StreamOptions{})
+		require.NoError(t, err)
+
+		chunks, err := readStream(reader)
+		require.ErrorIs(t, err, io.EOF)
+		require.Len(t, chunks, 1)
+		assert.Equal(t, "single", string(chunks[0].Payload))
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		reader, err := RequestStream(context.Background(), requester, types.NewMessageEnvelopeForRequest(nil, nil), "test/request", "test/response", StreamOptions{})
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+
+		_, err = reader.Next()
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestResponseStreamEnded(t *testing.T) {
+	client := newMemoryTestClient(t, uuid.NewString())
+	stream := NewResponseStream(client, types.NewMessageEnvelopeForRequest(nil, nil), "test/response")
+
+	require.NoError(t, stream.Send(context.Background(), []byte("{}"), ""))
+	require.NoError(t, stream.Close(context.Background()))
+	require.NoError(t, stream.Close(context.Background()))
+	require.NoError(t, stream.Fail(context.Background(), errors.New("ignored")))
+	require.Error(t, stream.Send(context.Background(), []byte("{}"), ""))
+}
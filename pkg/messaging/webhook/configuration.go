@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+	"strings"
+)
+
+type webhookOptionalConfigurationBuilder struct {
+	options  map[string]string
+	webhooks []string
+}
+
+// NewWebhookOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewWebhookOptionalConfigurationBuilder() *webhookOptionalConfigurationBuilder {
+	return &webhookOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (w *webhookOptionalConfigurationBuilder) Build() map[string]string {
+	return w.options
+}
+
+// Webhook adds a URL the messages published to the topics matching the topic filter are POSTed to, to the optional
+// configuration properties. Can be called several times to add more webhooks.
+func (w *webhookOptionalConfigurationBuilder) Webhook(topicFilter string, url string) *webhookOptionalConfigurationBuilder {
+	w.webhooks = append(w.webhooks, topicFilter+"="+url)
+	w.options[internal.Webhooks] = strings.Join(w.webhooks, " ")
+
+	return w
+}
+
+// Secret adds the HMAC-SHA256 key used to sign and verify the POSTs to the optional configuration properties.
+func (w *webhookOptionalConfigurationBuilder) Secret(secret string) *webhookOptionalConfigurationBuilder {
+	w.options[internal.Secret] = secret
+
+	return w
+}
+
+// MaxRetries adds the number of times a failed POST is retried to the optional configuration properties.
+func (w *webhookOptionalConfigurationBuilder) MaxRetries(retries int) *webhookOptionalConfigurationBuilder {
+	w.options[internal.MaxRetries] = strconv.Itoa(retries)
+
+	return w
+}
+
+// RetryBackoff adds the wait, in milliseconds, before the first retry to the optional configuration properties.
+func (w *webhookOptionalConfigurationBuilder) RetryBackoff(backoff int) *webhookOptionalConfigurationBuilder {
+	w.options[internal.RetryBackoff] = strconv.Itoa(backoff)
+
+	return w
+}
+
+// ConnectTimeout adds the timeout, in seconds, of each POST attempt to the optional configuration properties.
+func (w *webhookOptionalConfigurationBuilder) ConnectTimeout(timeout int) *webhookOptionalConfigurationBuilder {
+	w.options[internal.ConnectTimeout] = strconv.Itoa(timeout)
+
+	return w
+}
+
+// Format adds the name of the codec encoding the published envelopes, e.g. "json", "cbor", "msgpack", "protobuf" or
+// the name of a codec registered with codec.Register, to the optional configuration properties.
+func (w *webhookOptionalConfigurationBuilder) Format(format string) *webhookOptionalConfigurationBuilder {
+	w.options[internal.Format] = format
+
+	return w
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (w *webhookOptionalConfigurationBuilder) RequestMode(mode string) *webhookOptionalConfigurationBuilder {
+	w.options[internal.RequestMode] = mode
+
+	return w
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *webhookOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name: "Webhooks",
+			builder: NewWebhookOptionalConfigurationBuilder().
+				Webhook("edgex/events/#", "http://service/events").
+				Webhook("edgex/commands/#", "http://service/commands"),
+			expectedValues: map[string]string{
+				internal.Webhooks: "edgex/events/#=http://service/events edgex/commands/#=http://service/commands",
+			},
+		},
+		{
+			name:           "Secret",
+			builder:        NewWebhookOptionalConfigurationBuilder().Secret("MySecret"),
+			expectedValues: map[string]string{internal.Secret: "MySecret"},
+		},
+		{
+			name: "Retries",
+			builder: NewWebhookOptionalConfigurationBuilder().
+				MaxRetries(5).
+				RetryBackoff(100).
+				ConnectTimeout(10),
+			expectedValues: map[string]string{
+				internal.MaxRetries:     "5",
+				internal.RetryBackoff:   "100",
+				internal.ConnectTimeout: "10",
+			},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewWebhookOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+		{
+			name:           "Format",
+			builder:        NewWebhookOptionalConfigurationBuilder().Format("cbor"),
+			expectedValues: map[string]string{internal.Format: "cbor"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"messaging/pkg/internal"
+	"strconv"
+)
+
+type websocketOptionalConfigurationBuilder struct {
+	options map[string]string
+}
+
+// NewWebSocketOptionalConfigurationBuilder creates a new builder which aids in creating the map that can be used as
+// MessageBusConfig.Optional field to provide additional configuration options.
+func NewWebSocketOptionalConfigurationBuilder() *websocketOptionalConfigurationBuilder {
+	return &websocketOptionalConfigurationBuilder{
+		options: make(map[string]string),
+	}
+}
+
+// Build constructs a map with the configured configuration options.
+func (w *websocketOptionalConfigurationBuilder) Build() map[string]string {
+	return w.options
+}
+
+// Path adds the HTTP path of the WebSocket handler to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) Path(path string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.Path] = path
+
+	return w
+}
+
+// Username adds the username sent as basic authentication to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) Username(username string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.Username] = username
+
+	return w
+}
+
+// Password adds the password sent as basic authentication to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) Password(password string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.Password] = password
+
+	return w
+}
+
+// SkipCertVerify adds the flag to skip the verification of the server's certificate with "wss" to the optional
+// configuration properties.
+func (w *websocketOptionalConfigurationBuilder) SkipCertVerify(skipCertVerify bool) *websocketOptionalConfigurationBuilder {
+	w.options[internal.SkipCertVerify] = strconv.FormatBool(skipCertVerify)
+
+	return w
+}
+
+// CertFile adds the client certificate file presented with "wss" to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) CertFile(certFile string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.CertFile] = certFile
+
+	return w
+}
+
+// KeyFile adds the client private key file to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) KeyFile(keyFile string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.KeyFile] = keyFile
+
+	return w
+}
+
+// CaFile adds the CA certificate file verifying the server's certificate with "wss" to the optional configuration
+// properties.
+func (w *websocketOptionalConfigurationBuilder) CaFile(caFile string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.CaFile] = caFile
+
+	return w
+}
+
+// ConnectTimeout adds the timeout, in seconds, of the WebSocket handshake to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) ConnectTimeout(timeout int) *websocketOptionalConfigurationBuilder {
+	w.options[internal.ConnectTimeout] = strconv.Itoa(timeout)
+
+	return w
+}
+
+// RequestMode adds how the requests wait for their response, i.e. messaging.RequestModeSubscribe or
+// messaging.RequestModeInbox, to the optional configuration properties.
+func (w *websocketOptionalConfigurationBuilder) RequestMode(mode string) *websocketOptionalConfigurationBuilder {
+	w.options[internal.RequestMode] = mode
+
+	return w
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"messaging/pkg/internal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMethods(t *testing.T) {
+	tests := []struct {
+		name           string
+		builder        *websocketOptionalConfigurationBuilder
+		expectedValues map[string]string
+	}{
+		{
+			name:           "Path",
+			builder:        NewWebSocketOptionalConfigurationBuilder().Path("/messagebus"),
+			expectedValues: map[string]string{internal.Path: "/messagebus"},
+		},
+		{
+			name: "Credentials",
+			builder: NewWebSocketOptionalConfigurationBuilder().
+				Username("TestUser").
+				Password("MyPassword"),
+			expectedValues: map[string]string{
+				internal.Username: "TestUser",
+				internal.Password: "MyPassword",
+			},
+		},
+		{
+			name: "Connection",
+			builder: NewWebSocketOptionalConfigurationBuilder().
+				SkipCertVerify(true).
+				ConnectTimeout(10),
+			expectedValues: map[string]string{
+				internal.SkipCertVerify: "true",
+				internal.ConnectTimeout: "10",
+			},
+		},
+		{
+			name: "TLS",
+			builder: NewWebSocketOptionalConfigurationBuilder().
+				CertFile("client.pem").
+				KeyFile("client.key").
+				CaFile("ca.pem"),
+			expectedValues: map[string]string{
+				internal.CertFile: "client.pem",
+				internal.KeyFile:  "client.key",
+				internal.CaFile:   "ca.pem",
+			},
+		},
+		{
+			name:           "RequestMode",
+			builder:        NewWebSocketOptionalConfigurationBuilder().RequestMode("inbox"),
+			expectedValues: map[string]string{internal.RequestMode: "inbox"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			observedConfig := test.builder.Build()
+
+			for key, value := range test.expectedValues {
+				assert.Equal(t, value, observedConfig[key])
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"messaging/pkg/types"
+	"strings"
+	"sync"
+)
+
+// Codec encodes and decodes the payloads of one content type. Unlike the codecs of the codec package, which encode the
+// whole envelopes, it encodes the requests and responses carried by their Payload.
+type Codec interface {
+	// Marshal returns the encoding of the value.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes the data into the value pointed to.
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	codecs = map[string]Codec{
+		types.ContentTypeJSON: jsonCodec{},
+		types.ContentTypeText: textCodec{},
+	}
+	codecsMutex sync.RWMutex
+)
+
+// RegisterCodec adds the codec of the content type, e.g. types.ContentTypeCBOR, making it available to Call and
+// Handle. The content types are case-insensitive, their parameters are ignored and they can't be registered twice
+// nor with a nil codec.
+func RegisterCodec(contentType string, c Codec) error {
+	mediaType := mediaTypeOf(contentType)
+	if mediaType == "" {
+		return errors.New("content type can't be empty")
+	}
+	if c == nil {
+		return fmt.Errorf("codec of content type '%s' can't be nil", mediaType)
+	}
+
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+
+	if _, exists := codecs[mediaType]; exists {
+		return fmt.Errorf("codec of content type '%s' already registered", mediaType)
+	}
+
+	codecs[mediaType] = c
+
+	return nil
+}
+
+// codecFor returns the codec of the content type, ignoring its parameters such as the charset.
+func codecFor(contentType string) (Codec, error) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+
+	c, exists := codecs[mediaTypeOf(contentType)]
+	if !exists {
+		return nil, fmt.Errorf("unsupported content type '%s'", contentType)
+	}
+
+	return c, nil
+}
+
+// mediaTypeOf returns the lower case media type of the content type, without its parameters.
+func mediaTypeOf(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// jsonCodec encodes the payloads as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// textCodec passes string and []byte payloads through as they are.
+type textCodec struct{}
+
+func (textCodec) Marshal(v any) ([]byte, error) {
+	switch value := v.(type) {
+	case string:
+		return []byte(value), nil
+	case []byte:
+		return value, nil
+	case fmt.Stringer:
+		return []byte(value.String()), nil
+	default:
+		return nil, fmt.Errorf("unable to encode %T as %s", v, types.ContentTypeText)
+	}
+}
+
+func (textCodec) Unmarshal(data []byte, v any) error {
+	switch value := v.(type) {
+	case *string:
+		*value = string(data)
+	case *[]byte:
+		*value = append([]byte(nil), data...)
+	default:
+		return fmt.Errorf("unable to decode %s into %T", types.ContentTypeText, v)
+	}
+
+	return nil
+}
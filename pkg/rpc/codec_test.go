@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"messaging/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    Codec
+		wantErr     bool
+	}{
+		{"JSON", types.ContentTypeJSON, jsonCodec{}, false},
+		{"JSON with charset", "Application/JSON; charset=utf-8", jsonCodec{}, false},
+		{"Text", types.ContentTypeText, textCodec{}, false},
+		{"Unsupported", "application/xml", nil, true},
+		{"Empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := codecFor(tt.contentType)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+		})
+	}
+}
+
+func TestTextCodec(t *testing.T) {
+	c := textCodec{}
+
+	data, err := c.Marshal("text")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("text"), data)
+
+	data, err = c.Marshal([]byte("bytes"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bytes"), data)
+
+	_, err = c.Marshal(42)
+	require.Error(t, err)
+
+	var s string
+	require.NoError(t, c.Unmarshal([]byte("text"), &s))
+	assert.Equal(t, "text", s)
+
+	var b []byte
+	require.NoError(t, c.Unmarshal([]byte("bytes"), &b))
+	assert.Equal(t, []byte("bytes"), b)
+
+	var i int
+	require.Error(t, c.Unmarshal([]byte("42"), &i))
+}
@@ -0,0 +1,142 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+// Package rpc is a typed request-reply layer over messaging.MessageClient.Request and messaging.Server. The payloads
+// are encoded and decoded with the codec of the envelope's ContentType, JSON and text being supported by default and
+// RegisterCodec adding other content types, and error responses are returned as *RemoteError.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"messaging/pkg/messaging"
+	"messaging/pkg/types"
+)
+
+// DefaultResponseTopicPrefix is the prefix of the response topics when Options.ResponseTopicPrefix isn't set.
+const DefaultResponseTopicPrefix = "rpc/response"
+
+// Options customizes Call and Handle.
+type Options struct {
+	// ResponseTopicPrefix is the prefix of the topics the responses are published to, DefaultResponseTopicPrefix when
+	// empty. The requester and the responder must use the same prefix.
+	ResponseTopicPrefix string
+	// ContentType is the content type the requests are encoded with by Call, types.ContentTypeJSON when empty. The
+	// responses are encoded with the content type of their request.
+	ContentType string
+	// MaxConcurrency is the maximum number of requests handled at once by Handle, see messaging.Server.
+	MaxConcurrency int
+}
+
+// RemoteError is the error returned by Call when the responder sent an error response, i.e. with a non-zero
+// ErrorCode. It matches the errors of its code with errors.Is, e.g. types.ErrNotFound.
+type RemoteError = types.MessageError
+
+// Call sends the request to the topic and returns the decoded response, waiting for it until the context is done.
+func Call[Req, Resp any](ctx context.Context, client messaging.MessageClient, topic string, req Req) (Resp, error) {
+	return CallWithOptions[Req, Resp](ctx, client, topic, req, Options{})
+}
+
+// CallWithOptions is Call with options.
+func CallWithOptions[Req, Resp any](ctx context.Context, client messaging.MessageClient, topic string, req Req, options Options) (Resp, error) {
+	var resp Resp
+
+	options = options.withDefaults()
+	c, err := codecFor(options.ContentType)
+	if err != nil {
+		return resp, err
+	}
+
+	payload, err := c.Marshal(req)
+	if err != nil {
+		return resp, fmt.Errorf("unable to encode the request to '%s': %w", topic, err)
+	}
+
+	request := types.NewMessageEnvelopeForRequest(payload, nil)
+	request.ContentType = options.ContentType
+
+	response, err := messaging.WithContext(client).RequestContext(ctx, request, topic, options.ResponseTopicPrefix)
+	if err != nil {
+		return resp, err
+	}
+
+	if err = types.ErrorFromEnvelope(*response); err != nil {
+		return resp, err
+	}
+
+	c, err = codecFor(response.ContentType)
+	if err != nil {
+		return resp, fmt.Errorf("unable to decode the response to '%s': %w", topic, err)
+	}
+
+	if err = c.Unmarshal(response.Payload, &resp); err != nil {
+		return resp, fmt.Errorf("unable to decode the response to '%s': %w", topic, err)
+	}
+
+	return resp, nil
+}
+
+// Handle starts a messaging.Server calling the handler with the decoded requests received on the topic and responding
+// with its encoded response, or with an error response when it returns an error. Errors wrapping e.g.
+// types.ErrNotFound, or a *types.MessageError, are returned by Call with their code. Use Stop to stop it.
+func Handle[Req, Resp any](client messaging.MessageClient, topic string, handler func(ctx context.Context, req Req) (Resp, error)) (*messaging.Server, error) {
+	return HandleWithOptions(client, topic, handler, Options{})
+}
+
+// HandleWithOptions is Handle with options.
+func HandleWithOptions[Req, Resp any](client messaging.MessageClient, topic string, handler func(ctx context.Context, req Req) (Resp, error), options Options) (*messaging.Server, error) {
+	options = options.withDefaults()
+
+	server := &messaging.Server{
+		Client:              client,
+		RequestTopic:        topic,
+		ResponseTopicPrefix: options.ResponseTopicPrefix,
+		MaxConcurrency:      options.MaxConcurrency,
+		Handler: func(ctx context.Context, request types.MessageEnvelope) ([]byte, string, error) {
+			contentType := request.ContentType
+			if contentType == "" {
+				contentType = types.ContentTypeJSON
+			}
+
+			c, err := codecFor(contentType)
+			if err != nil {
+				return nil, "", fmt.Errorf("%w: %v", types.ErrInvalid, err)
+			}
+
+			var req Req
+			if err = c.Unmarshal(request.Payload, &req); err != nil {
+				return nil, "", fmt.Errorf("%w: unable to decode the request: %v", types.ErrInvalid, err)
+			}
+
+			resp, err := handler(ctx, req)
+			if err != nil {
+				return nil, "", err
+			}
+
+			payload, err := c.Marshal(resp)
+			if err != nil {
+				return nil, "", fmt.Errorf("unable to encode the response: %w", err)
+			}
+
+			return payload, contentType, nil
+		},
+	}
+
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.ResponseTopicPrefix == "" {
+		o.ResponseTopicPrefix = DefaultResponseTopicPrefix
+	}
+
+	if o.ContentType == "" {
+		o.ContentType = types.ContentTypeJSON
+	}
+
+	return o
+}
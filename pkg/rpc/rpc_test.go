@@ -0,0 +1,160 @@
+//go:build !no_messagebus
+// +build !no_messagebus
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"messaging/pkg/messaging"
+	"messaging/pkg/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addRequest struct {
+	A, B int
+}
+
+type addResponse struct {
+	Sum int
+}
+
+func newTestClient(t *testing.T, bus string) messaging.MessageClient {
+	if _, exists := messaging.LookupBackend(messaging.Memory); !exists {
+		t.Skip("memory backend excluded from the build")
+	}
+
+	client, err := messaging.NewMessageClient(types.MessageBusConfig{Type: messaging.Memory, Broker: types.HostInfo{Host: bus}})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Disconnect() })
+
+	return client
+}
+
+func TestCall(t *testing.T) {
+	bus := uuid.NewString()
+	requester := newTestClient(t, bus)
+	responder := newTestClient(t, bus)
+
+	add, err := Handle(responder, "test/add", func(_ context.Context, req addRequest) (addResponse, error) {
+		if req.A < 0 || req.B < 0 {
+			return addResponse{}, errors.New("negative operand")
+		}
+		if req.A > 1000 {
+			return addResponse{}, &types.MessageError{Code: types.ErrorCodeInvalid, Message: "operand too large", Details: map[string]string{"field": "A"}}
+		}
+		return addResponse{Sum: req.A + req.B}, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, add.Stop()) }()
+
+	upper, err := HandleWithOptions(responder, "test/upper", func(_ context.Context, req string) (string, error) {
+		return strings.ToUpper(req), nil
+	}, Options{ResponseTopicPrefix: "test/response"})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, upper.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	t.Run("JSON", func(t *testing.T) {
+		resp, err := Call[addRequest, addResponse](ctx, requester, "test/add", addRequest{A: 1, B: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 3, resp.Sum)
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		resp, err := CallWithOptions[string, string](ctx, requester, "test/upper", "hello",
+			Options{ResponseTopicPrefix: "test/response", ContentType: types.ContentTypeText})
+		require.NoError(t, err)
+		assert.Equal(t, "HELLO", resp)
+	})
+
+	t.Run("Handler error", func(t *testing.T) {
+		_, err := Call[addRequest, addResponse](ctx, requester, "test/add", addRequest{A: -1})
+		var remoteErr *RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Equal(t, 1, remoteErr.Code)
+		assert.Equal(t, "negative operand", remoteErr.Message)
+	})
+
+	t.Run("Structured handler error", func(t *testing.T) {
+		_, err := Call[addRequest, addResponse](ctx, requester, "test/add", addRequest{A: 1001})
+		require.ErrorIs(t, err, types.ErrInvalid)
+		var remoteErr *RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Equal(t, "operand too large", remoteErr.Message)
+		assert.Equal(t, map[string]string{"field": "A"}, remoteErr.Details)
+	})
+
+	t.Run("Undecodable request", func(t *testing.T) {
+		_, err := CallWithOptions[string, addResponse](ctx, requester, "test/add", "not json", Options{ContentType: types.ContentTypeText})
+		require.ErrorIs(t, err, types.ErrInvalid)
+		assert.Contains(t, err.Error(), "unable to decode the request")
+	})
+
+	t.Run("Undecodable response", func(t *testing.T) {
+		_, err := Call[addRequest, string](ctx, requester, "test/add", addRequest{A: 1, B: 2})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to decode the response")
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		_, err := CallWithOptions[addRequest, addResponse](ctx, requester, "test/add", addRequest{}, Options{ContentType: "application/xml"})
+		require.Error(t, err)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := Call[addRequest, addResponse](ctx, requester, "test/unhandled", addRequest{})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// cborCodec encodes the payloads as CBOR, a content type not supported by default.
+type cborCodec struct{}
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v any) error {
+	return cbor.Unmarshal(data, v)
+}
+
+func TestRegisterCodec(t *testing.T) {
+	require.NoError(t, RegisterCodec(types.ContentTypeCBOR, cborCodec{}))
+	require.Error(t, RegisterCodec("Application/CBOR; charset=binary", cborCodec{}))
+	require.Error(t, RegisterCodec(types.ContentTypeJSON, cborCodec{}))
+	require.Error(t, RegisterCodec(" ", cborCodec{}))
+	require.Error(t, RegisterCodec("application/x-nil", nil))
+	_, err := codecFor("application/x-nil")
+	require.Error(t, err)
+
+	bus := uuid.NewString()
+	requester := newTestClient(t, bus)
+	responder := newTestClient(t, bus)
+
+	add, err := Handle(responder, "test/add", func(_ context.Context, req addRequest) (addResponse, error) {
+		return addResponse{Sum: req.A + req.B}, nil
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, add.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, err := CallWithOptions[addRequest, addResponse](ctx, requester, "test/add", addRequest{A: 1, B: 2}, Options{ContentType: types.ContentTypeCBOR})
+	require.NoError(t, err)
+	assert.Equal(t, 3, resp.Sum)
+}
@@ -0,0 +1,156 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Error codes of MessageEnvelope.ErrorCode.
+const (
+	// ErrorCodeNone indicates there's no error.
+	ErrorCodeNone = 0
+	// ErrorCodeInternal indicates an internal error of the responder. It is also the code of the error envelopes
+	// predating the other codes, whose payload is the error message.
+	ErrorCodeInternal = 1
+	// ErrorCodeInvalid indicates the request is invalid and retrying it as is won't help.
+	ErrorCodeInvalid = 2
+	// ErrorCodeNotFound indicates the requested entity doesn't exist.
+	ErrorCodeNotFound = 3
+	// ErrorCodeUnavailable indicates the responder or one of its dependencies is temporarily unavailable.
+	ErrorCodeUnavailable = 4
+	// ErrorCodeTimeout indicates the responder timed out handling the request.
+	ErrorCodeTimeout = 5
+)
+
+// Errors matching the MessageError of each error code with errors.Is. Wrap them to create an error envelope with
+// their code, e.g. fmt.Errorf("device %s: %w", name, types.ErrNotFound).
+var (
+	ErrInternal    = errors.New("internal error")
+	ErrInvalid     = errors.New("invalid request")
+	ErrNotFound    = errors.New("not found")
+	ErrUnavailable = errors.New("unavailable")
+	ErrTimeout     = errors.New("timeout")
+)
+
+var errorsByCode = map[int]error{
+	ErrorCodeInternal:    ErrInternal,
+	ErrorCodeInvalid:     ErrInvalid,
+	ErrorCodeNotFound:    ErrNotFound,
+	ErrorCodeUnavailable: ErrUnavailable,
+	ErrorCodeTimeout:     ErrTimeout,
+}
+
+// ErrorPayload is the JSON payload of the error envelopes created from a MessageError.
+type ErrorPayload struct {
+	// Message describes the error.
+	Message string
+	// Details optionally provides key/value pairs about the error, e.g. the name of the invalid field.
+	Details map[string]string `json:",omitempty"`
+	// Retryable indicates whether the request may succeed when sent again.
+	Retryable bool `json:",omitempty"`
+}
+
+// MessageError is the error carried by an error envelope.
+type MessageError struct {
+	// Code is the ErrorCode of the envelope.
+	Code int
+	// Message describes the error.
+	Message string
+	// Details optionally provides key/value pairs about the error.
+	Details map[string]string
+	// Retryable indicates whether the request may succeed when sent again.
+	Retryable bool
+}
+
+// NewMessageError creates a MessageError, retryable when the code is ErrorCodeUnavailable or ErrorCodeTimeout.
+func NewMessageError(code int, message string) *MessageError {
+	return &MessageError{
+		Code:      code,
+		Message:   message,
+		Retryable: code == ErrorCodeUnavailable || code == ErrorCodeTimeout,
+	}
+}
+
+func (e *MessageError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	if sentinel, exists := errorsByCode[e.Code]; exists {
+		return sentinel.Error()
+	}
+
+	return fmt.Sprintf("error code %d", e.Code)
+}
+
+// Is reports whether the target is the error of the MessageError's code, e.g. ErrNotFound.
+func (e *MessageError) Is(target error) bool {
+	sentinel, exists := errorsByCode[e.Code]
+	return exists && target == sentinel
+}
+
+// NewMessageEnvelopeFromError creates the error envelope of the error. A MessageError, or an error wrapping one of the
+// errors of the codes such as ErrNotFound or context.DeadlineExceeded, is sent with its code and an ErrorPayload
+// encoded as JSON. The internal errors, including any other error, are sent as NewMessageEnvelopeWithError does, i.e.
+// with ErrorCodeInternal and the error message as payload, since that's what the receivers predating the other codes
+// expect. Their Details and Retryable are therefore not sent.
+func NewMessageEnvelopeFromError(requestId string, err error) MessageEnvelope {
+	var messageErr *MessageError
+	if !errors.As(err, &messageErr) {
+		for _, code := range []int{ErrorCodeInvalid, ErrorCodeNotFound, ErrorCodeUnavailable, ErrorCodeTimeout, ErrorCodeInternal} {
+			if errors.Is(err, errorsByCode[code]) {
+				messageErr = NewMessageError(code, err.Error())
+				break
+			}
+		}
+	}
+
+	if messageErr == nil && errors.Is(err, context.DeadlineExceeded) {
+		messageErr = NewMessageError(ErrorCodeTimeout, err.Error())
+	}
+
+	if messageErr == nil {
+		return NewMessageEnvelopeWithError(requestId, err.Error())
+	}
+
+	if messageErr.Code == ErrorCodeInternal {
+		return NewMessageEnvelopeWithError(requestId, messageErr.Error())
+	}
+
+	payload, marshalErr := json.Marshal(ErrorPayload{
+		Message:   messageErr.Message,
+		Details:   messageErr.Details,
+		Retryable: messageErr.Retryable,
+	})
+	if marshalErr != nil {
+		return NewMessageEnvelopeWithError(requestId, err.Error())
+	}
+
+	envelope := NewMessageEnvelopeWithError(requestId, "")
+	envelope.ErrorCode = messageErr.Code
+	envelope.Payload = payload
+	envelope.ContentType = ContentTypeJSON
+
+	return envelope
+}
+
+// ErrorFromEnvelope returns the MessageError carried by the envelope, or nil when its ErrorCode is ErrorCodeNone.
+// The payload is decoded as an ErrorPayload when its ContentType is JSON, otherwise the payload is the message.
+func ErrorFromEnvelope(envelope MessageEnvelope) error {
+	if envelope.ErrorCode == ErrorCodeNone {
+		return nil
+	}
+
+	messageErr := &MessageError{Code: envelope.ErrorCode, Message: string(envelope.Payload)}
+
+	var payload ErrorPayload
+	if envelope.ContentType == ContentTypeJSON && json.Unmarshal(envelope.Payload, &payload) == nil && payload.Message != "" {
+		messageErr.Message = payload.Message
+		messageErr.Details = payload.Details
+		messageErr.Retryable = payload.Retryable
+	}
+
+	return messageErr
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessageEnvelopeFromError(t *testing.T) {
+	tests := []struct {
+		name              string
+		err               error
+		expectedCode      int
+		expectedSentinel  error
+		expectedMessage   string
+		expectedRetryable bool
+		expectStructured  bool
+	}{
+		{"Plain error", errors.New("failed"), ErrorCodeInternal, ErrInternal, "failed", false, false},
+		{"Wrapped internal", fmt.Errorf("cache: %w", ErrInternal), ErrorCodeInternal, ErrInternal, "cache: internal error", false, false},
+		{"Internal message error", NewMessageError(ErrorCodeInternal, "crashed"), ErrorCodeInternal, ErrInternal, "crashed", false, false},
+		{"Wrapped not found", fmt.Errorf("device 'd1': %w", ErrNotFound), ErrorCodeNotFound, ErrNotFound, "device 'd1': not found", false, true},
+		{"Wrapped unavailable", fmt.Errorf("%w: database down", ErrUnavailable), ErrorCodeUnavailable, ErrUnavailable, "unavailable: database down", true, true},
+		{"Deadline exceeded", fmt.Errorf("query: %w", context.DeadlineExceeded), ErrorCodeTimeout, ErrTimeout, "query: context deadline exceeded", true, true},
+		{"Message error", fmt.Errorf("wrapped: %w", NewMessageError(ErrorCodeInvalid, "bad name")), ErrorCodeInvalid, ErrInvalid, "bad name", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope := NewMessageEnvelopeFromError(testRequestId, tt.err)
+			assert.Equal(t, testRequestId, envelope.RequestID)
+			assert.Equal(t, tt.expectedCode, envelope.ErrorCode)
+			if tt.expectStructured {
+				assert.Equal(t, ContentTypeJSON, envelope.ContentType)
+			} else {
+				assert.Equal(t, ContentTypeText, envelope.ContentType)
+				assert.Equal(t, tt.expectedMessage, string(envelope.Payload))
+			}
+
+			err := ErrorFromEnvelope(envelope)
+			require.ErrorIs(t, err, tt.expectedSentinel)
+			assert.Equal(t, tt.expectedMessage, err.Error())
+
+			var messageErr *MessageError
+			require.ErrorAs(t, err, &messageErr)
+			assert.Equal(t, tt.expectedCode, messageErr.Code)
+			assert.Equal(t, tt.expectedRetryable, messageErr.Retryable)
+		})
+	}
+}
+
+func TestErrorFromEnvelope(t *testing.T) {
+	assert.NoError(t, ErrorFromEnvelope(testMessageEnvelope()))
+
+	// Error envelopes predating the error codes
+	err := ErrorFromEnvelope(NewMessageEnvelopeWithError(testRequestId, "legacy error"))
+	require.ErrorIs(t, err, ErrInternal)
+	assert.Equal(t, "legacy error", err.Error())
+
+	// JSON payload which isn't an ErrorPayload
+	envelope := NewMessageEnvelopeWithError(testRequestId, `{"data":"myData"}`)
+	envelope.ContentType = ContentTypeJSON
+	assert.Equal(t, `{"data":"myData"}`, ErrorFromEnvelope(envelope).Error())
+
+	// Unknown codes don't match any error
+	envelope = MessageEnvelope{ErrorCode: 42, Payload: []byte("unknown")}
+	err = ErrorFromEnvelope(envelope)
+	for _, sentinel := range []error{ErrInternal, ErrInvalid, ErrNotFound, ErrUnavailable, ErrTimeout} {
+		assert.NotErrorIs(t, err, sentinel)
+	}
+	assert.Equal(t, "error code 42", (&MessageError{Code: 42}).Error())
+	assert.Equal(t, "not found", (&MessageError{Code: ErrorCodeNotFound}).Error())
+
+	details := NewMessageError(ErrorCodeInvalid, "bad")
+	details.Details = map[string]string{"field": "name"}
+	err = ErrorFromEnvelope(NewMessageEnvelopeFromError(testRequestId, details))
+	var messageErr *MessageError
+	require.ErrorAs(t, err, &messageErr)
+	assert.Equal(t, details, messageErr)
+}
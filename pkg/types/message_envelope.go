@@ -5,15 +5,34 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 )
 
 const (
-	ApiVersion      = "v1"
-	CorrelationID   = "X-Correlation-ID"
-	ContentType     = "Content-Type"
-	ContentTypeJSON = "application/json"
-	ContentTypeText = "text/plain"
+	ApiVersion             = "v1"
+	CorrelationID          = "X-Correlation-ID"
+	ContentType            = "Content-Type"
+	ContentTypeJSON        = "application/json"
+	ContentTypeText        = "text/plain"
+	ContentTypeCBOR        = "application/cbor"
+	ContentTypeMessagePack = "application/msgpack"
+	ContentTypeProtobuf    = "application/x-protobuf"
+)
+
+// Reserved keys of MessageEnvelope.Headers. The keys starting with ReservedHeaderPrefix are reserved for this module,
+// application specific headers must use their own keys.
+const (
+	ReservedHeaderPrefix = "messaging."
+	// HeaderTenantID identifies the tenant the message belongs to.
+	HeaderTenantID = ReservedHeaderPrefix + "tenant-id"
+	// HeaderTraceParent carries the W3C Trace Context traceparent of the message.
+	HeaderTraceParent = ReservedHeaderPrefix + "traceparent"
+	// HeaderTraceState carries the W3C Trace Context tracestate of the message.
+	HeaderTraceState = ReservedHeaderPrefix + "tracestate"
+	// HeaderRoutingHint optionally tells the receivers how to route the message.
+	HeaderRoutingHint = ReservedHeaderPrefix + "routing-hint"
 )
 
 // MessageEnvelope is the data structure for messages. It wraps the generic message payload with attributes.
@@ -26,8 +45,8 @@ type MessageEnvelope struct {
 	ApiVersion string
 	// RequestID is an object id to identify the request.
 	RequestID string
-	// ErrorCode provides the indication of error. '0' indicates no error, '1' indicates error.
-	// Additional codes may be added in the future. If non-0, the payload will contain the error.
+	// ErrorCode provides the indication of error. '0' indicates no error, otherwise it is one of the ErrorCode
+	// constants, '1' being a generic error. If non-0, the payload will contain the error, see ErrorFromEnvelope.
 	ErrorCode int
 	// Payload is byte representation of the data being transferred.
 	Payload []byte
@@ -35,6 +54,17 @@ type MessageEnvelope struct {
 	ContentType string
 	// QueryParams is optionally provided kye/value pairs.
 	QueryParams map[string]string
+	// Sequence is the position, starting at 1, of the envelope in a streamed response. 0 when not streamed.
+	Sequence int `json:",omitempty"`
+	// EndOfStream marks the last envelope of a streamed response.
+	EndOfStream bool `json:",omitempty"`
+	// Deadline is the time, in Unix milliseconds, after which nobody waits for the handling of the envelope anymore,
+	// 0 when none. It is set on the requests from the deadline of the requester's context by the Request APIs. As an
+	// absolute time it relies on the clocks of the requester and the responder being synchronized.
+	Deadline int64 `json:",omitempty"`
+	// Headers is optionally provided metadata about the envelope, such as the tenant or the trace context, see the
+	// Header constants. Unlike QueryParams they aren't parameters of the request.
+	Headers map[string]string `json:",omitempty"`
 }
 
 // NewMessageEnvelope creates a new MessageEnvelope for the specified payload with attributes from the specified context
@@ -143,6 +173,41 @@ func NewMessageEnvelopeWithError(requestId string, errorMessage string) MessageE
 	}
 }
 
+// DeadlineTime returns the Deadline of the envelope, and whether it is set.
+func (m MessageEnvelope) DeadlineTime() (time.Time, bool) {
+	if m.Deadline == 0 {
+		return time.Time{}, false
+	}
+
+	return time.UnixMilli(m.Deadline), true
+}
+
+// Expired reports whether the Deadline of the envelope is set and has passed.
+func (m MessageEnvelope) Expired() bool {
+	deadline, ok := m.DeadlineTime()
+	return ok && !time.Now().Before(deadline)
+}
+
+// GetHeader returns the value of the header, and whether it is set.
+func (m MessageEnvelope) GetHeader(key string) (string, bool) {
+	value, exists := m.Headers[key]
+	return value, exists
+}
+
+// SetHeader sets the header to the value, creating the Headers when needed.
+func (m *MessageEnvelope) SetHeader(key string, value string) {
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+
+	m.Headers[key] = value
+}
+
+// DeleteHeader removes the header.
+func (m *MessageEnvelope) DeleteHeader(key string) {
+	delete(m.Headers, key)
+}
+
 func fromContext(ctx context.Context, key string) string {
 	hdr, ok := ctx.Value(key).(string)
 	if !ok {
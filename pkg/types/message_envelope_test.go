@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -174,6 +175,80 @@ func TestNewMessageEnvelopeWithError(t *testing.T) {
 	assert.Empty(t, envelope.QueryParams)
 }
 
+func TestMessageEnvelopeStreamFieldsJSON(t *testing.T) {
+	data, err := json.Marshal(testMessageEnvelope())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Sequence")
+	assert.NotContains(t, string(data), "EndOfStream")
+
+	chunk := testMessageEnvelope()
+	chunk.Sequence = 2
+	chunk.EndOfStream = true
+	data, err = json.Marshal(chunk)
+	require.NoError(t, err)
+
+	var decoded MessageEnvelope
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, 2, decoded.Sequence)
+	assert.True(t, decoded.EndOfStream)
+}
+
+func TestMessageEnvelopeDeadline(t *testing.T) {
+	envelope := testMessageEnvelope()
+	_, ok := envelope.DeadlineTime()
+	assert.False(t, ok)
+	assert.False(t, envelope.Expired())
+
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Deadline")
+
+	deadline := time.Now().Add(time.Minute)
+	envelope.Deadline = deadline.UnixMilli()
+	actual, ok := envelope.DeadlineTime()
+	require.True(t, ok)
+	assert.Equal(t, deadline.UnixMilli(), actual.UnixMilli())
+	assert.False(t, envelope.Expired())
+
+	data, err = json.Marshal(envelope)
+	require.NoError(t, err)
+	var decoded MessageEnvelope
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, envelope.Deadline, decoded.Deadline)
+
+	envelope.Deadline = time.Now().Add(-time.Millisecond).UnixMilli()
+	assert.True(t, envelope.Expired())
+}
+
+func TestMessageEnvelopeHeaders(t *testing.T) {
+	envelope := testMessageEnvelope()
+	_, exists := envelope.GetHeader(HeaderTenantID)
+	assert.False(t, exists)
+
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Headers")
+
+	envelope.QueryParams = map[string]string{HeaderTenantID: "query"}
+	envelope.SetHeader(HeaderTenantID, "tenant")
+	envelope.SetHeader(HeaderTraceParent, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	value, exists := envelope.GetHeader(HeaderTenantID)
+	require.True(t, exists)
+	assert.Equal(t, "tenant", value)
+	assert.Equal(t, "query", envelope.QueryParams[HeaderTenantID], "QueryParams unchanged")
+
+	data, err = json.Marshal(envelope)
+	require.NoError(t, err)
+	var decoded MessageEnvelope
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, envelope.Headers, decoded.Headers)
+
+	envelope.DeleteHeader(HeaderTenantID)
+	_, exists = envelope.GetHeader(HeaderTenantID)
+	assert.False(t, exists)
+}
+
 func testMessageEnvelope() MessageEnvelope {
 	return MessageEnvelope{
 		CorrelationID: testCorrelationId,
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RequestAllOptions defines when a scatter-gather request stops collecting responses, besides the deadline of its
+// context. The zero value collects responses until the context is done.
+type RequestAllOptions struct {
+	// MaxResponses is the number of responses after which the request completes, no limit when not positive.
+	MaxResponses int
+	// QuietPeriod is the time without a new response after which the request completes, counted from when the request
+	// is published and then from each response. Disabled when not positive.
+	QuietPeriod time.Duration
+}
+
+// RequestTimeoutErr is returned along with the responses received so far when the deadline of a scatter-gather
+// request is reached before it completes. It matches context.DeadlineExceeded with errors.Is.
+type RequestTimeoutErr struct {
+	// ResponseTopic is the topic the responses were waited for on.
+	ResponseTopic string
+	// Received is the number of responses received before the deadline.
+	Received int
+}
+
+func (e *RequestTimeoutErr) Error() string {
+	return fmt.Sprintf("timed out waiting for responses on %s topic after %d response(s): %v",
+		e.ResponseTopic, e.Received, context.DeadlineExceeded)
+}
+
+func (e *RequestTimeoutErr) Unwrap() error {
+	return context.DeadlineExceeded
+}
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeoutErr(t *testing.T) {
+	var err error = &RequestTimeoutErr{ResponseTopic: "response/123", Received: 2}
+
+	assert.Equal(t, "timed out waiting for responses on response/123 topic after 2 response(s): context deadline exceeded", err.Error())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var timeoutErr *RequestTimeoutErr
+	assert.True(t, errors.As(fmt.Errorf("wrapped: %w", err), &timeoutErr))
+	assert.Equal(t, 2, timeoutErr.Received)
+}
@@ -0,0 +1,509 @@
+// Package websocket exposes a message bus to browsers and edge gateways over WebSocket. The Handler serves the
+// subscribe, unsubscribe, publish and request operations of any messaging.MessageClient through a JSON frame
+// protocol carrying types.MessageEnvelope, encoded with the codec of the connection's format, see Frame.
+//
+// The "websocket" MessageClient implementation is the Go client of this handler.
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"messaging/pkg/codec"
+	"messaging/pkg/internal"
+	"messaging/pkg/types"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// FormatParameter is the query parameter of the connection's URL naming the codec of the envelopes carried by
+	// its frames, see codec.Lookup. The envelopes are carried as is when it's missing or JSON.
+	FormatParameter = "format"
+
+	// MaxFrameSize is the maximum size in bytes of a frame received from a client.
+	MaxFrameSize = 16 * 1024 * 1024
+
+	// DefaultRequestTimeout is the time a request waits for its response when the frame has no Timeout.
+	DefaultRequestTimeout = 30 * time.Second
+
+	// outboundBufferSize is the number of frames buffered for a connection, a connection falling further behind is
+	// disconnected rather than holding up the delivery to the other connections.
+	outboundBufferSize = 256
+	// maxConcurrentRequests is the number of requests of a connection waiting for their response at once, the
+	// following frames of the connection are read once one of them completes.
+	maxConcurrentRequests = 64
+	// writeTimeout is the maximum time writing a frame may take before the connection is considered dead.
+	writeTimeout = 10 * time.Second
+	// pingInterval is the interval of the pings keeping idle connections alive through proxies.
+	pingInterval = 30 * time.Second
+)
+
+// Client is the part of messaging.MessageClient the Handler needs, any messaging.MessageClient can be used.
+type Client interface {
+	Publish(message types.MessageEnvelope, topic string) error
+	Subscribe(topics []types.TopicChannel, messageErrors chan error) error
+	Request(message types.MessageEnvelope, requestTopic string, responseTopicPrefix string, timeout time.Duration) (*types.MessageEnvelope, error)
+	Unsubscribe(topics ...string) error
+}
+
+// Handler is an http.Handler upgrading the requests to WebSocket connections served through the client. The
+// connections subscribed to the same topic filter share a single subscription of the client.
+type Handler struct {
+	// CheckOrigin returns true when the request's Origin header is acceptable, when nil the connections are only
+	// accepted from the same origin as the request's Host.
+	CheckOrigin func(r *http.Request) bool
+
+	client Client
+
+	connections   map[*connection]bool
+	subscriptions map[string]*subscription
+	closed        bool
+	mutex         sync.Mutex
+
+	// Serializes the subscription changes made to the client, it's distinct from mutex since delivering the messages
+	// needs mutex while the client may wait on the delivery to unsubscribe
+	subscribeMutex sync.Mutex
+
+	// Tracks the go routines of the connections
+	connectionsGroup sync.WaitGroup
+}
+
+// subscription is a subscription of the client shared by the connections subscribed to its topic filter.
+type subscription struct {
+	filter      string
+	messages    chan types.MessageEnvelope
+	errors      chan error
+	connections map[*connection]bool
+	done        chan struct{}
+}
+
+// NewHandler creates a Handler serving the WebSocket connections through the client, which must be connected.
+func NewHandler(client Client) *Handler {
+	return &Handler{
+		client:        client,
+		connections:   make(map[*connection]bool),
+		subscriptions: make(map[string]*subscription),
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves its frames until the connection is closed.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	envelopeCodec, err := codec.Lookup(r.URL.Query().Get(FormatParameter))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: h.CheckOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with the error
+		return
+	}
+
+	conn.SetReadLimit(MaxFrameSize)
+
+	c := newConnection(h, conn, envelopeCodec)
+	if !h.addConnection(c) {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server closed"), time.Now().Add(writeTimeout))
+		_ = conn.Close()
+		return
+	}
+
+	c.start()
+}
+
+// Close disconnects the connections and removes their subscriptions from the client, which isn't disconnected.
+func (h *Handler) Close() error {
+	h.mutex.Lock()
+	h.closed = true
+	connections := make([]*connection, 0, len(h.connections))
+	for c := range h.connections {
+		connections = append(connections, c)
+	}
+	h.mutex.Unlock()
+
+	for _, c := range connections {
+		c.close()
+	}
+	h.connectionsGroup.Wait()
+
+	return nil
+}
+
+func (h *Handler) addConnection(c *connection) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	h.connections[c] = true
+	h.connectionsGroup.Add(2)
+	return true
+}
+
+func (h *Handler) removeConnection(c *connection) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.connections, c)
+}
+
+// subscribe adds the connection to the subscriptions of the topic filters, subscribing the client to the filters
+// without subscription yet.
+func (h *Handler) subscribe(c *connection, filters []string) error {
+	for _, filter := range filters {
+		if err := internal.ValidateTopicFilter(filter); err != nil {
+			return err
+		}
+	}
+
+	h.subscribeMutex.Lock()
+	defer h.subscribeMutex.Unlock()
+
+	for _, filter := range filters {
+		h.mutex.Lock()
+		s, exists := h.subscriptions[filter]
+		if exists {
+			s.connections[c] = true
+		}
+		h.mutex.Unlock()
+
+		if exists {
+			continue
+		}
+
+		s = &subscription{
+			filter:      filter,
+			messages:    make(chan types.MessageEnvelope),
+			errors:      make(chan error),
+			connections: map[*connection]bool{c: true},
+			done:        make(chan struct{}),
+		}
+
+		// The subscription must be known before the client delivers its first message
+		h.mutex.Lock()
+		h.subscriptions[filter] = s
+		h.mutex.Unlock()
+
+		go h.forward(s)
+
+		if err := h.client.Subscribe([]types.TopicChannel{{Topic: filter, Messages: s.messages}}, s.errors); err != nil {
+			h.mutex.Lock()
+			delete(h.subscriptions, filter)
+			h.mutex.Unlock()
+			close(s.done)
+
+			return fmt.Errorf("unable to subscribe to '%s': %w", filter, err)
+		}
+	}
+
+	return nil
+}
+
+// unsubscribe removes the connection from the subscriptions of the topic filters, unsubscribing the client from the
+// filters without any connection left.
+func (h *Handler) unsubscribe(c *connection, filters []string) error {
+	h.subscribeMutex.Lock()
+	defer h.subscribeMutex.Unlock()
+
+	var unused []*subscription
+	h.mutex.Lock()
+	for _, filter := range filters {
+		s, exists := h.subscriptions[filter]
+		if !exists || !s.connections[c] {
+			continue
+		}
+
+		delete(s.connections, c)
+		if len(s.connections) == 0 {
+			delete(h.subscriptions, filter)
+			unused = append(unused, s)
+		}
+	}
+	h.mutex.Unlock()
+
+	var err error
+	for _, s := range unused {
+		if unsubscribeErr := h.client.Unsubscribe(s.filter); unsubscribeErr != nil {
+			err = fmt.Errorf("unable to unsubscribe from '%s': %w", s.filter, unsubscribeErr)
+		}
+		close(s.done)
+	}
+
+	return err
+}
+
+// forward delivers the messages and errors of the subscription to its connections until it's removed.
+func (h *Handler) forward(s *subscription) {
+	for {
+		select {
+		case message := <-s.messages:
+			h.route(s, Frame{Type: FrameMessage, Topic: s.filter, Envelope: &message})
+		case err := <-s.errors:
+			h.route(s, Frame{Type: FrameError, Topic: s.filter, Error: err.Error()})
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// route sends the frame to the connections of the subscription.
+func (h *Handler) route(s *subscription, frame Frame) {
+	h.mutex.Lock()
+	recipients := make([]*connection, 0, len(s.connections))
+	for c := range s.connections {
+		recipients = append(recipients, c)
+	}
+	h.mutex.Unlock()
+
+	// Sending happens outside the lock since it disconnects the slow connections
+	for _, c := range recipients {
+		c.send(frame)
+	}
+}
+
+// request publishes the request of the frame and sends back the response, or the error, with the frame's ID.
+func (h *Handler) request(c *connection, frame Frame) {
+	timeout := DefaultRequestTimeout
+	if frame.Timeout > 0 {
+		timeout = time.Duration(frame.Timeout) * time.Millisecond
+	}
+
+	response, err := h.client.Request(*frame.Envelope, frame.Topic, frame.ResponseTopicPrefix, timeout)
+	if err != nil {
+		c.send(Frame{Type: FrameError, ID: frame.ID, Error: err.Error()})
+		return
+	}
+
+	c.send(Frame{Type: FrameResponse, ID: frame.ID, Envelope: response})
+}
+
+// connection is the server side of a WebSocket connection.
+type connection struct {
+	handler *Handler
+	conn    *websocket.Conn
+	// codec encodes the envelopes of the frames, they are carried as is when it's JSON
+	codec codec.Codec
+
+	filters map[string]bool
+
+	outbound chan Frame
+	requests chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+	// closeCode and closeText are sent with the close message, they are set before done is closed
+	closeCode int
+	closeText string
+}
+
+func newConnection(h *Handler, conn *websocket.Conn, envelopeCodec codec.Codec) *connection {
+	return &connection{
+		handler:  h,
+		conn:     conn,
+		codec:    envelopeCodec,
+		filters:  make(map[string]bool),
+		outbound: make(chan Frame, outboundBufferSize),
+		requests: make(chan struct{}, maxConcurrentRequests),
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *connection) start() {
+	go c.read()
+	go c.write()
+}
+
+// read processes the frames received from the connection, in order, until it's closed. The requests are processed
+// concurrently since they wait for their response, up to maxConcurrentRequests at once.
+func (c *connection) read() {
+	defer c.handler.connectionsGroup.Done()
+	defer c.removeSubscriptions()
+	defer c.close()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame Frame
+		if err = json.Unmarshal(data, &frame); err != nil {
+			c.send(Frame{Type: FrameError, Error: fmt.Sprintf("unable to decode frame: %v", err)})
+			continue
+		}
+
+		if frame.Data != nil {
+			if frame.Envelope, err = internal.DecodeFrameEnvelope(nil, frame.Data, c.codec); err != nil {
+				c.send(Frame{Type: FrameError, ID: frame.ID, Error: fmt.Sprintf("unable to decode envelope: %v", err)})
+				continue
+			}
+		}
+
+		if frame.Type == FrameRequest {
+			if err := validateRequest(frame); err != nil {
+				c.send(Frame{Type: FrameError, ID: frame.ID, Error: err.Error()})
+				continue
+			}
+
+			select {
+			case c.requests <- struct{}{}:
+			case <-c.done:
+				return
+			}
+
+			go func() {
+				defer func() { <-c.requests }()
+				c.handler.request(c, frame)
+			}()
+			continue
+		}
+
+		if err := c.handle(frame); err != nil {
+			c.send(Frame{Type: FrameError, ID: frame.ID, Error: err.Error()})
+			continue
+		}
+
+		c.send(Frame{Type: FrameAck, ID: frame.ID})
+	}
+}
+
+func (c *connection) handle(frame Frame) error {
+	switch frame.Type {
+	case FramePublish:
+		if frame.Envelope == nil {
+			return errors.New("publish frame is missing the envelope")
+		}
+
+		return c.handler.client.Publish(*frame.Envelope, frame.Topic)
+
+	case FrameSubscribe:
+		var filters []string
+		for _, topic := range frame.Topics {
+			if !c.filters[topic] {
+				filters = append(filters, topic)
+			}
+		}
+
+		if err := c.handler.subscribe(c, filters); err != nil {
+			// Roll back the filters subscribed before the failure
+			_ = c.handler.unsubscribe(c, filters)
+			return err
+		}
+
+		for _, topic := range filters {
+			c.filters[topic] = true
+		}
+
+	case FrameUnsubscribe:
+		for _, topic := range frame.Topics {
+			delete(c.filters, topic)
+		}
+
+		return c.handler.unsubscribe(c, frame.Topics)
+
+	default:
+		return errors.New("unsupported frame type '" + string(frame.Type) + "'")
+	}
+
+	return nil
+}
+
+// write sends the outbound frames, and the pings, to the connection until it's closed. The underlying connection is
+// closed on return, which ends the read loop.
+func (c *connection) write() {
+	defer c.handler.connectionsGroup.Done()
+	defer func() { _ = c.conn.Close() }()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case frame := <-c.outbound:
+			frame = c.encode(frame)
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteJSON(frame); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			_ = c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeText), time.Now().Add(writeTimeout))
+			return
+		}
+	}
+}
+
+// encode returns the frame carrying its envelope with the connection's codec, or the error frame replacing it when the
+// envelope can't be encoded. The frame is a copy, the frames routed to several connections share their envelope.
+func (c *connection) encode(frame Frame) Frame {
+	if frame.Envelope == nil {
+		return frame
+	}
+
+	envelope, data, err := internal.EncodeFrameEnvelope(*frame.Envelope, c.codec)
+	if err != nil {
+		return Frame{Type: FrameError, ID: frame.ID, Topic: frame.Topic,
+			Error: fmt.Sprintf("unable to encode envelope: %v", err)}
+	}
+
+	frame.Envelope = envelope
+	frame.Data = data
+	return frame
+}
+
+// send queues the frame for the connection unless it's closed. A connection too slow to keep up, i.e. whose
+// outbound buffer is full, is disconnected instead of waiting for it.
+func (c *connection) send(frame Frame) {
+	select {
+	case c.outbound <- frame:
+	case <-c.done:
+	default:
+		c.disconnect(websocket.ClosePolicyViolation, "too slow to keep up with the frames")
+	}
+}
+
+func (c *connection) close() {
+	c.disconnect(websocket.CloseNormalClosure, "")
+}
+
+// disconnect closes the connection, with the code and text of the close message sent to the peer.
+func (c *connection) disconnect(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+		c.handler.removeConnection(c)
+	})
+}
+
+// removeSubscriptions removes the connection from the subscriptions of its topic filters once it's closed.
+func (c *connection) removeSubscriptions() {
+	filters := make([]string, 0, len(c.filters))
+	for filter := range c.filters {
+		filters = append(filters, filter)
+	}
+	_ = c.handler.unsubscribe(c, filters)
+}
+
+func validateRequest(frame Frame) error {
+	if frame.Envelope == nil {
+		return errors.New("request frame is missing the envelope")
+	}
+
+	return internal.ValidatePublishTopic(frame.Topic)
+}
@@ -0,0 +1,309 @@
+package websocket
+
+import (
+	"messaging/pkg/codec"
+	"messaging/pkg/internal/memory"
+	"messaging/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+func startHandler(t *testing.T) (*Handler, *memory.Client, string) {
+	bus, err := memory.NewClient(types.MessageBusConfig{Broker: types.HostInfo{Host: t.Name()}})
+	require.NoError(t, err)
+
+	handler := NewHandler(bus)
+	server := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		require.NoError(t, handler.Close())
+		server.Close()
+		_ = bus.Disconnect()
+	})
+
+	return handler, bus, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// testConn is a raw protocol connection to the handler.
+type testConn struct {
+	t      *testing.T
+	conn   *websocket.Conn
+	nextID uint64
+}
+
+func dial(t *testing.T, url string) *testConn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testConn{t: t, conn: conn}
+}
+
+func (c *testConn) send(frame Frame) Frame {
+	c.nextID++
+	frame.ID = c.nextID
+	require.NoError(c.t, c.conn.WriteJSON(frame))
+
+	response := c.receive()
+	require.Equal(c.t, frame.ID, response.ID)
+
+	return response
+}
+
+func (c *testConn) receive() Frame {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(testTimeout)))
+
+	var frame Frame
+	require.NoError(c.t, c.conn.ReadJSON(&frame))
+
+	return frame
+}
+
+func (c *testConn) expectNothing() {
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+	var frame Frame
+	require.Error(c.t, c.conn.ReadJSON(&frame), "unexpected frame %v", frame)
+}
+
+func TestHandlerSharedSubscriptions(t *testing.T) {
+	_, bus, url := startHandler(t)
+
+	first := dial(t, url)
+	second := dial(t, url)
+
+	assert.Equal(t, FrameAck, first.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/#"}}).Type)
+	assert.Equal(t, FrameAck, second.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/#", "other"}}).Type)
+
+	require.NoError(t, bus.Publish(types.MessageEnvelope{CorrelationID: "123"}, "edgex/events"))
+
+	for _, conn := range []*testConn{first, second} {
+		frame := conn.receive()
+		assert.Equal(t, FrameMessage, frame.Type)
+		assert.Equal(t, "edgex/#", frame.Topic)
+		require.NotNil(t, frame.Envelope)
+		assert.Equal(t, "123", frame.Envelope.CorrelationID)
+		assert.Equal(t, "edgex/events", frame.Envelope.ReceivedTopic)
+	}
+
+	// The subscription of the bus stays in place as long as a connection uses it
+	assert.Equal(t, FrameAck, first.send(Frame{Type: FrameUnsubscribe, Topics: []string{"edgex/#"}}).Type)
+	require.NoError(t, bus.Publish(types.MessageEnvelope{CorrelationID: "456"}, "edgex/events"))
+	assert.Equal(t, "456", second.receive().Envelope.CorrelationID)
+	first.expectNothing()
+
+	// The subscriptions of a closed connection are removed from the bus
+	require.NoError(t, second.conn.Close())
+	require.Eventually(t, func() bool {
+		return bus.Subscribe([]types.TopicChannel{
+			{Topic: "edgex/#", Messages: make(chan types.MessageEnvelope)},
+			{Topic: "other", Messages: make(chan types.MessageEnvelope)},
+		}, make(chan error)) == nil
+	}, testTimeout, 10*time.Millisecond)
+}
+
+func TestHandlerPublish(t *testing.T) {
+	_, bus, url := startHandler(t)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, bus.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	conn := dial(t, url)
+	envelope := types.MessageEnvelope{CorrelationID: "123", Payload: []byte("data")}
+	assert.Equal(t, FrameAck, conn.send(Frame{Type: FramePublish, Topic: "edgex/events", Envelope: &envelope}).Type)
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, []byte("data"), message.Payload)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for message")
+	}
+}
+
+func TestHandlerFormat(t *testing.T) {
+	_, bus, url := startHandler(t)
+
+	_, response, err := websocket.DefaultDialer.Dial(url+"?"+FormatParameter+"=xml", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	messages := make(chan types.MessageEnvelope, 1)
+	require.NoError(t, bus.Subscribe([]types.TopicChannel{{Topic: "edgex/events", Messages: messages}}, make(chan error)))
+
+	conn := dial(t, url+"?"+FormatParameter+"="+codec.NameCBOR)
+	assert.Equal(t, FrameAck, conn.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/#"}}).Type)
+
+	data, err := codec.CBOR.Encode(types.MessageEnvelope{CorrelationID: "123", Payload: []byte("data")})
+	require.NoError(t, err)
+	assert.Equal(t, FrameAck, conn.send(Frame{Type: FramePublish, Topic: "edgex/events", Data: data}).Type)
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "123", message.CorrelationID)
+		assert.Equal(t, []byte("data"), message.Payload)
+	case <-time.After(testTimeout):
+		require.Fail(t, "timed out waiting for message")
+	}
+
+	// The envelopes sent to the connection are encoded with its format
+	frame := conn.receive()
+	assert.Equal(t, FrameMessage, frame.Type)
+	assert.Nil(t, frame.Envelope)
+	var message types.MessageEnvelope
+	require.NoError(t, codec.CBOR.Decode(frame.Data, &message))
+	assert.Equal(t, "123", message.CorrelationID)
+	assert.Equal(t, "edgex/events", message.ReceivedTopic)
+
+	// Data which can't be decoded is rejected
+	assert.Equal(t, FrameError, conn.send(Frame{Type: FramePublish, Topic: "edgex/events", Data: []byte("garbage")}).Type)
+}
+
+func TestHandlerRequest(t *testing.T) {
+	_, bus, url := startHandler(t)
+
+	requests := make(chan types.MessageEnvelope)
+	require.NoError(t, bus.Subscribe([]types.TopicChannel{{Topic: "edgex/request", Messages: requests}}, make(chan error)))
+
+	go func() {
+		request := <-requests
+		response, _ := types.NewMessageEnvelopeForResponse([]byte("response"), request.RequestID, request.CorrelationID, types.ContentTypeJSON)
+		_ = bus.Publish(response, "edgex/response/"+request.RequestID)
+	}()
+
+	conn := dial(t, url)
+	request := types.NewMessageEnvelopeForRequest([]byte("request"), nil)
+	response := conn.send(Frame{
+		Type:                FrameRequest,
+		Topic:               "edgex/request",
+		ResponseTopicPrefix: "edgex/response",
+		Timeout:             testTimeout.Milliseconds(),
+		Envelope:            &request,
+	})
+	assert.Equal(t, FrameResponse, response.Type)
+	require.NotNil(t, response.Envelope)
+	assert.Equal(t, request.RequestID, response.Envelope.RequestID)
+	assert.Equal(t, []byte("response"), response.Envelope.Payload)
+
+	// Without responder the request times out
+	response = conn.send(Frame{Type: FrameRequest, Topic: "edgex/nobody", ResponseTopicPrefix: "edgex/response", Timeout: 100, Envelope: &request})
+	assert.Equal(t, FrameError, response.Type)
+	assert.NotEmpty(t, response.Error)
+}
+
+func TestHandlerBoundsConcurrentRequests(t *testing.T) {
+	_, _, url := startHandler(t)
+	conn := dial(t, url)
+
+	for i := 0; i <= maxConcurrentRequests; i++ {
+		request := types.NewMessageEnvelopeForRequest(nil, nil)
+		require.NoError(t, conn.conn.WriteJSON(Frame{Type: FrameRequest, ID: uint64(i + 1), Topic: "edgex/nobody", ResponseTopicPrefix: "edgex/response", Timeout: 200, Envelope: &request}))
+	}
+	require.NoError(t, conn.conn.WriteJSON(Frame{Type: FrameSubscribe, ID: maxConcurrentRequests + 2, Topics: []string{"test"}}))
+
+	// The subscribe frame is only read once a request has completed
+	frame := conn.receive()
+	assert.Equal(t, FrameError, frame.Type)
+	assert.LessOrEqual(t, frame.ID, uint64(maxConcurrentRequests))
+}
+
+func TestHandlerDisconnectsSlowConnection(t *testing.T) {
+	_, bus, url := startHandler(t)
+
+	slow := dial(t, url)
+	assert.Equal(t, FrameAck, slow.send(Frame{Type: FrameSubscribe, Topics: []string{"edgex/events"}}).Type)
+
+	// The slow connection doesn't read while the messages overflow its buffer and those of the network
+	published := make(chan error, 1)
+	go func() {
+		payload := make([]byte, 64*1024)
+		for i := 0; i < 1000; i++ {
+			if err := bus.Publish(types.MessageEnvelope{Payload: payload}, "edgex/events"); err != nil {
+				published <- err
+				return
+			}
+		}
+		published <- nil
+	}()
+
+	select {
+	case err := <-published:
+		require.NoError(t, err)
+	case <-time.After(testTimeout):
+		require.Fail(t, "publishing was held up by the slow connection")
+	}
+
+	require.NoError(t, slow.conn.SetReadDeadline(time.Now().Add(testTimeout)))
+	var err error
+	for err == nil {
+		_, _, err = slow.conn.ReadMessage()
+	}
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "unexpected error %v", err)
+}
+
+func TestHandlerInvalidFrames(t *testing.T) {
+	_, _, url := startHandler(t)
+	conn := dial(t, url)
+
+	tests := []struct {
+		name  string
+		frame Frame
+	}{
+		{"Unsupported type", Frame{Type: "unknown"}},
+		{"Publish without envelope", Frame{Type: FramePublish, Topic: "test"}},
+		{"Publish to wildcard", Frame{Type: FramePublish, Topic: "test/#", Envelope: &types.MessageEnvelope{}}},
+		{"Invalid filter", Frame{Type: FrameSubscribe, Topics: []string{"test/#/invalid"}}},
+		{"Request without envelope", Frame{Type: FrameRequest, Topic: "test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := conn.send(tt.frame)
+			assert.Equal(t, FrameError, response.Type)
+			assert.NotEmpty(t, response.Error)
+		})
+	}
+
+	// Frames which aren't JSON are reported without closing the connection
+	require.NoError(t, conn.conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+	assert.Equal(t, FrameError, conn.receive().Type)
+	assert.Equal(t, FrameAck, conn.send(Frame{Type: FrameSubscribe, Topics: []string{"test"}}).Type)
+}
+
+func TestHandlerCheckOrigin(t *testing.T) {
+	handler, _, url := startHandler(t)
+
+	header := http.Header{"Origin": []string{"http://elsewhere.example"}}
+	_, response, err := websocket.DefaultDialer.Dial(url, header)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	handler.CheckOrigin = func(r *http.Request) bool { return r.Header.Get("Origin") == "http://elsewhere.example" }
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	_ = conn.Close()
+}
+
+func TestHandlerClose(t *testing.T) {
+	handler, _, url := startHandler(t)
+	conn := dial(t, url)
+	assert.Equal(t, FrameAck, conn.send(Frame{Type: FrameSubscribe, Topics: []string{"test"}}).Type)
+
+	require.NoError(t, handler.Close())
+
+	var frame Frame
+	require.NoError(t, conn.conn.SetReadDeadline(time.Now().Add(testTimeout)))
+	require.Error(t, conn.conn.ReadJSON(&frame))
+
+	// New connections are closed right away
+	closed := dial(t, url)
+	require.NoError(t, closed.conn.SetReadDeadline(time.Now().Add(testTimeout)))
+	require.Error(t, closed.conn.ReadJSON(&frame))
+}
@@ -0,0 +1,44 @@
+package websocket
+
+import "messaging/pkg/types"
+
+// FrameType identifies the purpose of a frame.
+type FrameType string
+
+const (
+	// FramePublish is sent by a client to publish the Envelope to the Topic.
+	FramePublish FrameType = "publish"
+	// FrameSubscribe is sent by a client to subscribe to the Topics, which are topic filters with MQTT wildcards.
+	FrameSubscribe FrameType = "subscribe"
+	// FrameUnsubscribe is sent by a client to unsubscribe from the Topics.
+	FrameUnsubscribe FrameType = "unsubscribe"
+	// FrameRequest is sent by a client to publish the Envelope as a request to the Topic and wait up to Timeout
+	// milliseconds for the response published under the ResponseTopicPrefix.
+	FrameRequest FrameType = "request"
+	// FrameMessage is sent by the server to deliver the Envelope received by the client's subscription to the Topic
+	// filter. The Envelope's ReceivedTopic is the topic the message was published to.
+	FrameMessage FrameType = "message"
+	// FrameResponse is sent by the server with the response Envelope of the client's request with the same ID.
+	FrameResponse FrameType = "response"
+	// FrameAck is sent by the server once the client's frame with the same ID has been processed.
+	FrameAck FrameType = "ack"
+	// FrameError is sent by the server when the client's frame with the same ID failed, the Error describes why. An
+	// error frame without ID but with a Topic reports an error of the subscription to that topic filter.
+	FrameError FrameType = "error"
+)
+
+// Frame is the unit exchanged between the server and its clients, each frame is a JSON encoded text message. The
+// frames of a connection using the JSON format, the default, carry the Envelope as is, the other formats, set with the
+// FormatParameter of the connection's URL, carry its encoding with the format's codec as Data instead. The Handler's
+// Client may use any Format since the Handler exchanges the decoded envelopes with it.
+type Frame struct {
+	Type                FrameType              `json:"type"`
+	ID                  uint64                 `json:"id,omitempty"`
+	Topic               string                 `json:"topic,omitempty"`
+	Topics              []string               `json:"topics,omitempty"`
+	ResponseTopicPrefix string                 `json:"responseTopicPrefix,omitempty"`
+	Timeout             int64                  `json:"timeout,omitempty"` // Milliseconds
+	Envelope            *types.MessageEnvelope `json:"envelope,omitempty"`
+	Data                []byte                 `json:"data,omitempty"` // Encoded envelope, when not JSON
+	Error               string                 `json:"error,omitempty"`
+}